@@ -0,0 +1,75 @@
+package checksum
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"hash"
+	"hash/crc64"
+	"io"
+	"strconv"
+)
+
+const (
+	// 对象 CRC64-ECMA 校验和的自定义元数据名称，值为十进制数字
+	MetadataKeyCRC64 = "x-qn-meta-crc64ecma"
+	// 对象 MD5 校验和的自定义元数据名称，值为十六进制字符串
+	MetadataKeyMD5 = "x-qn-meta-md5"
+)
+
+var crc64Table = crc64.MakeTable(crc64.ECMA)
+
+// Hasher 同时计算 CRC64-ECMA 与 MD5 校验和，未启用的算法不计算
+type Hasher struct {
+	crc64 hash.Hash64
+	md5   hash.Hash
+	size  uint64
+}
+
+// NewHasher 创建校验和计算器
+func NewHasher(withCRC64, withMD5 bool) *Hasher {
+	var hasher Hasher
+	if withCRC64 {
+		hasher.crc64 = crc64.New(crc64Table)
+	}
+	if withMD5 {
+		hasher.md5 = md5.New()
+	}
+	return &hasher
+}
+
+func (hasher *Hasher) Write(p []byte) (int, error) {
+	if hasher.crc64 != nil {
+		hasher.crc64.Write(p)
+	}
+	if hasher.md5 != nil {
+		hasher.md5.Write(p)
+	}
+	hasher.size += uint64(len(p))
+	return len(p), nil
+}
+
+// Size 返回已经计算的数据量
+func (hasher *Hasher) Size() uint64 {
+	return hasher.size
+}
+
+// CRC64 返回 CRC64-ECMA 校验和，未启用时返回空字符串
+func (hasher *Hasher) CRC64() string {
+	if hasher.crc64 == nil {
+		return ""
+	}
+	return strconv.FormatUint(hasher.crc64.Sum64(), 10)
+}
+
+// MD5 返回 MD5 校验和，未启用时返回空字符串
+func (hasher *Hasher) MD5() string {
+	if hasher.md5 == nil {
+		return ""
+	}
+	return hex.EncodeToString(hasher.md5.Sum(nil))
+}
+
+// ReadFrom 从 r 读取全部数据并计算校验和
+func (hasher *Hasher) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{hasher}, r)
+}
@@ -14,6 +14,7 @@ const (
 	InterceptorPriorityNormal         InterceptorPriority = 500
 	InterceptorPriorityAuth           InterceptorPriority = 600
 	InterceptorPriorityAntiHijacking  InterceptorPriority = 700
+	InterceptorPriorityBandwidthLimit InterceptorPriority = 750
 	InterceptorPriorityDebug          InterceptorPriority = 800
 )
 
@@ -0,0 +1,43 @@
+package clientv2
+
+import (
+	"net/http"
+
+	"github.com/qiniu/go-sdk/v7/storagev2/bandwidth"
+)
+
+type bandwidthLimitInterceptor struct {
+	limiter *bandwidth.Limiter
+}
+
+// NewBandwidthLimitInterceptor 创建带宽限制拦截器
+//
+// 优先使用请求 context 上附加的带宽限制器，如果没有则使用 limiter，两者都为空则不限速。
+// 该拦截器位于重试拦截器之内，每次重试都会重新限速请求体。
+func NewBandwidthLimitInterceptor(limiter *bandwidth.Limiter) Interceptor {
+	return &bandwidthLimitInterceptor{limiter: limiter}
+}
+
+func (interceptor *bandwidthLimitInterceptor) Priority() InterceptorPriority {
+	return InterceptorPriorityBandwidthLimit
+}
+
+func (interceptor *bandwidthLimitInterceptor) Intercept(req *http.Request, handler Handler) (*http.Response, error) {
+	ctx := req.Context()
+	limiter := bandwidth.LimiterFromContext(ctx)
+	if limiter == nil {
+		limiter = interceptor.limiter
+	}
+	if limiter == nil {
+		return handler(req)
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		req = req.WithContext(ctx)
+		req.Body = bandwidth.NewReadCloser(ctx, limiter, req.Body)
+	}
+	resp, err := handler(req)
+	if resp != nil && resp.Body != nil && resp.Body != http.NoBody {
+		resp.Body = bandwidth.NewReadCloser(ctx, limiter, resp.Body)
+	}
+	return resp, err
+}
@@ -0,0 +1,108 @@
+package etag
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"hash"
+	"io"
+)
+
+// 七牛 Etag 的分块大小，固定为 4 MB
+const BlockSize = 1 << 22
+
+const (
+	smallFlag byte = 0x16
+	largeFlag byte = 0x96
+)
+
+type etagHasher struct {
+	blockHasher hash.Hash
+	blockSHA1s  []byte
+	blockRest   int
+}
+
+// 创建七牛 Etag 计算器
+//
+// 数据按 4 MB 分块，每块计算 SHA1。
+// 如果只有一块，结果为 0x16 + SHA1，否则为 0x96 + SHA1(所有块 SHA1 拼接)。
+func New() hash.Hash {
+	return &etagHasher{blockHasher: sha1.New(), blockRest: BlockSize}
+}
+
+func (h *etagHasher) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		if h.blockRest == 0 {
+			h.blockSHA1s = h.blockHasher.Sum(h.blockSHA1s)
+			h.blockHasher.Reset()
+			h.blockRest = BlockSize
+		}
+		toWrite := p
+		if len(toWrite) > h.blockRest {
+			toWrite = toWrite[:h.blockRest]
+		}
+		written, _ := h.blockHasher.Write(toWrite)
+		h.blockRest -= written
+		n += written
+		p = p[written:]
+	}
+	return
+}
+
+func (h *etagHasher) Sum(b []byte) []byte {
+	if len(h.blockSHA1s) == 0 {
+		b = append(b, smallFlag)
+		return h.blockHasher.Sum(b)
+	}
+	blockSHA1s := h.blockSHA1s
+	if h.blockRest < BlockSize {
+		blockSHA1s = h.blockHasher.Sum(append(make([]byte, 0, len(blockSHA1s)+sha1.Size), blockSHA1s...))
+	}
+	hasher := sha1.New()
+	hasher.Write(blockSHA1s)
+	b = append(b, largeFlag)
+	return hasher.Sum(b)
+}
+
+func (h *etagHasher) Reset() {
+	h.blockHasher.Reset()
+	h.blockSHA1s = h.blockSHA1s[:0]
+	h.blockRest = BlockSize
+}
+
+func (h *etagHasher) Size() int {
+	return sha1.Size + 1
+}
+
+func (h *etagHasher) BlockSize() int {
+	return h.blockHasher.BlockSize()
+}
+
+// 将 Etag 计算结果编码为字符串
+func Encode(sum []byte) string {
+	return base64.URLEncoding.EncodeToString(sum)
+}
+
+// 计算 io.Reader 的七牛 Etag
+func FromReader(r io.Reader) (string, error) {
+	hasher := New()
+	if _, err := io.Copy(hasher, r); err != nil {
+		return "", err
+	}
+	return Encode(hasher.Sum(nil)), nil
+}
+
+// 计算 io.ReadSeeker 的七牛 Etag，计算完毕后恢复原有读取位置
+func FromReadSeeker(r io.ReadSeeker) (string, error) {
+	offset, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", err
+	}
+	etag, err := FromReader(r)
+	if err != nil {
+		return "", err
+	}
+	if _, err = r.Seek(offset, io.SeekStart); err != nil {
+		return "", err
+	}
+	return etag, nil
+}
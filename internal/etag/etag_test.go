@@ -0,0 +1,73 @@
+//go:build unit
+// +build unit
+
+package etag
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"math/rand"
+	"testing"
+)
+
+func TestEtagOfEmptyData(t *testing.T) {
+	etag, err := FromReader(bytes.NewReader(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if etag != "Fto5o-5ea0sNMlW_75VgGJCv2AcJ" {
+		t.Fatalf("unexpected etag: %s", etag)
+	}
+}
+
+func TestEtagOfSmallData(t *testing.T) {
+	data := make([]byte, BlockSize)
+	rand.New(rand.NewSource(0)).Read(data)
+
+	sha1Sum := sha1.Sum(data)
+	expected := base64.URLEncoding.EncodeToString(append([]byte{0x16}, sha1Sum[:]...))
+
+	etag, err := FromReadSeeker(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if etag != expected {
+		t.Fatalf("unexpected etag: %s", etag)
+	}
+}
+
+func TestEtagOfLargeData(t *testing.T) {
+	data := make([]byte, 2*BlockSize+1024)
+	rand.New(rand.NewSource(0)).Read(data)
+
+	blockSHA1s := make([]byte, 0, 3*sha1.Size)
+	for offset := 0; offset < len(data); offset += BlockSize {
+		end := offset + BlockSize
+		if end > len(data) {
+			end = len(data)
+		}
+		sum := sha1.Sum(data[offset:end])
+		blockSHA1s = append(blockSHA1s, sum[:]...)
+	}
+	sha1Sum := sha1.Sum(blockSHA1s)
+	expected := base64.URLEncoding.EncodeToString(append([]byte{0x96}, sha1Sum[:]...))
+
+	hasher := New()
+	for offset := 0; offset < len(data); offset += 1000 {
+		end := offset + 1000
+		if end > len(data) {
+			end = len(data)
+		}
+		hasher.Write(data[offset:end])
+	}
+	if etag := Encode(hasher.Sum(nil)); etag != expected {
+		t.Fatalf("unexpected etag: %s", etag)
+	}
+
+	hasher.Reset()
+	hasher.Write(nil)
+	if etag := Encode(hasher.Sum(nil)); etag != "Fto5o-5ea0sNMlW_75VgGJCv2AcJ" {
+		t.Fatalf("unexpected etag after reset: %s", etag)
+	}
+}
@@ -0,0 +1,285 @@
+package kvstore
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/gofrs/flock"
+)
+
+type (
+	// 单文件键值存储
+	//
+	// 所有写操作以日志的形式追加到同一个文件中，内存中保存全部键值。
+	// 每次操作前持有文件锁并重放其他进程追加的日志，因此可以被多个进程同时使用。
+	// 无效数据超过一定比例后会重写文件以回收空间。
+	FileStore struct {
+		filePath string
+		lock     *flock.Flock
+		mutex    sync.Mutex
+		file     *os.File
+		offset   int64
+		values   map[string][]byte
+		sizes    map[string]int64
+		garbage  int64
+	}
+)
+
+const (
+	fileStoreOpSet byte = iota + 1
+	fileStoreOpAppend
+	fileStoreOpDelete
+)
+
+const (
+	fileStoreEntryHeaderSize = 8
+	fileStoreMaxEntrySize    = 1 << 30
+	fileStoreCompactMinSize  = 1 << 20
+)
+
+var errCorruptedEntry = errors.New("corrupted entry")
+
+// 创建单文件键值存储，文件在首次操作时创建
+func NewFileStore(filePath string) *FileStore {
+	return &FileStore{filePath: filePath, lock: flock.New(filePath + ".lock")}
+}
+
+// 获取值，键不存在时返回 false
+func (store *FileStore) Get(key string) (value []byte, ok bool, err error) {
+	err = store.do(func() error {
+		if value, ok = store.values[key]; ok {
+			value = append([]byte(nil), value...)
+		}
+		return nil
+	})
+	return
+}
+
+// 设置值
+func (store *FileStore) Set(key string, value []byte) error {
+	return store.do(func() error { return store.write(fileStoreOpSet, key, value) })
+}
+
+// 在值的末尾追加数据，键不存在时创建
+func (store *FileStore) Append(key string, value []byte) error {
+	return store.do(func() error { return store.write(fileStoreOpAppend, key, value) })
+}
+
+// 删除键，键不存在时不返回错误
+func (store *FileStore) Delete(key string) error {
+	return store.do(func() error {
+		if _, ok := store.values[key]; !ok {
+			return nil
+		}
+		return store.write(fileStoreOpDelete, key, nil)
+	})
+}
+
+// 按照键的顺序遍历具有指定前缀的键值，fn 返回 false 时停止遍历
+//
+// 遍历的是调用时的快照，fn 中可以修改存储。
+func (store *FileStore) Range(prefix string, fn func(key string, value []byte) bool) error {
+	var snapshot map[string][]byte
+	if err := store.do(func() error {
+		snapshot = filterByPrefix(store.values, prefix)
+		return nil
+	}); err != nil {
+		return err
+	}
+	return rangeSnapshot(snapshot, fn)
+}
+
+// 关闭存储文件
+func (store *FileStore) Close() error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.file == nil {
+		return nil
+	}
+	err := store.file.Close()
+	store.file = nil
+	return err
+}
+
+func (store *FileStore) do(fn func() error) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if err := store.lock.Lock(); err != nil {
+		return err
+	}
+	defer store.lock.Unlock()
+
+	if err := store.replay(); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	return store.compactIfNeeded()
+}
+
+// 重放其他进程追加的日志，文件被其他进程重写后重新加载
+func (store *FileStore) replay() error {
+	if store.file != nil {
+		fileInfo, err := store.file.Stat()
+		if err != nil {
+			return err
+		}
+		if pathInfo, err := os.Stat(store.filePath); err != nil || !os.SameFile(fileInfo, pathInfo) {
+			store.file.Close()
+			store.file = nil
+		}
+	}
+	if store.file == nil {
+		file, err := os.OpenFile(store.filePath, os.O_RDWR|os.O_CREATE, 0600)
+		if err != nil {
+			return err
+		}
+		store.file = file
+		store.offset = 0
+		store.values = make(map[string][]byte)
+		store.sizes = make(map[string]int64)
+		store.garbage = 0
+	}
+
+	if _, err := store.file.Seek(store.offset, io.SeekStart); err != nil {
+		return err
+	}
+	reader := bufio.NewReader(store.file)
+	for {
+		op, key, value, size, err := readFileStoreEntry(reader)
+		if err == io.EOF {
+			return nil
+		} else if err == io.ErrUnexpectedEOF || err == errCorruptedEntry {
+			// 持有文件锁时仍然存在不完整的日志，说明写入日志的进程异常退出，直接截断
+			return store.file.Truncate(store.offset)
+		} else if err != nil {
+			return err
+		}
+		store.apply(op, key, value, size)
+		store.offset += size
+	}
+}
+
+func (store *FileStore) write(op byte, key string, value []byte) error {
+	entry := encodeFileStoreEntry(op, key, value)
+	if _, err := store.file.WriteAt(entry, store.offset); err != nil {
+		return err
+	}
+	store.apply(op, key, value, int64(len(entry)))
+	store.offset += int64(len(entry))
+	return nil
+}
+
+func (store *FileStore) apply(op byte, key string, value []byte, size int64) {
+	switch op {
+	case fileStoreOpSet:
+		store.garbage += store.sizes[key]
+		store.values[key] = append([]byte(nil), value...)
+		store.sizes[key] = size
+	case fileStoreOpAppend:
+		store.values[key] = append(store.values[key], value...)
+		store.sizes[key] += size
+	case fileStoreOpDelete:
+		store.garbage += store.sizes[key] + size
+		delete(store.values, key)
+		delete(store.sizes, key)
+	}
+}
+
+// 无效数据超过有效数据时，将有效数据写入新文件并替换原文件
+func (store *FileStore) compactIfNeeded() error {
+	if store.garbage < fileStoreCompactMinSize || store.garbage < store.offset-store.garbage {
+		return nil
+	}
+	tmpFilePath := store.filePath + ".tmp"
+	tmpFile, err := os.OpenFile(tmpFilePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(tmpFile)
+	sizes := make(map[string]int64, len(store.values))
+	var offset int64
+	for key, value := range store.values {
+		entry := encodeFileStoreEntry(fileStoreOpSet, key, value)
+		if _, err = writer.Write(entry); err != nil {
+			break
+		}
+		sizes[key] = int64(len(entry))
+		offset += int64(len(entry))
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = tmpFile.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmpFilePath, store.filePath)
+	}
+	if err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFilePath)
+		return err
+	}
+	store.file.Close()
+	store.file = tmpFile
+	store.offset = offset
+	store.sizes = sizes
+	store.garbage = 0
+	return nil
+}
+
+// 日志格式：4 字节长度 + 4 字节 CRC32 + 操作类型 + 键长度（Uvarint） + 键 + 值
+func encodeFileStoreEntry(op byte, key string, value []byte) []byte {
+	body := make([]byte, 0, 1+binary.MaxVarintLen64+len(key)+len(value))
+	body = append(body, op)
+	body = binary.AppendUvarint(body, uint64(len(key)))
+	body = append(body, key...)
+	body = append(body, value...)
+
+	entry := make([]byte, fileStoreEntryHeaderSize, fileStoreEntryHeaderSize+len(body))
+	binary.LittleEndian.PutUint32(entry[0:4], uint32(len(body)))
+	binary.LittleEndian.PutUint32(entry[4:8], crc32.ChecksumIEEE(body))
+	return append(entry, body...)
+}
+
+func readFileStoreEntry(reader io.Reader) (op byte, key string, value []byte, size int64, err error) {
+	var header [fileStoreEntryHeaderSize]byte
+	if _, err = io.ReadFull(reader, header[:]); err != nil {
+		return
+	}
+	bodySize := binary.LittleEndian.Uint32(header[0:4])
+	if bodySize == 0 || bodySize > fileStoreMaxEntrySize {
+		err = errCorruptedEntry
+		return
+	}
+	body := make([]byte, bodySize)
+	if _, err = io.ReadFull(reader, body); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return
+	}
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(header[4:8]) {
+		err = errCorruptedEntry
+		return
+	}
+	op = body[0]
+	keySize, n := binary.Uvarint(body[1:])
+	if n <= 0 || uint64(len(body)-1-n) < keySize {
+		err = errCorruptedEntry
+		return
+	}
+	key = string(body[1+n : 1+n+int(keySize)])
+	value = body[1+n+int(keySize):]
+	size = int64(fileStoreEntryHeaderSize + len(body))
+	return
+}
@@ -0,0 +1,97 @@
+package kvstore
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+type (
+	// 内存键值存储，并发安全
+	MemoryStore struct {
+		values map[string][]byte
+		mutex  sync.RWMutex
+	}
+)
+
+// 创建内存键值存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{values: make(map[string][]byte)}
+}
+
+// 获取值，键不存在时返回 false
+func (store *MemoryStore) Get(key string) ([]byte, bool, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	value, ok := store.values[key]
+	if !ok {
+		return nil, false, nil
+	}
+	return append([]byte(nil), value...), true, nil
+}
+
+// 设置值
+func (store *MemoryStore) Set(key string, value []byte) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.values[key] = append([]byte(nil), value...)
+	return nil
+}
+
+// 在值的末尾追加数据，键不存在时创建
+func (store *MemoryStore) Append(key string, value []byte) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.values[key] = append(store.values[key], value...)
+	return nil
+}
+
+// 删除键，键不存在时不返回错误
+func (store *MemoryStore) Delete(key string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	delete(store.values, key)
+	return nil
+}
+
+// 按照键的顺序遍历具有指定前缀的键值，fn 返回 false 时停止遍历
+//
+// 遍历的是调用时的快照，fn 中可以修改存储。
+func (store *MemoryStore) Range(prefix string, fn func(key string, value []byte) bool) error {
+	return rangeSnapshot(store.snapshot(prefix), fn)
+}
+
+func (store *MemoryStore) snapshot(prefix string) map[string][]byte {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	return filterByPrefix(store.values, prefix)
+}
+
+func filterByPrefix(values map[string][]byte, prefix string) map[string][]byte {
+	snapshot := make(map[string][]byte)
+	for key, value := range values {
+		if strings.HasPrefix(key, prefix) {
+			snapshot[key] = append([]byte(nil), value...)
+		}
+	}
+	return snapshot
+}
+
+func rangeSnapshot(snapshot map[string][]byte, fn func(key string, value []byte) bool) error {
+	keys := make([]string, 0, len(snapshot))
+	for key := range snapshot {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !fn(key, snapshot[key]) {
+			break
+		}
+	}
+	return nil
+}
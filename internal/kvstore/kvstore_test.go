@@ -0,0 +1,127 @@
+//go:build unit
+// +build unit
+
+package kvstore
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+type store interface {
+	Get(string) ([]byte, bool, error)
+	Set(string, []byte) error
+	Append(string, []byte) error
+	Delete(string) error
+	Range(string, func(string, []byte) bool) error
+}
+
+func testStore(t *testing.T, s store) {
+	if err := s.Set("a/1", []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Append("a/1", []byte(" world")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Append("a/2", []byte("created")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("b/1", []byte("other")); err != nil {
+		t.Fatal(err)
+	}
+	if value, ok, err := s.Get("a/1"); err != nil {
+		t.Fatal(err)
+	} else if !ok || string(value) != "hello world" {
+		t.Fatalf("unexpected value: %s", value)
+	}
+	var keys []string
+	if err := s.Range("a/", func(key string, value []byte) bool {
+		keys = append(keys, key)
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0] != "a/1" || keys[1] != "a/2" {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+	if err := s.Delete("a/1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := s.Get("a/1"); err != nil {
+		t.Fatal(err)
+	} else if ok {
+		t.Fatalf("a/1 should be deleted")
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "store")
+	s := NewFileStore(filePath)
+	defer s.Close()
+	testStore(t, s)
+
+	// 其他实例追加的日志应当可见
+	another := NewFileStore(filePath)
+	defer another.Close()
+	if err := another.Append("a/2", []byte("!")); err != nil {
+		t.Fatal(err)
+	}
+	if value, ok, err := s.Get("a/2"); err != nil {
+		t.Fatal(err)
+	} else if !ok || string(value) != "created!" {
+		t.Fatalf("unexpected value: %s", value)
+	}
+
+	// 不完整的日志应当被截断
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write(encodeFileStoreEntry(fileStoreOpSet, "c", []byte("broken"))[:10])
+	file.Close()
+	reopened := NewFileStore(filePath)
+	if value, ok, err := reopened.Get("b/1"); err != nil {
+		t.Fatal(err)
+	} else if !ok || string(value) != "other" {
+		t.Fatalf("unexpected value: %s", value)
+	}
+	reopened.Close()
+	if newFileInfo, err := os.Stat(filePath); err != nil {
+		t.Fatal(err)
+	} else if newFileInfo.Size() != fileInfo.Size() {
+		t.Fatalf("broken entry should be truncated")
+	}
+
+	// 反复覆盖后文件应当被压缩
+	value := make([]byte, 64*1024)
+	for i := 0; i < 64; i++ {
+		if err = s.Set("big", append(value, strconv.Itoa(i)...)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fileInfo, err = os.Stat(filePath); err != nil {
+		t.Fatal(err)
+	} else if fileInfo.Size() > 2*1024*1024 {
+		t.Fatalf("file should be compacted, size: %d", fileInfo.Size())
+	}
+	if v, ok, err := another.Get("big"); err != nil {
+		t.Fatal(err)
+	} else if !ok || string(v[len(value):]) != "63" {
+		t.Fatalf("unexpected value after compaction")
+	}
+	if v, ok, err := another.Get("b/1"); err != nil {
+		t.Fatal(err)
+	} else if !ok || string(v) != "other" {
+		t.Fatalf("unexpected value after compaction")
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/qiniu/go-sdk/v7/client"
+	"github.com/qiniu/go-sdk/v7/storagev2/bandwidth"
 	"github.com/qiniu/go-sdk/v7/storagev2/defaults"
 	"github.com/qiniu/go-sdk/v7/storagev2/uplog"
 )
@@ -31,6 +32,9 @@ type UploadConfig struct {
 	UseCdnDomains       bool
 	AccelerateUploading bool
 	Regions             *RegionGroup
+
+	// 【可选】带宽限制器，限制该上传管理器所有上传请求的总带宽，可以与 storagev2 的上传与下载管理器共享
+	BandwidthLimiter *bandwidth.Limiter
 }
 
 // NewUploadConfig 创建默认的 UploadConfig 对象
@@ -74,6 +78,9 @@ type UploadExtra struct {
 
 	// 【可选】分片上传时每次上传的块大小，单位：字节，默认：4 * 1024 * 1024
 	PartSize int64
+
+	// 【可选】带宽限制器，如果设置，则覆盖 UploadConfig 中的带宽限制器
+	BandwidthLimiter *bandwidth.Limiter
 }
 
 func (extra *UploadExtra) init() {
@@ -193,6 +200,11 @@ func (manager *UploadManager) Put(ctx context.Context, ret interface{}, upToken
 	if source == nil {
 		return errors.New("source invalid")
 	}
+	if extra != nil && extra.BandwidthLimiter != nil {
+		ctx = bandwidth.WithLimiter(ctx, extra.BandwidthLimiter)
+	} else if manager.cfg.BandwidthLimiter != nil {
+		ctx = bandwidth.WithLimiter(ctx, manager.cfg.BandwidthLimiter)
+	}
 
 	return manager.putRetryBetweenRegion(ctx, ret, upToken, key, source, extra)
 }
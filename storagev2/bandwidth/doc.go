@@ -0,0 +1,22 @@
+// Package bandwidth 提供基于令牌桶的带宽限制。
+//
+// 一个 [Limiter] 可以被多个上传管理器、下载管理器以及 v1 的上传管理器共享，
+// 所有并发分片的请求体和响应体都从同一个令牌桶中获取令牌，因此限制的是总带宽。
+// 限速可以在运行时通过 [Limiter.SetLimit] 调整，立即对正在进行的传输生效。
+//
+// # 为客户端配置带宽限制
+//
+//	limiter := bandwidth.NewLimiter(10 * 1024 * 1024) // 10 MB/s
+//
+//	uploadManager := uploader.NewUploadManager(&uploader.UploadManagerOptions{
+//	    Options: http_client.Options{Credentials: cred, BandwidthLimiter: limiter},
+//	})
+//	downloadManager := downloader.NewDownloadManager(&downloader.DownloadManagerOptions{
+//	    Options: http_client.Options{Credentials: cred, BandwidthLimiter: limiter},
+//	})
+//
+// # 为单次调用配置带宽限制
+//
+// uploader.ObjectOptions 和 downloader.ObjectOptions 中的 BandwidthLimiter 字段将覆盖客户端的带宽限制，
+// 也可以通过 [WithLimiter] 将带宽限制附加在 context 上。
+package bandwidth
@@ -0,0 +1,165 @@
+package bandwidth
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// 令牌桶的最小容量，避免限速过低时每次读取的数据量过小
+const minBurst = 32 * 1024
+
+type (
+	// 带宽限制器
+	//
+	// 基于令牌桶实现，每个字节消耗一个令牌，令牌以每秒 limit 个的速度生成，桶的容量为一秒的令牌数。
+	// 可以被多个协程并发使用。
+	Limiter struct {
+		mutex  sync.Mutex
+		limit  int64
+		burst  int64
+		tokens float64
+		last   time.Time
+	}
+
+	limitedReadCloser struct {
+		ctx     context.Context
+		limiter *Limiter
+		r       io.ReadCloser
+	}
+
+	limiterContextKey struct{}
+)
+
+// 创建带宽限制器
+//
+// bytesPerSecond 为每秒允许传输的字节数，小于等于 0 表示不限速。
+func NewLimiter(bytesPerSecond int64) *Limiter {
+	limiter := Limiter{last: time.Now()}
+	limiter.setLimit(bytesPerSecond)
+	limiter.tokens = float64(limiter.burst)
+	return &limiter
+}
+
+// 获取当前限速，单位为字节每秒，小于等于 0 表示不限速
+func (limiter *Limiter) Limit() int64 {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	return limiter.limit
+}
+
+// 调整限速，单位为字节每秒，小于等于 0 表示不限速
+func (limiter *Limiter) SetLimit(bytesPerSecond int64) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	limiter.advance(time.Now())
+	limiter.setLimit(bytesPerSecond)
+}
+
+func (limiter *Limiter) setLimit(bytesPerSecond int64) {
+	limiter.limit = bytesPerSecond
+	limiter.burst = bytesPerSecond
+	if limiter.burst < minBurst {
+		limiter.burst = minBurst
+	}
+	if limiter.tokens > float64(limiter.burst) {
+		limiter.tokens = float64(limiter.burst)
+	}
+}
+
+func (limiter *Limiter) advance(now time.Time) {
+	if elapsed := now.Sub(limiter.last); elapsed > 0 && limiter.limit > 0 {
+		limiter.tokens += elapsed.Seconds() * float64(limiter.limit)
+		if limiter.tokens > float64(limiter.burst) {
+			limiter.tokens = float64(limiter.burst)
+		}
+	}
+	limiter.last = now
+}
+
+// 等待 n 个字节的令牌
+//
+// 如果 context 在等待期间被取消，则返回 context 的错误。
+func (limiter *Limiter) WaitN(ctx context.Context, n int) error {
+	for n > 0 {
+		wait, taken := limiter.reserve(n)
+		if taken == 0 {
+			return nil
+		}
+		n -= taken
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+	return nil
+}
+
+// 预定令牌，允许令牌数变为负数，返回需要等待的时间与本次预定的令牌数，不限速时预定的令牌数为 0
+func (limiter *Limiter) reserve(n int) (time.Duration, int) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	if limiter.limit <= 0 {
+		return 0, 0
+	}
+	if int64(n) > limiter.burst {
+		n = int(limiter.burst)
+	}
+	limiter.advance(time.Now())
+	limiter.tokens -= float64(n)
+	if limiter.tokens >= 0 {
+		return 0, n
+	}
+	return time.Duration(-limiter.tokens / float64(limiter.limit) * float64(time.Second)), n
+}
+
+// 为 io.ReadCloser 附加带宽限制
+//
+// 每次读取的数据量不会超过令牌桶的容量，读取后等待相应数量的令牌。
+func NewReadCloser(ctx context.Context, limiter *Limiter, r io.ReadCloser) io.ReadCloser {
+	if limiter == nil {
+		return r
+	}
+	return &limitedReadCloser{ctx: ctx, limiter: limiter, r: r}
+}
+
+func (r *limitedReadCloser) Read(p []byte) (int, error) {
+	if limit := r.limiter.Limit(); limit > 0 {
+		burst := limit
+		if burst < minBurst {
+			burst = minBurst
+		}
+		if int64(len(p)) > burst {
+			p = p[:burst]
+		}
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if waitErr := r.limiter.WaitN(r.ctx, n); waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
+
+func (r *limitedReadCloser) Close() error {
+	return r.r.Close()
+}
+
+// 将带宽限制器附加在 context 上，其优先级高于客户端配置的带宽限制器
+func WithLimiter(ctx context.Context, limiter *Limiter) context.Context {
+	return context.WithValue(ctx, limiterContextKey{}, limiter)
+}
+
+// 获取 context 上附加的带宽限制器，如果没有则返回 nil
+func LimiterFromContext(ctx context.Context) *Limiter {
+	limiter, _ := ctx.Value(limiterContextKey{}).(*Limiter)
+	return limiter
+}
@@ -0,0 +1,81 @@
+//go:build unit
+// +build unit
+
+package bandwidth_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/qiniu/go-sdk/v7/storagev2/bandwidth"
+)
+
+func TestLimiterWaitN(t *testing.T) {
+	limiter := bandwidth.NewLimiter(64 * 1024)
+	begin := time.Now()
+	// 初始令牌桶为满，第一个 64 KB 不需要等待
+	if err := limiter.WaitN(context.Background(), 64*1024); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(begin); elapsed > 100*time.Millisecond {
+		t.Fatalf("unexpected wait: %s", elapsed)
+	}
+	if err := limiter.WaitN(context.Background(), 32*1024); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(begin); elapsed < 400*time.Millisecond || elapsed > 900*time.Millisecond {
+		t.Fatalf("unexpected wait: %s", elapsed)
+	}
+}
+
+func TestLimiterSetLimit(t *testing.T) {
+	limiter := bandwidth.NewLimiter(0)
+	begin := time.Now()
+	if err := limiter.WaitN(context.Background(), 1<<30); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(begin); elapsed > 100*time.Millisecond {
+		t.Fatalf("unlimited limiter should not wait: %s", elapsed)
+	}
+
+	limiter.SetLimit(32 * 1024)
+	if limiter.Limit() != 32*1024 {
+		t.Fatalf("unexpected limit")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := limiter.WaitN(ctx, 128*1024); err != context.DeadlineExceeded {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	limiter.SetLimit(-1)
+	begin = time.Now()
+	if err := limiter.WaitN(context.Background(), 1<<30); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(begin); elapsed > 100*time.Millisecond {
+		t.Fatalf("unlimited limiter should not wait: %s", elapsed)
+	}
+}
+
+func TestLimitedReadCloser(t *testing.T) {
+	limiter := bandwidth.NewLimiter(32 * 1024)
+	data := make([]byte, 64*1024)
+	r := bandwidth.NewReadCloser(context.Background(), limiter, io.NopCloser(bytes.NewReader(data)))
+	begin := time.Now()
+	n, err := io.Copy(io.Discard, r)
+	if err != nil {
+		t.Fatal(err)
+	} else if n != int64(len(data)) {
+		t.Fatalf("unexpected read size: %d", n)
+	}
+	if elapsed := time.Since(begin); elapsed < 800*time.Millisecond || elapsed > 1500*time.Millisecond {
+		t.Fatalf("unexpected elapsed: %s", elapsed)
+	}
+	if err = r.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
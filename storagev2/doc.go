@@ -9,7 +9,12 @@
 //   - storagev2/uploader: 上传管理，[uploader.NewUploadManager] 自动选择上传方式
 //   - storagev2/downloader: 下载管理，[downloader.NewDownloadManager]
 //   - storagev2/objects: 对象管理，[objects.NewObjectsManager] 提供流式 API
+//   - storagev2/encryption: 客户端加密，为上传管理和下载管理提供透明的加密与解密
+//   - storagev2/syncer: 增量同步，[syncer.NewSyncer] 比较本地目录与空间目录并仅传输存在差异的文件
+//   - storagev2/inventory: 空间清单，[inventory.Snapshot] 生成可断点续传的分片清单，[inventory.Diff] 比较两份清单
+//   - storagev2/bandwidth: 带宽限制，[bandwidth.NewLimiter] 创建可在多个客户端之间共享的令牌桶限速器
 //   - storagev2/uptoken: 上传凭证，[uptoken.NewPutPolicy] 创建上传策略
+//   - storagev2/fop: 数据处理指令，类型化地构建图片处理、音视频处理指令及处理管道
 //   - storagev2/apis: 低级 API 客户端，[apis.NewStorage] 提供所有类型化 API 方法
 //   - storagev2/region: 区域信息，RegionsProvider 接口
 //   - storagev2/http_client: HTTP 客户端选项
@@ -0,0 +1,162 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/qiniu/go-sdk/v7/storagev2/backoff"
+	"github.com/qiniu/go-sdk/v7/storagev2/objects"
+)
+
+type (
+	// 归档对象自动解冻选项
+	ArchiveRestoreOptions struct {
+		// 解冻有效期，单位为天，取值范围为 1 到 7，如果不填写，默认为 1 天
+		FreezeAfterDays int64
+
+		// 轮询退避器，决定每次查询解冻状态之前的等待时长，如果不填写，默认每 30 秒查询一次
+		PollBackoff backoff.Backoff
+
+		// 最大轮询次数，超过后返回 ErrArchiveRestoreNotCompleted，如果不填写，默认为不限制，直到 context 取消
+		MaxPolls int
+
+		// 目录下载时批量解冻的对象数量，如果不填写，默认为 1000
+		BatchSize int
+
+		// 解冻请求发出成功后回调
+		OnRestoreRequested func(bucketName, objectName string)
+
+		// 每次查询解冻状态后回调
+		OnRestorePolled func(bucketName, objectName string, status objects.RestoreStatus)
+
+		// 对象解冻完成后回调
+		OnRestored func(bucketName, objectName string)
+	}
+
+	archivedObjectToRestore struct {
+		objectName, fullPath string
+		restoreErr           error
+	}
+)
+
+// 归档对象在最大轮询次数内未完成解冻
+var ErrArchiveRestoreNotCompleted = errors.New("archived object restoration is not completed")
+
+func (options *ArchiveRestoreOptions) freezeAfterDays() int64 {
+	if options.FreezeAfterDays > 0 {
+		return options.FreezeAfterDays
+	}
+	return 1
+}
+
+func (options *ArchiveRestoreOptions) batchSize() int {
+	if options.BatchSize > 0 {
+		return options.BatchSize
+	}
+	return 1000
+}
+
+func isArchivedStorageClass(storageClass objects.StorageClass) bool {
+	return storageClass == objects.ArchiveStorageClass || storageClass == objects.DeepArchiveStorageClass
+}
+
+// 判断下载错误是否可能由对象处于冻结状态引起，如果是，则查询对象元信息确认后解冻对象并等待解冻完成
+//
+// 返回 true 表示对象已经解冻，可以重新下载
+func (downloadManager *DownloadManager) restoreIfArchived(ctx context.Context, bucketName, objectName string, downloadErr error) (bool, error) {
+	if downloadManager.archiveRestore == nil || bucketName == "" || httpCodeOf(downloadErr) != http.StatusForbidden {
+		return false, nil
+	}
+	object := downloadManager.objectsManager.Bucket(bucketName).Object(objectName)
+	details, err := object.Stat().Call(ctx)
+	if err != nil {
+		return false, err
+	} else if !isArchivedStorageClass(details.StorageClass) || details.RestoreStatus == objects.RestoredStatus {
+		return false, nil
+	}
+	var restoreErr error
+	if details.RestoreStatus == objects.FrozenStatus {
+		if restoreErr = object.Restore(downloadManager.archiveRestore.freezeAfterDays()).Call(ctx); restoreErr == nil {
+			if onRestoreRequested := downloadManager.archiveRestore.OnRestoreRequested; onRestoreRequested != nil {
+				onRestoreRequested(bucketName, objectName)
+			}
+		}
+	}
+	if err = downloadManager.waitForRestored(ctx, bucketName, objectName, restoreErr); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// 轮询对象解冻状态，直到解冻完成
+//
+// 如果对象仍然处于冻结状态且解冻请求失败，则返回解冻请求的错误
+func (downloadManager *DownloadManager) waitForRestored(ctx context.Context, bucketName, objectName string, restoreErr error) error {
+	options := downloadManager.archiveRestore
+	pollBackoff := options.PollBackoff
+	if pollBackoff == nil {
+		pollBackoff = backoff.NewFixedBackoff(30 * time.Second)
+	}
+	object := downloadManager.objectsManager.Bucket(bucketName).Object(objectName)
+	for attempts := 0; options.MaxPolls <= 0 || attempts < options.MaxPolls; attempts++ {
+		if attempts > 0 || restoreErr == nil {
+			timer := time.NewTimer(pollBackoff.Time(ctx, &backoff.BackoffOptions{Attempts: attempts}))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+		details, err := object.Stat().Call(ctx)
+		if err != nil {
+			return err
+		}
+		if onRestorePolled := options.OnRestorePolled; onRestorePolled != nil {
+			onRestorePolled(bucketName, objectName, details.RestoreStatus)
+		}
+		switch details.RestoreStatus {
+		case objects.RestoredStatus:
+			if onRestored := options.OnRestored; onRestored != nil {
+				onRestored(bucketName, objectName)
+			}
+			return nil
+		case objects.FrozenStatus:
+			if restoreErr != nil {
+				return restoreErr
+			}
+		}
+	}
+	return ErrArchiveRestoreNotCompleted
+}
+
+// 通过批处理为冻结的对象发出解冻请求，每个对象的解冻请求错误记录在 restoreErr 中
+func (downloadManager *DownloadManager) restoreArchivedObjects(ctx context.Context, bucketName string, toRestore []*archivedObjectToRestore) error {
+	var (
+		operations = make([]objects.Operation, 0, len(toRestore))
+		bucket     = downloadManager.objectsManager.Bucket(bucketName)
+		lock       sync.Mutex
+	)
+	for _, archivedObject := range toRestore {
+		archivedObject := archivedObject
+		operations = append(operations, bucket.Object(archivedObject.objectName).
+			Restore(downloadManager.archiveRestore.freezeAfterDays()).
+			OnResponse(func() {
+				lock.Lock()
+				archivedObject.restoreErr = nil
+				lock.Unlock()
+				if onRestoreRequested := downloadManager.archiveRestore.OnRestoreRequested; onRestoreRequested != nil {
+					onRestoreRequested(bucketName, archivedObject.objectName)
+				}
+			}).
+			OnError(func(err error) {
+				lock.Lock()
+				defer lock.Unlock()
+				archivedObject.restoreErr = err
+			}))
+	}
+	return downloadManager.objectsManager.Batch(ctx, operations, nil)
+}
@@ -0,0 +1,413 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qiniu/go-sdk/v7/storagev2/objects"
+)
+
+type (
+	// 存储空间只读文件系统，实现了 fs.FS、fs.ReadDirFS 和 fs.StatFS
+	//
+	// 以 / 作为路径分隔符，将对象名称映射为文件路径，将公共前缀映射为目录。
+	// 打开的文件实现了 io.Seeker 和 io.ReaderAt，读取时通过 Range 请求获取数据，因此可以用于 http.FS。
+	BucketFS interface {
+		fs.ReadDirFS
+		fs.StatFS
+
+		// 清空缓存的列举和查询结果
+		InvalidateCache()
+	}
+
+	// 存储空间文件系统选项
+	BucketFSOptions struct {
+		// 根目录对应的对象前缀，可选
+		Prefix string
+
+		// 是否使用 HTTP 协议，默认为不使用
+		UseInsecureProtocol bool
+
+		// 下载 URL 生成器
+		DownloadURLsProvider DownloadURLsProvider
+
+		// 列举和查询结果缓存时间，默认为 1 分钟，小于 0 表示不缓存
+		CacheTTL time.Duration
+
+		// 打开文件时使用的读取选项，其中的 GenerateOptions 和 DownloadURLsProvider 将被忽略
+		ReaderOptions *ObjectReaderOptions
+	}
+
+	bucketFS struct {
+		downloadManager *DownloadManager
+		bucket          *objects.Bucket
+		prefix          string
+		generateOptions GenerateOptions
+		urlsProvider    DownloadURLsProvider
+		cacheTTL        time.Duration
+		readerOptions   ObjectReaderOptions
+
+		cacheMutex sync.Mutex
+		statCache  map[string]bucketFSCacheEntry
+		dirCache   map[string]bucketFSCacheEntry
+	}
+
+	bucketFSCacheEntry struct {
+		info      fs.FileInfo
+		entries   []fs.DirEntry
+		expiredAt time.Time
+	}
+
+	bucketFileInfo struct {
+		name    string
+		isDir   bool
+		details *objects.ObjectDetails
+	}
+
+	bucketFile struct {
+		fsys   *bucketFS
+		info   *bucketFileInfo
+		key    string
+		mutex  sync.Mutex
+		reader ObjectReader
+		closed bool
+	}
+
+	bucketDir struct {
+		info    *bucketFileInfo
+		entries []fs.DirEntry
+		offset  int
+	}
+)
+
+// 创建存储空间只读文件系统
+//
+// fs.FS 的方法不接受 context.Context，文件系统发出的每个请求都使用独立的 context.Context，打开的文件在关闭时取消其正在进行的读取。
+func (downloadManager *DownloadManager) BucketFS(bucketName string, options *BucketFSOptions) BucketFS {
+	if options == nil {
+		options = &BucketFSOptions{}
+	}
+	prefix := options.Prefix
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	cacheTTL := options.CacheTTL
+	if cacheTTL == 0 {
+		cacheTTL = time.Minute
+	}
+	var readerOptions ObjectReaderOptions
+	if options.ReaderOptions != nil {
+		readerOptions = *options.ReaderOptions
+	}
+	return &bucketFS{
+		downloadManager: downloadManager,
+		bucket:          downloadManager.objectsManager.Bucket(bucketName),
+		prefix:          prefix,
+		generateOptions: GenerateOptions{BucketName: bucketName, UseInsecureProtocol: options.UseInsecureProtocol},
+		urlsProvider:    options.DownloadURLsProvider,
+		cacheTTL:        cacheTTL,
+		readerOptions:   readerOptions,
+		statCache:       make(map[string]bucketFSCacheEntry),
+		dirCache:        make(map[string]bucketFSCacheEntry),
+	}
+}
+
+func (fsys *bucketFS) Open(name string) (fs.File, error) {
+	info, err := fsys.stat("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if info.isDir {
+		entries, err := fsys.readDir("open", name)
+		if err != nil {
+			return nil, err
+		}
+		return &bucketDir{info: info, entries: entries}, nil
+	}
+	return &bucketFile{fsys: fsys, info: info, key: fsys.objectName(name)}, nil
+}
+
+func (fsys *bucketFS) Stat(name string) (fs.FileInfo, error) {
+	info, err := fsys.stat("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (fsys *bucketFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := fsys.readDir("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	return append([]fs.DirEntry(nil), entries...), nil
+}
+
+func (fsys *bucketFS) InvalidateCache() {
+	fsys.cacheMutex.Lock()
+	defer fsys.cacheMutex.Unlock()
+
+	fsys.statCache = make(map[string]bucketFSCacheEntry)
+	fsys.dirCache = make(map[string]bucketFSCacheEntry)
+}
+
+// 先查询同名对象，不存在时再判断是否存在以其为前缀的对象
+func (fsys *bucketFS) stat(op, name string) (*bucketFileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &bucketFileInfo{name: ".", isDir: true}, nil
+	}
+	if entry, ok := fsys.getCache(fsys.statCache, name); ok {
+		if entry.info == nil {
+			return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		return entry.info.(*bucketFileInfo), nil
+	}
+
+	details, err := fsys.bucket.Object(fsys.objectName(name)).Stat().Call(context.Background())
+	if err == nil {
+		info := &bucketFileInfo{name: path.Base(name), details: details}
+		fsys.setCache(fsys.statCache, name, bucketFSCacheEntry{info: info})
+		return info, nil
+	} else if httpCodeOf(err) != 612 {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+
+	var (
+		limit        = uint64(1)
+		firstDetails objects.ObjectDetails
+	)
+	lister := fsys.bucket.List(context.Background(), &objects.ListObjectsOptions{Prefix: fsys.objectName(name) + "/", Limit: &limit})
+	isDir := lister.Next(&firstDetails)
+	err = lister.Error()
+	lister.Close()
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	} else if !isDir {
+		fsys.setCache(fsys.statCache, name, bucketFSCacheEntry{})
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	info := &bucketFileInfo{name: path.Base(name), isDir: true}
+	fsys.setCache(fsys.statCache, name, bucketFSCacheEntry{info: info})
+	return info, nil
+}
+
+// 列举目录，条目按照名称排序，列举到的子目录和文件同时加入查询缓存
+func (fsys *bucketFS) readDir(op, name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if entry, ok := fsys.getCache(fsys.dirCache, name); ok {
+		if entry.entries == nil {
+			return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		return entry.entries, nil
+	}
+
+	dirPrefix := fsys.prefix
+	if name != "." {
+		dirPrefix = fsys.objectName(name) + "/"
+	}
+	entries := make([]fs.DirEntry, 0)
+	if err := fsys.bucket.Directory(dirPrefix, "/").ListEntries(context.Background(), nil, func(entry *objects.Entry) error {
+		var info *bucketFileInfo
+		if entry.DirectoryName != "" {
+			info = &bucketFileInfo{name: path.Base(strings.TrimSuffix(entry.DirectoryName, "/")), isDir: true}
+		} else if relativeName := strings.TrimPrefix(entry.Object.Name, dirPrefix); relativeName != "" && !strings.Contains(relativeName, "/") {
+			info = &bucketFileInfo{name: relativeName, details: entry.Object}
+		} else {
+			return nil // 目录占位对象或无法映射为文件的对象
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+		return nil
+	}); err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	if len(entries) == 0 && name != "." {
+		fsys.setCache(fsys.dirCache, name, bucketFSCacheEntry{})
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	fsys.setCache(fsys.dirCache, name, bucketFSCacheEntry{entries: entries})
+	for _, entry := range entries {
+		info, _ := entry.Info()
+		fsys.setCache(fsys.statCache, path.Join(name, entry.Name()), bucketFSCacheEntry{info: info})
+	}
+	return entries, nil
+}
+
+func (fsys *bucketFS) objectName(name string) string {
+	return fsys.prefix + name
+}
+
+func httpCodeOf(err error) int {
+	var httpCodeErr interface{ HttpCode() int }
+	if errors.As(err, &httpCodeErr) {
+		return httpCodeErr.HttpCode()
+	}
+	return 0
+}
+
+func (fsys *bucketFS) getCache(cache map[string]bucketFSCacheEntry, name string) (bucketFSCacheEntry, bool) {
+	if fsys.cacheTTL < 0 {
+		return bucketFSCacheEntry{}, false
+	}
+	fsys.cacheMutex.Lock()
+	defer fsys.cacheMutex.Unlock()
+
+	entry, ok := cache[name]
+	if ok && time.Now().After(entry.expiredAt) {
+		delete(cache, name)
+		ok = false
+	}
+	return entry, ok
+}
+
+func (fsys *bucketFS) setCache(cache map[string]bucketFSCacheEntry, name string, entry bucketFSCacheEntry) {
+	if fsys.cacheTTL < 0 {
+		return
+	}
+	fsys.cacheMutex.Lock()
+	defer fsys.cacheMutex.Unlock()
+
+	entry.expiredAt = time.Now().Add(fsys.cacheTTL)
+	cache[name] = entry
+}
+
+func (info *bucketFileInfo) Name() string {
+	return info.name
+}
+
+func (info *bucketFileInfo) Size() int64 {
+	if info.details == nil {
+		return 0
+	}
+	return info.details.Size
+}
+
+func (info *bucketFileInfo) Mode() fs.FileMode {
+	if info.isDir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+func (info *bucketFileInfo) ModTime() time.Time {
+	if info.details == nil {
+		return time.Time{}
+	}
+	return info.details.UploadedAt
+}
+
+func (info *bucketFileInfo) IsDir() bool {
+	return info.isDir
+}
+
+// 文件返回 *objects.ObjectDetails，目录返回 nil
+func (info *bucketFileInfo) Sys() interface{} {
+	if info.details == nil {
+		return nil
+	}
+	return info.details
+}
+
+func (file *bucketFile) Stat() (fs.FileInfo, error) {
+	return file.info, nil
+}
+
+func (file *bucketFile) Read(p []byte) (int, error) {
+	reader, err := file.getReader("read")
+	if err != nil {
+		return 0, err
+	}
+	return reader.Read(p)
+}
+
+func (file *bucketFile) ReadAt(p []byte, off int64) (int, error) {
+	reader, err := file.getReader("read")
+	if err != nil {
+		return 0, err
+	}
+	return reader.ReadAt(p, off)
+}
+
+func (file *bucketFile) Seek(offset int64, whence int) (int64, error) {
+	reader, err := file.getReader("seek")
+	if err != nil {
+		return 0, err
+	}
+	return reader.Seek(offset, whence)
+}
+
+func (file *bucketFile) Close() error {
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+
+	if file.closed {
+		return &fs.PathError{Op: "close", Path: file.info.name, Err: fs.ErrClosed}
+	}
+	file.closed = true
+	if file.reader != nil {
+		return file.reader.Close()
+	}
+	return nil
+}
+
+// 首次读取时才打开对象，仅查询文件信息时不会发出下载请求
+func (file *bucketFile) getReader(op string) (ObjectReader, error) {
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+
+	if file.closed {
+		return nil, &fs.PathError{Op: op, Path: file.info.name, Err: fs.ErrClosed}
+	}
+	if file.reader == nil {
+		readerOptions := file.fsys.readerOptions
+		readerOptions.GenerateOptions = file.fsys.generateOptions
+		readerOptions.DownloadURLsProvider = file.fsys.urlsProvider
+		reader, err := file.fsys.downloadManager.OpenObject(context.Background(), file.key, &readerOptions)
+		if err != nil {
+			return nil, &fs.PathError{Op: op, Path: file.info.name, Err: err}
+		}
+		file.reader = reader
+	}
+	return file.reader, nil
+}
+
+func (dir *bucketDir) Stat() (fs.FileInfo, error) {
+	return dir.info, nil
+}
+
+func (dir *bucketDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: dir.info.name, Err: errors.New("is a directory")}
+}
+
+func (dir *bucketDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := dir.entries[dir.offset:]
+	if n <= 0 {
+		dir.offset = len(dir.entries)
+		return append([]fs.DirEntry(nil), remaining...), nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(remaining) {
+		n = len(remaining)
+	}
+	dir.offset += n
+	return append([]fs.DirEntry(nil), remaining[:n]...), nil
+}
+
+func (dir *bucketDir) Close() error {
+	return nil
+}
@@ -0,0 +1,208 @@
+//go:build unit
+// +build unit
+
+package downloader_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/qiniu/go-sdk/v7/storagev2/apis/get_objects"
+	"github.com/qiniu/go-sdk/v7/storagev2/apis/stat_object"
+	"github.com/qiniu/go-sdk/v7/storagev2/credentials"
+	"github.com/qiniu/go-sdk/v7/storagev2/downloader"
+	"github.com/qiniu/go-sdk/v7/storagev2/http_client"
+	"github.com/qiniu/go-sdk/v7/storagev2/region"
+)
+
+func TestBucketFS(t *testing.T) {
+	putTime := time.Now().UnixNano() / 100
+	objects := map[string][]byte{
+		"root/a.txt":           []byte("hello world"),
+		"root/dir/":            {},
+		"root/dir/b.txt":       []byte("bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"),
+		"root/dir/sub/c.html":  []byte("<html></html>"),
+		"root/dir/sub/d/e.bin": bytes.Repeat([]byte{1, 2, 3}, 10000),
+		"other/f.txt":          []byte("not in fs"),
+	}
+	keys := make([]string, 0, len(objects))
+	for key := range objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var statRequests, listRequests int64
+	rsMux := http.NewServeMux()
+	rsMux.HandleFunc("/stat/", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&statRequests, 1)
+		entry, err := base64.URLEncoding.DecodeString(strings.TrimPrefix(r.URL.Path, "/stat/"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("X-ReqId", "fakereqid")
+		data, ok := objects[strings.TrimPrefix(string(entry), "bucket1:")]
+		if !ok {
+			w.WriteHeader(612)
+			w.Write([]byte(`{"error":"no such file or directory"}`))
+			return
+		}
+		jsonData, err := json.Marshal(&stat_object.Response{
+			Size:     int64(len(data)),
+			Hash:     "testhash",
+			MimeType: "application/octet-stream",
+			PutTime:  putTime,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(jsonData)
+	})
+	rsServer := httptest.NewServer(rsMux)
+	defer rsServer.Close()
+
+	rsfMux := http.NewServeMux()
+	rsfMux.HandleFunc("/list", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&listRequests, 1)
+		query := r.URL.Query()
+		if query.Get("bucket") != "bucket1" {
+			t.Fatalf("unexpected bucket")
+		}
+		prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+		limit := len(keys)
+		if l := query.Get("limit"); l != "" {
+			limit, _ = strconv.Atoi(l)
+		}
+		// 空列表无法通过 get_objects.Response 序列化，因此直接构造 JSON
+		var response struct {
+			CommonPrefixes []string                        `json:"commonPrefixes,omitempty"`
+			Items          []get_objects.ListedObjectEntry `json:"items"`
+		}
+		response.Items = []get_objects.ListedObjectEntry{}
+		commonPrefixes := make(map[string]struct{})
+		for _, key := range keys {
+			if !strings.HasPrefix(key, prefix) {
+				continue
+			}
+			if delimiter != "" {
+				if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+					commonPrefix := key[:len(prefix)+i+len(delimiter)]
+					if _, ok := commonPrefixes[commonPrefix]; !ok {
+						commonPrefixes[commonPrefix] = struct{}{}
+						response.CommonPrefixes = append(response.CommonPrefixes, commonPrefix)
+					}
+					continue
+				}
+			}
+			if len(response.Items) >= limit {
+				break
+			}
+			response.Items = append(response.Items, get_objects.ListedObjectEntry{
+				Key:      key,
+				PutTime:  putTime,
+				Hash:     "testhash",
+				Size:     int64(len(objects[key])),
+				MimeType: "application/octet-stream",
+			})
+		}
+		jsonData, err := json.Marshal(&response)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("X-ReqId", "fakereqid")
+		w.Write(jsonData)
+	})
+	rsfServer := httptest.NewServer(rsfMux)
+	defer rsfServer.Close()
+
+	ioServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := objects[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("ETag", `"testhash"`)
+		w.Header().Add("X-ReqId", "fakereqid")
+		http.ServeContent(w, r, "", time.Now(), bytes.NewReader(data))
+	}))
+	defer ioServer.Close()
+
+	downloadManager := downloader.NewDownloadManager(&downloader.DownloadManagerOptions{
+		Options: http_client.Options{
+			Regions: &region.Region{
+				Rs:  region.Endpoints{Preferred: []string{rsServer.URL}},
+				Rsf: region.Endpoints{Preferred: []string{rsfServer.URL}},
+			},
+			Credentials:         credentials.NewCredentials("testaccesskey", "testsecretkey"),
+			UseInsecureProtocol: true,
+		},
+	})
+	fsys := downloadManager.BucketFS("bucket1", &downloader.BucketFSOptions{
+		Prefix:               "root",
+		UseInsecureProtocol:  true,
+		DownloadURLsProvider: downloader.NewStaticDomainBasedURLsProvider([]string{ioServer.URL}),
+		ReaderOptions:        &downloader.ObjectReaderOptions{BlockSize: 4096, CacheBlocks: 4},
+	})
+	if err := fstest.TestFS(fsys, "a.txt", "dir/b.txt", "dir/sub/c.html", "dir/sub/d/e.bin"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsys.Stat("f.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("object outside of prefix should not exist: %v", err)
+	}
+	if info, err := fsys.Stat("dir/sub"); err != nil || !info.IsDir() {
+		t.Fatalf("dir/sub should be a directory: %v", err)
+	}
+
+	// 缓存命中时不再发出请求
+	statCount, listCount := atomic.LoadInt64(&statRequests), atomic.LoadInt64(&listRequests)
+	if _, err := fsys.ReadDir("dir"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsys.Stat("dir/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt64(&statRequests) != statCount || atomic.LoadInt64(&listRequests) != listCount {
+		t.Fatalf("cached results should be used")
+	}
+	fsys.InvalidateCache()
+	if _, err := fsys.Stat("dir/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt64(&statRequests) != statCount+1 {
+		t.Fatalf("cache should be invalidated")
+	}
+
+	// 可以作为 http.FileServer 的数据源
+	fileServer := httptest.NewServer(http.FileServer(http.FS(fsys)))
+	defer fileServer.Close()
+	req, err := http.NewRequest(http.MethodGet, fileServer.URL+"/dir/sub/d/e.bin", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Range", "bytes=100-199")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.StatusCode != http.StatusPartialContent || !bytes.Equal(body, objects["root/dir/sub/d/e.bin"][100:200]) {
+		t.Fatalf("unexpected response from file server: %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,63 @@
+package downloader
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/qiniu/go-sdk/v7/internal/checksum"
+	"github.com/qiniu/go-sdk/v7/storagev2/encryption"
+	"github.com/qiniu/go-sdk/v7/storagev2/errors"
+)
+
+// 记录首次获取到的对象 Header，用于下载完毕后校验
+type responseHeaderRecorder struct {
+	once   sync.Once
+	header http.Header
+}
+
+func (recorder *responseHeaderRecorder) wrap(options *ObjectOptions) *ObjectOptions {
+	newOptions := *options
+	onResponseHeader := options.OnResponseHeader
+	newOptions.OnResponseHeader = func(h http.Header) {
+		recorder.once.Do(func() { recorder.header = h.Clone() })
+		if onResponseHeader != nil {
+			onResponseHeader(h)
+		}
+	}
+	return &newOptions
+}
+
+// 按照对象元数据中记录的校验和校验已经下载的文件
+//
+// 对象元数据中没有记录校验和、仅下载了部分范围或对象经过客户端加密时不校验。
+func verifyDownloadedFile(filePath string, options *ObjectOptions, header http.Header) error {
+	if header == nil || (options.Header != nil && options.Header.Get("Range") != "") {
+		return nil
+	}
+	if _, err := encryption.EnvelopeFromHeader(header); err != encryption.ErrNotEncrypted {
+		return nil
+	}
+	expectedCRC64, expectedMD5 := header.Get(checksum.MetadataKeyCRC64), header.Get(checksum.MetadataKeyMD5)
+	if expectedCRC64 == "" && expectedMD5 == "" {
+		return nil
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	hasher := checksum.NewHasher(expectedCRC64 != "", expectedMD5 != "")
+	if _, err = hasher.ReadFrom(file); err != nil {
+		return err
+	}
+	if expectedCRC64 != "" && hasher.CRC64() != expectedCRC64 {
+		return errors.ChecksumMismatchError{Algorithm: "CRC64", Expected: expectedCRC64, Actual: hasher.CRC64()}
+	}
+	if expectedMD5 != "" && !strings.EqualFold(hasher.MD5(), expectedMD5) {
+		return errors.ChecksumMismatchError{Algorithm: "MD5", Expected: expectedMD5, Actual: hasher.MD5()}
+	}
+	return nil
+}
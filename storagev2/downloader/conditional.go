@@ -0,0 +1,134 @@
+package downloader
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/qiniu/go-sdk/v7/internal/etag"
+)
+
+type (
+	// 条件下载选项
+	ConditionalOptions struct {
+		// 附属文件路径，下载成功后在其中记录对象 Etag 和本地文件的大小及修改时间
+		//
+		// 如果设置且本地文件自记录后没有被修改过，则使用记录的 Etag 作为 If-None-Match；
+		// 如果不设置，则每次根据本地文件内容计算七牛 Etag，这仅对没有使用分片上传 V2 上传的对象有效。
+		SidecarFilePath string
+
+		// 是否附加 If-Modified-Since，值为本地文件的修改时间，默认为不附加
+		IfModifiedSince bool
+
+		// 对象没有变化时的回调函数
+		OnNotModified func()
+	}
+
+	conditionalSidecar struct {
+		ETag    string `json:"etag"`
+		Size    int64  `json:"size"`
+		ModTime int64  `json:"mod_time"`
+	}
+)
+
+// 根据本地文件生成条件请求 Header，本地文件不存在时返回 nil
+func conditionalHeaders(filePath string, options *ConditionalOptions) (http.Header, error) {
+	fileInfo, err := os.Stat(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	} else if !fileInfo.Mode().IsRegular() {
+		return nil, nil
+	}
+
+	headers := make(http.Header)
+	if options.SidecarFilePath != "" {
+		if sidecar, err := readConditionalSidecar(options.SidecarFilePath); err == nil &&
+			sidecar.ETag != "" && sidecar.Size == fileInfo.Size() && sidecar.ModTime == fileInfo.ModTime().UnixNano() {
+			headers.Set("If-None-Match", strconv.Quote(sidecar.ETag))
+		}
+	} else {
+		localETag, err := fileETag(filePath)
+		if err != nil {
+			return nil, err
+		}
+		headers.Set("If-None-Match", strconv.Quote(localETag))
+	}
+	if options.IfModifiedSince {
+		headers.Set("If-Modified-Since", fileInfo.ModTime().UTC().Format(http.TimeFormat))
+	}
+	if len(headers) == 0 {
+		return nil, nil
+	}
+	return headers, nil
+}
+
+// 为对象下载参数附加条件请求 Header，附加了 Range 的下载不做条件请求
+func withConditionalHeaders(filePath string, options *ObjectOptions) (*ObjectOptions, bool, error) {
+	if options.Header != nil && options.Header.Get("Range") != "" {
+		return options, false, nil
+	}
+	headers, err := conditionalHeaders(filePath, options.Conditional)
+	if err != nil || headers == nil {
+		return options, false, err
+	}
+	newOptions := *options
+	newOptions.Header = cloneHeader(options.Header)
+	for key, values := range headers {
+		newOptions.Header[key] = values
+	}
+	return &newOptions, true, nil
+}
+
+func readConditionalSidecar(sidecarFilePath string) (*conditionalSidecar, error) {
+	data, err := os.ReadFile(sidecarFilePath)
+	if err != nil {
+		return nil, err
+	}
+	var sidecar conditionalSidecar
+	if err = json.Unmarshal(data, &sidecar); err != nil {
+		return nil, err
+	}
+	return &sidecar, nil
+}
+
+// 下载成功后记录对象 Etag 和本地文件的状态
+func writeConditionalSidecar(filePath, sidecarFilePath string, header http.Header) error {
+	objectETag := parseEtag(header.Get("Etag"))
+	if objectETag == "" {
+		if err := os.Remove(sidecarFilePath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(&conditionalSidecar{ETag: objectETag, Size: fileInfo.Size(), ModTime: fileInfo.ModTime().UnixNano()})
+	if err != nil {
+		return err
+	}
+	tmpFilePath := sidecarFilePath + ".tmp." + strconv.FormatInt(time.Now().UnixNano(), 36)
+	if err = os.WriteFile(tmpFilePath, data, 0600); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpFilePath, sidecarFilePath); err != nil {
+		os.Remove(tmpFilePath)
+		return err
+	}
+	return nil
+}
+
+// 计算本地文件的七牛 Etag
+func fileETag(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	return etag.FromReader(file)
+}
@@ -0,0 +1,298 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/qiniu/go-sdk/v7/storagev2/downloader/destination"
+	"github.com/qiniu/go-sdk/v7/storagev2/encryption"
+)
+
+type (
+	// 解密数据目标
+	//
+	// 下载的是密文，写入被封装的数据目标的是明文。由于在获取到对象 Header 之前无法得知对象是否加密，
+	// 因此需要在 OnResponseHeader 回调中调用 init 完成初始化。
+	// 未加密的对象将直接写入被封装的数据目标。
+	decryptingDestination struct {
+		ctx         context.Context
+		dest        destination.Destination
+		keyProvider encryption.KeyProvider
+		ranged      bool
+		rangeFrom   uint64
+		rangeEnd    uint64 // 不包含，0 表示直到对象末尾
+
+		initOnce   sync.Once
+		initErr    error
+		cipher     *encryption.Cipher
+		plainSize  uint64 // 对象明文大小
+		firstFrame uint64 // 下载的第一帧序号
+		plainFrom  uint64 // 需要写入的明文起始位置（包含）
+		plainTo    uint64 // 需要写入的明文结束位置（不包含）
+		whole      *decryptingPart
+		written    uint64 // 加密对象已经写入的明文大小
+	}
+
+	decryptingPart struct {
+		dest                 *decryptingDestination
+		writer               destination.PartWriter
+		offset, size         uint64
+		nextFrame, lastFrame uint64
+		haveDownloaded       uint64
+	}
+)
+
+// 创建解密数据目标，并将明文范围请求转换为密文范围请求
+func newDecryptingDestination(ctx context.Context, dest destination.Destination, keyProvider encryption.KeyProvider, header http.Header) (*decryptingDestination, http.Header, error) {
+	dd := &decryptingDestination{ctx: ctx, dest: dest, keyProvider: keyProvider}
+	if rangeHeader := header.Get("Range"); rangeHeader != "" {
+		from, end, err := parsePlaintextRange(rangeHeader)
+		if err != nil {
+			return nil, nil, err
+		}
+		dd.ranged, dd.rangeFrom, dd.rangeEnd = true, from, end
+		header = cloneHeader(header)
+		if end > 0 {
+			setRange(header, from/encryption.FrameSize*encryption.CiphertextFrameSize, ((end-1)/encryption.FrameSize+1)*encryption.CiphertextFrameSize)
+		} else {
+			header.Set("Range", fmt.Sprintf("bytes=%d-", from/encryption.FrameSize*encryption.CiphertextFrameSize))
+		}
+	}
+	return dd, header, nil
+}
+
+// 解析明文范围请求，返回的 end 不包含，0 表示直到对象末尾
+func parsePlaintextRange(rangeHeader string) (from, end uint64, err error) {
+	spec := strings.TrimPrefix(rangeHeader, "bytes=")
+	items := strings.Split(spec, "-")
+	if spec == rangeHeader || len(items) != 2 || items[0] == "" {
+		return 0, 0, fmt.Errorf("unsupported range for encrypted object: %s", rangeHeader)
+	}
+	if from, err = strconv.ParseUint(items[0], 10, 64); err != nil {
+		return
+	}
+	if items[1] != "" {
+		var to uint64
+		if to, err = strconv.ParseUint(items[1], 10, 64); err != nil {
+			return
+		} else if to < from {
+			return 0, 0, fmt.Errorf("invalid range: %s", rangeHeader)
+		}
+		end = to + 1
+	}
+	return
+}
+
+func (dd *decryptingDestination) init(header http.Header) {
+	dd.initOnce.Do(func() {
+		dd.initErr = dd._init(header)
+	})
+}
+
+func (dd *decryptingDestination) _init(header http.Header) error {
+	envelope, err := encryption.EnvelopeFromHeader(header)
+	if err == encryption.ErrNotEncrypted {
+		if dd.ranged {
+			return errors.New("range has been converted for encrypted object, but the object is not encrypted")
+		}
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if dd.cipher, err = envelope.OpenCipher(dd.ctx, dd.keyProvider); err != nil {
+		return err
+	}
+
+	var cipherFrom, cipherSize uint64
+	if contentRange := header.Get("Content-Range"); contentRange != "" {
+		var cipherTo uint64
+		if _, err = fmt.Sscanf(contentRange, "bytes %d-%d/%d", &cipherFrom, &cipherTo, &cipherSize); err != nil {
+			return err
+		}
+	} else if cipherSize, err = strconv.ParseUint(header.Get("Content-Length"), 10, 64); err != nil {
+		return errors.New("unable to determine the size of encrypted object")
+	}
+	if cipherFrom%encryption.CiphertextFrameSize != 0 {
+		return errors.New("unaligned content range of encrypted object")
+	}
+	if dd.plainSize, err = encryption.PlaintextSize(cipherSize); err != nil {
+		return err
+	}
+	dd.firstFrame = cipherFrom / encryption.CiphertextFrameSize
+	dd.plainFrom, dd.plainTo = dd.firstFrame*encryption.FrameSize, dd.plainSize
+	if dd.ranged {
+		dd.plainFrom = dd.rangeFrom
+		if dd.rangeEnd > 0 && dd.rangeEnd < dd.plainTo {
+			dd.plainTo = dd.rangeEnd
+		}
+		if dd.firstFrame != dd.rangeFrom/encryption.FrameSize {
+			return errors.New("unexpected content range of encrypted object")
+		}
+	}
+	if dd.plainFrom > dd.plainTo {
+		dd.plainFrom = dd.plainTo
+	}
+	return nil
+}
+
+// 明文偏移量对应的帧序号
+func (dd *decryptingDestination) frameOf(plainOffset uint64) uint64 {
+	return plainOffset / encryption.FrameSize
+}
+
+// 帧的密文大小
+func (dd *decryptingDestination) frameCipherSize(frame uint64) uint64 {
+	size := dd.plainSize - frame*encryption.FrameSize
+	if size > encryption.FrameSize {
+		size = encryption.FrameSize
+	}
+	return size + encryption.TagSize
+}
+
+// 明文范围 [from, to) 对应的解密分片，偏移量相对于下载的第一帧
+func (dd *decryptingDestination) newPart(writer destination.PartWriter, from, to uint64) *decryptingPart {
+	firstFrame, lastFrame := dd.frameOf(from), dd.frameOf(to-1)
+	return &decryptingPart{
+		dest:      dd,
+		writer:    writer,
+		offset:    (firstFrame - dd.firstFrame) * encryption.CiphertextFrameSize,
+		size:      (lastFrame-firstFrame)*encryption.CiphertextFrameSize + dd.frameCipherSize(lastFrame),
+		nextFrame: firstFrame,
+		lastFrame: lastFrame,
+	}
+}
+
+func (dd *decryptingDestination) CopyFrom(r io.Reader, progress func(uint64)) (uint64, error) {
+	if dd.initErr != nil {
+		return 0, dd.initErr
+	} else if dd.cipher == nil {
+		return dd.dest.CopyFrom(r, progress)
+	} else if dd.plainFrom >= dd.plainTo {
+		return 0, nil
+	}
+	if dd.whole == nil {
+		dd.whole = dd.newPart(dd.dest, dd.plainFrom, dd.plainTo)
+	}
+	return dd.whole.CopyFrom(r, progress)
+}
+
+func (dd *decryptingDestination) Split(totalSize, partSize uint64, options *destination.SplitOptions) ([]destination.Part, error) {
+	if dd.initErr != nil {
+		return nil, dd.initErr
+	} else if dd.cipher == nil {
+		return dd.dest.Split(totalSize, partSize, options)
+	}
+
+	framesPerPart := partSize / encryption.CiphertextFrameSize
+	if framesPerPart == 0 {
+		framesPerPart = 1
+	}
+	underlyingParts, err := dd.dest.Split(dd.plainTo-dd.plainFrom, framesPerPart*encryption.FrameSize, nil)
+	if err != nil {
+		return nil, err
+	}
+	parts := make([]destination.Part, 0, len(underlyingParts))
+	for _, underlyingPart := range underlyingParts {
+		if underlyingPart.Size() == 0 {
+			continue
+		}
+		from := dd.plainFrom + underlyingPart.Offset()
+		parts = append(parts, dd.newPart(underlyingPart, from, from+underlyingPart.Size()))
+	}
+	return parts, nil
+}
+
+func (dd *decryptingDestination) DestinationID() (string, error) {
+	if dd.initErr != nil {
+		return "", dd.initErr
+	} else if dd.cipher == nil {
+		return dd.dest.DestinationID()
+	}
+	return "", nil // 加密对象不支持断点续传
+}
+
+func (dd *decryptingDestination) Close() error {
+	return nil // 被封装的数据目标由调用方关闭
+}
+
+func (dd *decryptingDestination) GetFile() *os.File {
+	if dd.cipher == nil {
+		return dd.dest.GetFile()
+	}
+	return nil
+}
+
+// 对象是否加密，仅在下载完成后调用
+func (dd *decryptingDestination) encrypted() bool {
+	return dd.cipher != nil
+}
+
+func (dd *decryptingDestination) plaintextWritten() uint64 {
+	return atomic.LoadUint64(&dd.written)
+}
+
+func (dp *decryptingPart) CopyFrom(r io.Reader, progress func(uint64)) (uint64, error) {
+	var (
+		n     uint64
+		dd    = dp.dest
+		frame = make([]byte, encryption.CiphertextFrameSize)
+		plain = make([]byte, 0, encryption.FrameSize)
+		err   error
+	)
+	for dp.nextFrame <= dp.lastFrame {
+		frameSize := dd.frameCipherSize(dp.nextFrame)
+		if _, err = io.ReadFull(r, frame[:frameSize]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return n, err
+		}
+		isFinal := (dp.nextFrame+1)*encryption.FrameSize >= dd.plainSize
+		if plain, err = dd.cipher.OpenFrame(plain[:0], frame[:frameSize], dp.nextFrame, isFinal); err != nil {
+			return n, err
+		}
+		frameOffset := dp.nextFrame * encryption.FrameSize
+		from, to := frameOffset, frameOffset+uint64(len(plain))
+		if from < dd.plainFrom {
+			from = dd.plainFrom
+		}
+		if to > dd.plainTo {
+			to = dd.plainTo
+		}
+		if from < to {
+			written, err := dp.writer.CopyFrom(bytes.NewReader(plain[from-frameOffset:to-frameOffset]), nil)
+			atomic.AddUint64(&dd.written, written)
+			if err != nil {
+				return n, err
+			}
+		}
+		n += frameSize
+		dp.haveDownloaded += frameSize
+		dp.nextFrame += 1
+		if progress != nil {
+			progress(dp.haveDownloaded)
+		}
+	}
+	return n, nil
+}
+
+func (dp *decryptingPart) Size() uint64 {
+	return dp.size
+}
+
+func (dp *decryptingPart) Offset() uint64 {
+	return dp.offset
+}
+
+func (dp *decryptingPart) HaveDownloaded() uint64 {
+	return dp.haveDownloaded
+}
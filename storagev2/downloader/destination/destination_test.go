@@ -4,17 +4,21 @@
 package destination_test
 
 import (
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
 	"crypto/md5"
 	"errors"
 	"io"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/qiniu/go-sdk/v7/storagev2/downloader/destination"
+	resumablerecorder "github.com/qiniu/go-sdk/v7/storagev2/downloader/resumable_recorder"
 )
 
 func TestSeekableDestination(t *testing.T) {
@@ -127,3 +131,202 @@ func TestUnseekableDestination(t *testing.T) {
 		t.Fatalf("unexpected downloading progress")
 	}
 }
+
+type recordsMedium []resumablerecorder.ResumableRecord
+
+func (m *recordsMedium) Next(record *resumablerecorder.ResumableRecord) error {
+	if len(*m) == 0 {
+		return io.EOF
+	}
+	*record = (*m)[0]
+	*m = (*m)[1:]
+	return nil
+}
+
+func (m *recordsMedium) Close() error {
+	return nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func TestTeeDestination(t *testing.T) {
+	data := make([]byte, 10*1024)
+	rand.New(rand.NewSource(time.Now().UnixNano())).Read(data)
+
+	tmpDir, err := os.MkdirTemp("", "test-tee-destination-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// 数据目标 1 已经写入了第一个分片的前 512 字节，数据目标 2 没有 ID，不会恢复
+	file1, err := os.Create(filepath.Join(tmpDir, "file1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = file1.Write(bytes.Repeat([]byte{'x'}, 512)); err != nil {
+		t.Fatal(err)
+	}
+	file2, err := os.Create(filepath.Join(tmpDir, "file2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dest := destination.NewTeeDestination(
+		destination.NewWriteAtCloserDestination(file1, file1.Name()),
+		destination.NewWriteAtCloserDestination(file2, ""),
+	)
+	if destinationID, err := dest.DestinationID(); err != nil {
+		t.Fatal(err)
+	} else if destinationID != file1.Name() {
+		t.Fatalf("unexpected destination id: %s", destinationID)
+	}
+	parts, err := dest.Split(uint64(len(data)), 1024, &destination.SplitOptions{
+		Medium: &recordsMedium{{Offset: 0, PartSize: 1024, PartWritten: 512}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 10 {
+		t.Fatalf("unexpected parts count: %d", len(parts))
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, len(parts))
+	for i, part := range parts {
+		wg.Add(1)
+		go func(i int, part destination.Part) {
+			defer wg.Done()
+			if part.HaveDownloaded() != 0 {
+				errs[i] = errors.New("unexpected downloaded size")
+				return
+			}
+			var lastDownloaded uint64
+			_, errs[i] = part.CopyFrom(bytes.NewReader(data[part.Offset():part.Offset()+part.Size()]), func(downloaded uint64) {
+				lastDownloaded = downloaded
+			})
+			if errs[i] == nil && lastDownloaded != part.Size() {
+				errs[i] = errors.New("unexpected downloading progress")
+			}
+		}(i, part)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = dest.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := os.ReadFile(file1.Name()); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(content[:512], bytes.Repeat([]byte{'x'}, 512)) || !bytes.Equal(content[512:], data[512:]) {
+		t.Fatalf("written data should be skipped")
+	}
+	if content, err := os.ReadFile(file2.Name()); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(content, data) {
+		t.Fatalf("unexpected file2 content")
+	}
+
+	// 不可寻址的数据目标只能作为单个分片写入
+	var buf bytes.Buffer
+	file3, err := os.Create(filepath.Join(tmpDir, "file3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dest = destination.NewTeeDestination(
+		destination.NewWriteAtCloserDestination(file3, file3.Name()),
+		destination.NewWriteCloserDestination(nopWriteCloser{&buf}, ""),
+	)
+	if parts, err = dest.Split(uint64(len(data)), 1024, nil); err != nil {
+		t.Fatal(err)
+	} else if len(parts) != 1 {
+		t.Fatalf("unexpected parts count: %d", len(parts))
+	}
+	if n, err := parts[0].CopyFrom(bytes.NewReader(data), nil); err != nil {
+		t.Fatal(err)
+	} else if n != uint64(len(data)) {
+		t.Fatalf("unexpected copied size: %d", n)
+	}
+	if err = dest.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := os.ReadFile(file3.Name()); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(content, data) || !bytes.Equal(buf.Bytes(), data) {
+		t.Fatalf("unexpected tee content")
+	}
+}
+
+func TestTransformers(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "test-transformers-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	makeTarGz := func(files map[string]string) []byte {
+		var buf bytes.Buffer
+		gzipWriter := gzip.NewWriter(&buf)
+		tarWriter := tar.NewWriter(gzipWriter)
+		for name, content := range files {
+			if err := tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tarWriter.Write([]byte(content)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := tarWriter.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := gzipWriter.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	extractDir := filepath.Join(tmpDir, "extracted")
+	if err = destination.NewGzipTransformer(destination.NewTarExtractor(extractDir)).Transform(bytes.NewReader(makeTarGz(map[string]string{
+		"a.txt":     "file a",
+		"dir/b.txt": "file b",
+	}))); err != nil {
+		t.Fatal(err)
+	}
+	for name, expected := range map[string]string{"a.txt": "file a", "dir/b.txt": "file b"} {
+		if content, err := os.ReadFile(filepath.Join(extractDir, filepath.FromSlash(name))); err != nil {
+			t.Fatal(err)
+		} else if string(content) != expected {
+			t.Fatalf("unexpected content of %s: %s", name, content)
+		}
+	}
+
+	if err = destination.NewGzipTransformer(destination.NewTarExtractor(extractDir)).Transform(bytes.NewReader(makeTarGz(map[string]string{
+		"../escaped.txt": "escaped",
+	}))); err == nil {
+		t.Fatalf("tar entry outside of the target directory should be rejected")
+	}
+	if _, err = os.Stat(filepath.Join(tmpDir, "escaped.txt")); !os.IsNotExist(err) {
+		t.Fatalf("escaped file should not be created")
+	}
+
+	var gzipped bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	gzipWriter.Write([]byte("hello world"))
+	gzipWriter.Close()
+	filePath := filepath.Join(tmpDir, "hello.txt")
+	if err = destination.NewGzipTransformer(destination.NewFileTransformer(filePath)).Transform(&gzipped); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := os.ReadFile(filePath); err != nil {
+		t.Fatal(err)
+	} else if string(content) != "hello world" {
+		t.Fatalf("unexpected decompressed content: %s", content)
+	}
+}
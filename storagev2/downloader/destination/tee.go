@@ -0,0 +1,256 @@
+package destination
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	resumablerecorder "github.com/qiniu/go-sdk/v7/storagev2/downloader/resumable_recorder"
+)
+
+type (
+	teeDestination struct {
+		destinations []Destination
+	}
+
+	teePart struct {
+		parts []Part
+	}
+
+	recordsMedium struct {
+		records []resumablerecorder.ResumableRecord
+	}
+
+	skipWriter struct {
+		w    io.Writer
+		skip uint64
+	}
+)
+
+var errPartsNotAligned = errors.New("parts of tee destinations are not aligned")
+
+// 将多个数据目标组合为一个数据目标，下载的数据将同时写入每个数据目标
+//
+// 如果所有数据目标的切片方式一致，则支持并发分片下载，否则将作为单个分片顺序下载。
+// 断点续传时，每个分片从所有数据目标中已经写入最少的位置开始下载，已经写入更多数据的数据目标将跳过重复的数据。
+// 没有数据目标 ID 的数据目标不会从可恢复记录中恢复。
+func NewTeeDestination(destinations ...Destination) Destination {
+	return &teeDestination{append([]Destination(nil), destinations...)}
+}
+
+func (td *teeDestination) CopyFrom(r io.Reader, progress func(uint64)) (uint64, error) {
+	copyFroms := make([]func(io.Reader) error, len(td.destinations))
+	skips := make([]uint64, len(td.destinations))
+	for i, dest := range td.destinations {
+		dest := dest
+		copyFroms[i] = func(r io.Reader) error {
+			_, err := dest.CopyFrom(r, nil)
+			return err
+		}
+	}
+	return teeCopy(r, copyFroms, skips, progress)
+}
+
+func (td *teeDestination) Split(totalSize, partSize uint64, options *SplitOptions) ([]Part, error) {
+	var records []resumablerecorder.ResumableRecord
+	if options != nil && options.Medium != nil {
+		for {
+			var record resumablerecorder.ResumableRecord
+			if err := options.Medium.Next(&record); err != nil {
+				break
+			}
+			records = append(records, record)
+		}
+	}
+
+	partsList, err := td.split(totalSize, partSize, records)
+	if err != nil {
+		return nil, err
+	}
+	if !partsAligned(partsList) {
+		if partsList, err = td.split(totalSize, totalSize, records); err != nil {
+			return nil, err
+		} else if !partsAligned(partsList) {
+			return nil, errPartsNotAligned
+		}
+	}
+
+	var parts []Part
+	if len(partsList) > 0 {
+		parts = make([]Part, len(partsList[0]))
+		for i := range parts {
+			subParts := make([]Part, len(partsList))
+			for j := range partsList {
+				subParts[j] = partsList[j][i]
+			}
+			parts[i] = &teePart{subParts}
+		}
+	}
+	return parts, nil
+}
+
+func (td *teeDestination) split(totalSize, partSize uint64, records []resumablerecorder.ResumableRecord) ([][]Part, error) {
+	partsList := make([][]Part, 0, len(td.destinations))
+	for _, dest := range td.destinations {
+		var splitOptions SplitOptions
+		if destinationID, err := dest.DestinationID(); err != nil {
+			return nil, err
+		} else if destinationID != "" && len(records) > 0 {
+			splitOptions.Medium = &recordsMedium{records}
+		}
+		parts, err := dest.Split(totalSize, partSize, &splitOptions)
+		if err != nil {
+			return nil, err
+		}
+		partsList = append(partsList, parts)
+	}
+	return partsList, nil
+}
+
+func partsAligned(partsList [][]Part) bool {
+	for _, parts := range partsList[min(1, len(partsList)):] {
+		if len(parts) != len(partsList[0]) {
+			return false
+		}
+		for i, part := range parts {
+			if part.Offset() != partsList[0][i].Offset() || part.Size() != partsList[0][i].Size() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// 数据目标 ID 由所有非空的数据目标 ID 组合而成
+func (td *teeDestination) DestinationID() (string, error) {
+	destinationIDs := make([]string, 0, len(td.destinations))
+	for _, dest := range td.destinations {
+		destinationID, err := dest.DestinationID()
+		if err != nil {
+			return "", err
+		} else if destinationID != "" {
+			destinationIDs = append(destinationIDs, destinationID)
+		}
+	}
+	return strings.Join(destinationIDs, "\n"), nil
+}
+
+func (td *teeDestination) Close() (err error) {
+	for _, dest := range td.destinations {
+		if closeErr := dest.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return
+}
+
+// 返回第一个数据目标的文件
+func (td *teeDestination) GetFile() *os.File {
+	if len(td.destinations) > 0 {
+		return td.destinations[0].GetFile()
+	}
+	return nil
+}
+
+func (tp *teePart) Size() uint64 {
+	return tp.parts[0].Size()
+}
+
+func (tp *teePart) Offset() uint64 {
+	return tp.parts[0].Offset()
+}
+
+// 返回所有数据目标中已经写入最少的数据量
+func (tp *teePart) HaveDownloaded() uint64 {
+	haveDownloaded := tp.parts[0].HaveDownloaded()
+	for _, part := range tp.parts[1:] {
+		haveDownloaded = min(haveDownloaded, part.HaveDownloaded())
+	}
+	return haveDownloaded
+}
+
+func (tp *teePart) CopyFrom(r io.Reader, progress func(uint64)) (uint64, error) {
+	var newProgress func(uint64)
+
+	haveCopied := tp.HaveDownloaded()
+	restSize := tp.Size() - haveCopied
+	if restSize == 0 {
+		return 0, nil
+	}
+	if progress != nil {
+		newProgress = func(downloaded uint64) { progress(haveCopied + downloaded) }
+	}
+	copyFroms := make([]func(io.Reader) error, len(tp.parts))
+	skips := make([]uint64, len(tp.parts))
+	for i, part := range tp.parts {
+		part := part
+		copyFroms[i] = func(r io.Reader) error {
+			_, err := part.CopyFrom(r, nil)
+			return err
+		}
+		skips[i] = part.HaveDownloaded() - haveCopied
+	}
+	return teeCopy(io.LimitReader(r, int64(restSize)), copyFroms, skips, newProgress)
+}
+
+// 将 r 中的数据通过管道同时复制给每个 copyFrom，每个 copyFrom 跳过 skips 中对应数量的字节
+func teeCopy(r io.Reader, copyFroms []func(io.Reader) error, skips []uint64, progress func(uint64)) (uint64, error) {
+	var (
+		wg         sync.WaitGroup
+		writers    = make([]io.Writer, len(copyFroms))
+		pipeWriter = make([]*io.PipeWriter, len(copyFroms))
+		errs       = make([]error, len(copyFroms))
+	)
+	for i, copyFrom := range copyFroms {
+		pr, pw := io.Pipe()
+		writers[i] = &skipWriter{pw, skips[i]}
+		pipeWriter[i] = pw
+		wg.Add(1)
+		go func(i int, copyFrom func(io.Reader) error) {
+			defer wg.Done()
+			errs[i] = copyFrom(pr)
+			if errs[i] != nil {
+				pr.CloseWithError(errs[i])
+			} else {
+				pr.Close()
+			}
+		}(i, copyFrom)
+	}
+	n, err := copyBuffer(io.MultiWriter(writers...), r, progress)
+	for _, pw := range pipeWriter {
+		pw.CloseWithError(err)
+	}
+	wg.Wait()
+	for _, e := range errs {
+		if e != nil {
+			return n, e
+		}
+	}
+	return n, err
+}
+
+func (sw *skipWriter) Write(p []byte) (int, error) {
+	if sw.skip >= uint64(len(p)) {
+		sw.skip -= uint64(len(p))
+		return len(p), nil
+	}
+	skipped := int(sw.skip)
+	sw.skip = 0
+	n, err := sw.w.Write(p[skipped:])
+	return skipped + n, err
+}
+
+func (rm *recordsMedium) Next(record *resumablerecorder.ResumableRecord) error {
+	if len(rm.records) == 0 {
+		return io.EOF
+	}
+	*record = rm.records[0]
+	rm.records = rm.records[1:]
+	return nil
+}
+
+func (rm *recordsMedium) Close() error {
+	return nil
+}
@@ -0,0 +1,146 @@
+package destination
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type (
+	// 数据转换，用于在下载完成后对数据做进一步处理，例如解压缩或解包
+	Transformer interface {
+		// 读取数据并转换
+		Transform(io.Reader) error
+	}
+
+	// 解压缩函数
+	Decompress func(io.Reader) (io.ReadCloser, error)
+
+	decompressTransformer struct {
+		decompress Decompress
+		next       Transformer
+	}
+
+	fileTransformer struct {
+		filePath string
+	}
+
+	tarExtractor struct {
+		dirPath string
+	}
+)
+
+// 创建解压缩转换，数据解压缩后交给 next 继续转换
+//
+// 可以通过 decompress 支持任意压缩格式，例如通过第三方库支持 zstd
+func NewDecompressTransformer(decompress Decompress, next Transformer) Transformer {
+	return &decompressTransformer{decompress, next}
+}
+
+// 创建 gzip 解压缩转换，数据解压缩后交给 next 继续转换
+func NewGzipTransformer(next Transformer) Transformer {
+	return NewDecompressTransformer(func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	}, next)
+}
+
+func (t *decompressTransformer) Transform(r io.Reader) error {
+	decompressed, err := t.decompress(r)
+	if err != nil {
+		return err
+	}
+	defer decompressed.Close()
+	return t.next.Transform(decompressed)
+}
+
+// 创建写入文件的转换，数据先写入临时文件，完成后再重命名为 filePath
+func NewFileTransformer(filePath string) Transformer {
+	return &fileTransformer{filePath}
+}
+
+func (t *fileTransformer) Transform(r io.Reader) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(t.filePath), "."+filepath.Base(t.filePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpFilePath := tmpFile.Name()
+	if _, err = io.Copy(tmpFile, r); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFilePath)
+		return err
+	}
+	if err = tmpFile.Close(); err != nil {
+		os.Remove(tmpFilePath)
+		return err
+	}
+	if err = os.Rename(tmpFilePath, t.filePath); err != nil {
+		os.Remove(tmpFilePath)
+		return err
+	}
+	return nil
+}
+
+// 创建 tar 解包转换，数据解包到 dirPath 目录
+//
+// 仅解包目录和普通文件，其他类型的条目将被忽略，条目路径不能超出 dirPath 目录
+func NewTarExtractor(dirPath string) Transformer {
+	return &tarExtractor{dirPath}
+}
+
+func (t *tarExtractor) Transform(r io.Reader) error {
+	if err := os.MkdirAll(t.dirPath, 0700); err != nil {
+		return err
+	}
+	tarReader := tar.NewReader(r)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		targetPath, err := t.targetPath(header.Name)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err = os.MkdirAll(targetPath, 0700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err = os.MkdirAll(filepath.Dir(targetPath), 0700); err != nil {
+				return err
+			}
+			if err = extractFile(targetPath, tarReader, header.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (t *tarExtractor) targetPath(name string) (string, error) {
+	targetPath := filepath.Join(t.dirPath, filepath.FromSlash(name))
+	if relativePath, err := filepath.Rel(t.dirPath, targetPath); err != nil {
+		return "", err
+	} else if relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) || filepath.IsAbs(name) {
+		return "", fmt.Errorf("tar entry %q is outside of the target directory", name)
+	}
+	return targetPath, nil
+}
+
+func extractFile(filePath string, r io.Reader, perm os.FileMode) error {
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm|0600)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(file, r); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
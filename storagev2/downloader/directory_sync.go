@@ -0,0 +1,57 @@
+package downloader
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 计算对象在目标目录中的本地路径，本地路径不能超出目标目录
+func localPathOfObject(targetDirPath, relativePath string) (string, error) {
+	fullPath := filepath.Join(targetDirPath, relativePath)
+	if rel, err := filepath.Rel(targetDirPath, fullPath); err != nil {
+		return "", err
+	} else if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("local path %q is outside of the target directory", relativePath)
+	}
+	return fullPath, nil
+}
+
+// 判断本地文件的大小和哈希值是否与对象一致
+func isLocalFileUnchanged(filePath string, size int64, objectETag string) (bool, error) {
+	fileInfo, err := os.Stat(filePath)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	} else if !fileInfo.Mode().IsRegular() || fileInfo.Size() != size || objectETag == "" {
+		return false, nil
+	}
+	localETag, err := fileETag(filePath)
+	if err != nil {
+		return false, err
+	}
+	return localETag == objectETag, nil
+}
+
+// 删除目标目录中不在 keep 中的文件，目录本身不会被删除
+func deleteExtraneousFiles(targetDirPath string, keep map[string]struct{}, onDeleted func(filePath string)) error {
+	return filepath.WalkDir(targetDirPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		} else if entry.IsDir() {
+			return nil
+		} else if _, ok := keep[filepath.Clean(path)]; ok {
+			return nil
+		}
+		if err = os.Remove(path); err != nil {
+			return err
+		}
+		if onDeleted != nil {
+			onDeleted(path)
+		}
+		return nil
+	})
+}
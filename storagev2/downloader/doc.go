@@ -26,6 +26,48 @@
 //	    BucketName: "my-bucket",
 //	})
 //
+// 目录下载可以通过 ShouldDownloadObject 过滤对象，通过 UpdateFilePath 更改本地路径，
+// 通过 SkipUnchanged 跳过大小和哈希值都没有变化的对象，并通过 DeleteExtraneousFiles 删除远端已经不存在的本地文件，实现目录同步：
+//
+//	err := downloadManager.DownloadDirectory(ctx, "/tmp/backup", &downloader.DirectoryOptions{
+//	    BucketName:            "my-bucket",
+//	    SkipUnchanged:         true,
+//	    DeleteExtraneousFiles: true,
+//	})
+//
+// # 条件下载
+//
+// 通过 [ObjectOptions] 的 Conditional 字段，仅在对象发生变化时下载，对象没有变化时服务器返回 304，本地文件保持不变：
+//
+//	n, err := downloadManager.DownloadToFile(ctx, "artifact.tar", "/cache/artifact.tar", &downloader.ObjectOptions{
+//	    GenerateOptions: downloader.GenerateOptions{BucketName: "my-bucket"},
+//	    Conditional:     &downloader.ConditionalOptions{SidecarFilePath: "/cache/artifact.tar.etag"},
+//	})
+//
+// # 多目标与数据转换
+//
+// 通过 destination.NewTeeDestination 可以将一次下载同时写入多个数据目标，并且支持断点续传。
+// 通过 [ObjectOptions] 的 Transformer 字段，可以在下载到文件并通过完整性校验后对文件做进一步处理，
+// 例如同时保留 .tar.gz 原始文件并解包到目录。zstd 等其他压缩格式可以通过 destination.NewDecompressTransformer 接入第三方解压缩库：
+//
+//	n, err := downloadManager.DownloadToFile(ctx, "dataset.tar.gz", "/data/dataset.tar.gz", &downloader.ObjectOptions{
+//	    GenerateOptions: downloader.GenerateOptions{BucketName: "my-bucket"},
+//	    VerifyChecksums: true,
+//	    Transformer:     destination.NewGzipTransformer(destination.NewTarExtractor("/data/dataset")),
+//	})
+//
+// # 归档对象
+//
+// 设置 [DownloadManagerOptions] 的 ArchiveRestore 字段后，下载尚未解冻的归档存储或深度归档存储对象时，
+// 下载管理器将自动发起解冻并轮询解冻状态，解冻完成后再下载。目录下载时，归档对象通过批处理批量解冻，并在其他对象下载后再下载：
+//
+//	downloadManager := downloader.NewDownloadManager(&downloader.DownloadManagerOptions{
+//	    ArchiveRestore: &downloader.ArchiveRestoreOptions{
+//	        FreezeAfterDays: 1,
+//	        PollBackoff:     backoff.NewFixedBackoff(time.Minute),
+//	    },
+//	})
+//
 // # URL 生成策略
 //
 // 通过 [DownloadURLsProvider] 接口控制下载 URL 的生成方式：
@@ -35,6 +77,11 @@
 //   - [NewDomainsQueryURLsProvider]: 通过 API 查询域名
 //   - [CombineDownloadURLsProviders]: 组合多个 Provider
 //
+// 使用 [NewHealthRankedURLsProvider] 可以根据下载时记录的响应时间、吞吐量和错误率对域名排序，
+// 优先使用最健康的域名，并临时冻结请求失败的域名：
+//
+//	rankedProvider := downloader.NewHealthRankedURLsProvider(urlsProvider, nil)
+//
 // # URL 签名
 //
 // 使用 [Signer] 接口为下载 URL 添加认证签名：
@@ -42,6 +89,14 @@
 //	signer := downloader.NewCredentialsSigner(cred)
 //	signedProvider := downloader.SignURLsProvider(urlsProvider, signer, nil)
 //
+// CDN 域名开启时间戳防盗链时，使用 [NewCDNTimestampAntiLeechSigner]，并通过 [NewDomainBasedSigner] 为不同域名选择不同的签名方式。
+// 设置 SignOptions.TimeBucket 后，同一时间窗口内的签名结果保持不变，有利于提高 CDN 缓存命中率：
+//
+//	signer := downloader.NewDomainBasedSigner(map[string]downloader.Signer{
+//	    "cdn.example.com": downloader.NewCDNTimestampAntiLeechSigner(encryptKey),
+//	}, downloader.NewCredentialsSigner(cred))
+//	signedProvider := downloader.SignURLsProvider(urlsProvider, signer, &downloader.SignOptions{TTL: time.Hour, TimeBucket: 10 * time.Minute})
+//
 // # 并发下载
 //
 // 使用 [NewConcurrentDownloader] 通过 HTTP Range 请求并发下载大文件：
@@ -50,4 +105,34 @@
 //	    Concurrency: 4,
 //	    PartSize:    4 * 1024 * 1024,
 //	})
+//
+// 使用 [NewParallelRangeDownloader] 可以将分片分散到多个下载 URL 上并行下载，单个分片失败时切换到其他 URL 重试，
+// 也可以通过 [DownloadManagerOptions] 的 ParallelRangeDownload 字段为 [DownloadManager] 启用：
+//
+//	downloadManager := downloader.NewDownloadManager(&downloader.DownloadManagerOptions{
+//	    ParallelRangeDownload: &downloader.ParallelRangeDownloaderOptions{
+//	        Concurrency: 8,
+//	        PartSize:    16 * 1024 * 1024,
+//	    },
+//	})
+//
+// # 随机读取
+//
+// 使用 [DownloadManager.OpenObject] 打开对象进行随机读取，只通过 Range 请求下载需要的范围，可以启用 LRU 块缓存和预读：
+//
+//	reader, err := downloadManager.OpenObject(ctx, "data.parquet", &downloader.ObjectReaderOptions{
+//	    GenerateOptions: downloader.GenerateOptions{BucketName: "my-bucket"},
+//	    CacheBlocks:     16,
+//	    ReadAheadBlocks: 2,
+//	})
+//	defer reader.Close()
+//	zipReader, err := zip.NewReader(reader, reader.Size())
+//
+// # 文件系统
+//
+// 使用 [DownloadManager.BucketFS] 将存储空间映射为只读的 fs.FS，可以用于 http.FileServer、template.ParseFS 等标准库接口，
+// 列举和查询结果按照 CacheTTL 缓存：
+//
+//	fsys := downloadManager.BucketFS("my-bucket", &downloader.BucketFSOptions{Prefix: "static/"})
+//	http.Handle("/", http.FileServer(http.FS(fsys)))
 package downloader
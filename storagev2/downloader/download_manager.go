@@ -2,16 +2,21 @@ package downloader
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/qiniu/go-sdk/v7/storagev2/bandwidth"
 	"github.com/qiniu/go-sdk/v7/storagev2/credentials"
 	"github.com/qiniu/go-sdk/v7/storagev2/downloader/destination"
+	"github.com/qiniu/go-sdk/v7/storagev2/encryption"
 	"github.com/qiniu/go-sdk/v7/storagev2/errors"
 	httpclient "github.com/qiniu/go-sdk/v7/storagev2/http_client"
 	"github.com/qiniu/go-sdk/v7/storagev2/objects"
@@ -27,6 +32,8 @@ type (
 		downloadURLsProvider     DownloadURLsProvider
 		downloadURLsProviderOnce sync.Once
 		options                  httpclient.Options
+		encryptionKeyProvider    encryption.KeyProvider
+		archiveRestore           *ArchiveRestoreOptions
 	}
 
 	// 下载管理器选项
@@ -37,11 +44,24 @@ type (
 		// 目标下载器
 		DestinationDownloader DestinationDownloader
 
+		// 并行范围下载选项，如果设置且没有设置 DestinationDownloader，则使用并行范围下载器，
+		// 大对象的分片将被分散到多个下载域名上同时下载
+		ParallelRangeDownload *ParallelRangeDownloaderOptions
+
 		// 分片列举版本，如果不填写，默认为 V1
 		ListerVersion objects.ListerVersion
 
 		// 下载 URL 生成器
 		DownloadURLsProvider DownloadURLsProvider
+
+		// 客户端加密密钥提供者，如果设置，则客户端加密的对象将在下载时透明解密，未加密的对象不受影响
+		// 设置后，对象下载附加的 Range Header 将被视为明文范围，仅支持 bytes=from- 和 bytes=from-to 两种形式
+		// 加密对象的下载不支持断点续传，下载进度以密文计算
+		EncryptionKeyProvider encryption.KeyProvider
+
+		// 归档对象自动解冻选项，如果设置，则下载归档存储或深度归档存储中尚未解冻的对象时，将自动解冻对象，等待解冻完成后再下载
+		// 仅当对象下载参数中指定了空间名称时生效，目录下载时将通过批处理批量解冻对象
+		ArchiveRestore *ArchiveRestoreOptions
 	}
 
 	// 对象下载参数
@@ -51,6 +71,23 @@ type (
 
 		// 下载 URL 生成器
 		DownloadURLsProvider DownloadURLsProvider
+
+		// 带宽限制器，如果设置，则覆盖 HTTP 客户端选项中的带宽限制器
+		BandwidthLimiter *bandwidth.Limiter
+
+		// 是否在下载到文件后校验对象完整性，默认为不校验
+		//
+		// 仅当对象元数据中记录了上传时计算的校验和（x-qn-meta-crc64ecma 或 x-qn-meta-md5）时校验，
+		// 校验失败时返回 errors.ChecksumMismatchError。
+		VerifyChecksums bool
+
+		// 条件下载选项，仅对下载到文件有效，如果设置且本地文件已经存在，则附加 If-None-Match 或 If-Modified-Since 发出条件请求，
+		// 服务器返回 304 时视为下载成功，不修改本地文件，返回的下载数据量为 0
+		Conditional *ConditionalOptions
+
+		// 数据转换，仅对下载到文件有效，如果设置，则在下载完成且完整性校验通过后，读取下载的文件执行转换，例如解压缩或解包
+		// 条件下载时服务器返回 304 则不执行转换
+		Transformer destination.Transformer
 	}
 
 	// 目录下载参数
@@ -82,6 +119,23 @@ type (
 		// 是否下载指定对象
 		ShouldDownloadObject func(objectName string) bool
 
+		// 更改本地文件路径，参数为对象名称去除前缀并转换分隔符后得到的相对路径，返回值为相对于目标目录的路径，不能超出目标目录
+		UpdateFilePath func(string) string
+
+		// 是否跳过未改变的对象，如果设置，则本地文件的大小和哈希值与对象一致时不下载，默认为不跳过
+		SkipUnchanged bool
+
+		// 对象因未改变而跳过后回调
+		OnObjectSkipped func(objectName string)
+
+		// 是否删除目标目录中远端已经不存在的本地文件，仅在所有对象下载成功后删除，默认为不删除
+		//
+		// 被 ShouldDownloadObject 过滤的对象对应的本地文件不会被删除，目录不会被删除
+		DeleteExtraneousFiles bool
+
+		// 本地文件被删除后回调
+		OnExtraneousFileDeleted func(filePath string)
+
 		// 分隔符，默认为 /
 		PathSeparator string
 	}
@@ -103,7 +157,11 @@ func NewDownloadManager(options *DownloadManagerOptions) *DownloadManager {
 	}
 	destinationDownloader := options.DestinationDownloader
 	if destinationDownloader == nil {
-		destinationDownloader = NewConcurrentDownloader(nil)
+		if options.ParallelRangeDownload != nil {
+			destinationDownloader = NewParallelRangeDownloader(options.ParallelRangeDownload)
+		} else {
+			destinationDownloader = NewConcurrentDownloader(nil)
+		}
 	}
 	objectsManager := objects.NewObjectsManager(&objects.ObjectsManagerOptions{
 		Options:       options.Options,
@@ -113,17 +171,120 @@ func NewDownloadManager(options *DownloadManagerOptions) *DownloadManager {
 		destinationDownloader: destinationDownloader,
 		objectsManager:        objectsManager,
 		options:               options.Options,
+		encryptionKeyProvider: options.EncryptionKeyProvider,
+		archiveRestore:        options.ArchiveRestore,
 	}
 }
 
 // 下载对象到文件
+//
+// 设置了完整性校验、条件下载或数据转换且没有指定 Range 时，如果本地文件已经存在且比对象更大（例如对象在两次下载之间变小），
+// 下载完成后会按照响应中的对象大小截断多余的内容。
 func (downloadManager *DownloadManager) DownloadToFile(ctx context.Context, objectName, filePath string, options *ObjectOptions) (uint64, error) {
+	return downloadManager.downloadToFile(ctx, objectName, filePath, options, false)
+}
+
+// 下载对象到文件，truncate 表示即使没有设置完整性校验、条件下载或数据转换，也按照响应中的对象大小截断本地文件
+func (downloadManager *DownloadManager) downloadToFile(ctx context.Context, objectName, filePath string, options *ObjectOptions, truncate bool) (uint64, error) {
+	var conditional bool
+	if options != nil && options.Conditional != nil {
+		var err error
+		if options, conditional, err = withConditionalHeaders(filePath, options); err != nil {
+			return 0, err
+		}
+	}
 	dest, err := destination.NewFileDestination(filePath)
 	if err != nil {
 		return 0, err
 	}
-	defer dest.Close()
-	return downloadManager.downloadToDestination(ctx, objectName, dest, options)
+	closeDest := sync.OnceValue(dest.Close)
+	defer closeDest()
+	if !truncate && (options == nil || (!options.VerifyChecksums && options.Conditional == nil && options.Transformer == nil)) {
+		return downloadManager.downloadToDestination(ctx, objectName, dest, options)
+	} else if options == nil {
+		options = &ObjectOptions{}
+	}
+
+	var recorder responseHeaderRecorder
+	n, err := downloadManager.downloadToDestination(ctx, objectName, dest, recorder.wrap(options))
+	if conditional && httpCodeOf(err) == http.StatusNotModified {
+		if onNotModified := options.Conditional.OnNotModified; onNotModified != nil {
+			onNotModified()
+		}
+		return 0, nil
+	} else if err != nil {
+		return n, err
+	}
+	if options.Header == nil || options.Header.Get("Range") == "" {
+		// 对象发生变化后可能比本地文件更小，需要在校验前截断多余的内容
+		if err = truncateToObjectSize(dest.GetFile(), recorder.header); err != nil {
+			return n, err
+		}
+	}
+	if options.VerifyChecksums {
+		if err = verifyDownloadedFile(filePath, options, recorder.header); err != nil {
+			return n, err
+		}
+	}
+	if options.Conditional != nil && (options.Header == nil || options.Header.Get("Range") == "") {
+		if err = closeDest(); err != nil {
+			return n, err
+		}
+		if sidecarFilePath := options.Conditional.SidecarFilePath; sidecarFilePath != "" && recorder.header != nil {
+			if err = writeConditionalSidecar(filePath, sidecarFilePath, recorder.header); err != nil {
+				return n, err
+			}
+		}
+	}
+	if options.Transformer != nil {
+		if err = closeDest(); err != nil {
+			return n, err
+		}
+		if err = transformDownloadedFile(filePath, options.Transformer); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// 按照响应 Header 中的对象大小截断本地文件，无法确定对象大小时不截断
+func truncateToObjectSize(file *os.File, header http.Header) error {
+	if file == nil || header == nil {
+		return nil
+	}
+	var size uint64
+	if contentRange := header.Get("Content-Range"); contentRange != "" {
+		var from, to uint64
+		if _, err := fmt.Sscanf(contentRange, "bytes %d-%d/%d", &from, &to, &size); err != nil {
+			return nil
+		}
+	} else if contentLength, err := strconv.ParseUint(header.Get("Content-Length"), 10, 64); err != nil {
+		return nil
+	} else {
+		size = contentLength
+	}
+	if _, err := encryption.EnvelopeFromHeader(header); err == nil {
+		plainSize, err := encryption.PlaintextSize(size)
+		if err != nil {
+			return nil
+		}
+		size = plainSize
+	}
+	if fileInfo, err := file.Stat(); err != nil {
+		return err
+	} else if uint64(fileInfo.Size()) > size {
+		return file.Truncate(int64(size))
+	}
+	return nil
+}
+
+func transformDownloadedFile(filePath string, transformer destination.Transformer) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return transformer.Transform(file)
 }
 
 // 下载对象到 io.Writer
@@ -142,23 +303,64 @@ func (downloadManager *DownloadManager) downloadToDestination(ctx context.Contex
 	if options == nil {
 		options = &ObjectOptions{}
 	}
-	downloadURLsProvider := options.DownloadURLsProvider
-	if downloadURLsProvider == nil {
-		if err := downloadManager.initDownloadURLsProvider(ctx); err != nil {
+	if limiter := options.BandwidthLimiter; limiter != nil {
+		ctx = bandwidth.WithLimiter(ctx, limiter)
+	} else if limiter = downloadManager.options.BandwidthLimiter; limiter != nil && bandwidth.LimiterFromContext(ctx) == nil {
+		ctx = bandwidth.WithLimiter(ctx, limiter)
+	}
+	download := func() (uint64, error) {
+		urls, err := downloadManager.getURLsIter(ctx, objectName, options.DownloadURLsProvider, &options.GenerateOptions)
+		if err != nil {
 			return 0, err
 		}
+		if downloadManager.encryptionKeyProvider != nil {
+			return downloadManager.downloadToDecryptingDestination(ctx, urls, dest, &options.DestinationDownloadOptions)
+		}
+		return downloadManager.destinationDownloader.Download(ctx, urls, dest, &options.DestinationDownloadOptions)
 	}
+	n, err := download()
+	if err != nil {
+		if restored, restoreErr := downloadManager.restoreIfArchived(ctx, options.BucketName, objectName, err); restoreErr != nil {
+			return n, restoreErr
+		} else if restored {
+			return download()
+		}
+	}
+	return n, err
+}
+
+func (downloadManager *DownloadManager) getURLsIter(ctx context.Context, objectName string, downloadURLsProvider DownloadURLsProvider, generateOptions *GenerateOptions) (URLsIter, error) {
 	if downloadURLsProvider == nil {
+		if err := downloadManager.initDownloadURLsProvider(ctx); err != nil {
+			return nil, err
+		}
 		downloadURLsProvider = downloadManager.downloadURLsProvider
 	}
 	if downloadURLsProvider == nil {
-		return 0, errors.MissingRequiredFieldError{Name: "DownloadURLsProvider"}
+		return nil, errors.MissingRequiredFieldError{Name: "DownloadURLsProvider"}
 	}
-	urls, err := downloadURLsProvider.GetURLsIter(ctx, objectName, &options.GenerateOptions)
+	return downloadURLsProvider.GetURLsIter(ctx, objectName, generateOptions)
+}
+
+func (downloadManager *DownloadManager) downloadToDecryptingDestination(ctx context.Context, urls URLsIter, dest destination.Destination, options *DestinationDownloadOptions) (uint64, error) {
+	decryptingDest, header, err := newDecryptingDestination(ctx, dest, downloadManager.encryptionKeyProvider, options.Header)
 	if err != nil {
 		return 0, err
 	}
-	return downloadManager.destinationDownloader.Download(ctx, urls, dest, &options.DestinationDownloadOptions)
+	destinationDownloadOptions := *options
+	destinationDownloadOptions.Header = header
+	destinationDownloadOptions.OnResponseHeader = func(h http.Header) {
+		decryptingDest.init(h)
+		if onResponseHeader := options.OnResponseHeader; onResponseHeader != nil {
+			onResponseHeader(h)
+		}
+	}
+	n, err := downloadManager.destinationDownloader.Download(ctx, urls, decryptingDest, &destinationDownloadOptions)
+	if !decryptingDest.encrypted() {
+		// 未加密的对象直接写入数据目标，下载器返回的大小已经包含断点续传前下载的部分
+		return n, err
+	}
+	return decryptingDest.plaintextWritten(), err
 }
 
 // 下载目录
@@ -188,18 +390,78 @@ func (downloadManager *DownloadManager) DownloadDirectory(ctx context.Context, t
 	})
 	defer lister.Close()
 
-	var object objects.ObjectDetails
-	for lister.Next(&object) {
-		objectName := object.Name
-		if options.ShouldDownloadObject != nil && !options.ShouldDownloadObject(objectName) {
-			continue
+	var (
+		object            objects.ObjectDetails
+		archivedToRestore []*archivedObjectToRestore
+		archivedToWait    []*archivedObjectToRestore
+		filePathsToKeep   = make(map[string]struct{})
+	)
+	skipIfUnchanged := func(objectName, fullPath string, size int64, objectETag string) (bool, error) {
+		if !options.SkipUnchanged {
+			return false, nil
 		}
-
+		unchanged, err := isLocalFileUnchanged(fullPath, size, objectETag)
+		if err == nil && unchanged && options.OnObjectSkipped != nil {
+			options.OnObjectSkipped(objectName)
+		}
+		return unchanged, err
+	}
+	downloadObject := func(objectName, fullPath string) error {
+		var destinationDownloadOptions DestinationDownloadOptions
+		if onDownloadingProgress := options.OnDownloadingProgress; onDownloadingProgress != nil {
+			destinationDownloadOptions.OnDownloadingProgress = func(progress *DownloadingProgress) {
+				onDownloadingProgress(objectName, progress)
+			}
+		}
+		objectOptions := ObjectOptions{
+			DestinationDownloadOptions: destinationDownloadOptions,
+			GenerateOptions: GenerateOptions{
+				BucketName:          options.BucketName,
+				UseInsecureProtocol: options.UseInsecureProtocol,
+			},
+			DownloadURLsProvider: options.DownloadURLsProvider,
+		}
+		if options.BeforeObjectDownload != nil {
+			options.BeforeObjectDownload(objectName, &objectOptions)
+		}
+		// 本地文件可能是对象变小之前下载的，需要截断多余的内容
+		n, err := downloadManager.downloadToFile(ctx, objectName, fullPath, &objectOptions, true)
+		if err == nil && options.OnObjectDownloaded != nil {
+			options.OnObjectDownloaded(objectName, &DownloadedObjectInfo{Size: n})
+		}
+		return err
+	}
+	flushArchivedToRestore := func() error {
+		if len(archivedToRestore) == 0 {
+			return nil
+		}
+		if err := downloadManager.restoreArchivedObjects(ctx, options.BucketName, archivedToRestore); err != nil {
+			return err
+		}
+		archivedToWait = append(archivedToWait, archivedToRestore...)
+		archivedToRestore = nil
+		return nil
+	}
+	for lister.Next(&object) {
+		objectName, size, objectETag := object.Name, object.Size, object.ETag
 		relativePath := strings.TrimPrefix(objectName, options.ObjectPrefix)
 		if pathSeparator != string(filepath.Separator) {
 			relativePath = strings.Replace(relativePath, pathSeparator, string(filepath.Separator), -1)
 		}
-		fullPath := filepath.Join(targetDirPath, relativePath)
+		if options.UpdateFilePath != nil {
+			relativePath = options.UpdateFilePath(relativePath)
+		}
+		fullPath, err := localPathOfObject(targetDirPath, relativePath)
+		if options.ShouldDownloadObject != nil && !options.ShouldDownloadObject(objectName) {
+			if err == nil {
+				filePathsToKeep[fullPath] = struct{}{}
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+		filePathsToKeep[fullPath] = struct{}{}
+
 		if relativePath == "" || strings.HasSuffix(relativePath, string(filepath.Separator)) {
 			if err = os.MkdirAll(fullPath, 0700); err != nil {
 				return err
@@ -208,36 +470,56 @@ func (downloadManager *DownloadManager) DownloadDirectory(ctx context.Context, t
 			if err = os.MkdirAll(filepath.Dir(fullPath), 0700); err != nil {
 				return err
 			}
-			g.Go(func() error {
-				var destinationDownloadOptions DestinationDownloadOptions
-				if onDownloadingProgress := options.OnDownloadingProgress; onDownloadingProgress != nil {
-					destinationDownloadOptions.OnDownloadingProgress = func(progress *DownloadingProgress) {
-						onDownloadingProgress(objectName, progress)
-					}
-				}
-				objectOptions := ObjectOptions{
-					DestinationDownloadOptions: destinationDownloadOptions,
-					GenerateOptions: GenerateOptions{
-						BucketName:          options.BucketName,
-						UseInsecureProtocol: options.UseInsecureProtocol,
-					},
-					DownloadURLsProvider: options.DownloadURLsProvider,
+			if downloadManager.archiveRestore != nil && isArchivedStorageClass(object.StorageClass) && object.RestoreStatus != objects.RestoredStatus {
+				// 归档对象先批量解冻，待其他对象下载后再等待解冻完成并下载，避免占用下载并发
+				if skipped, err := skipIfUnchanged(objectName, fullPath, size, objectETag); err != nil {
+					return err
+				} else if skipped {
+					continue
 				}
-				if options.BeforeObjectDownload != nil {
-					options.BeforeObjectDownload(objectName, &objectOptions)
+				archivedObject := &archivedObjectToRestore{objectName: objectName, fullPath: fullPath}
+				if object.RestoreStatus == objects.FrozenStatus {
+					archivedToRestore = append(archivedToRestore, archivedObject)
+					if len(archivedToRestore) >= downloadManager.archiveRestore.batchSize() {
+						if err = flushArchivedToRestore(); err != nil {
+							return err
+						}
+					}
+				} else {
+					archivedToWait = append(archivedToWait, archivedObject)
 				}
-				n, err := downloadManager.DownloadToFile(ctx, objectName, fullPath, &objectOptions)
-				if err == nil && options.OnObjectDownloaded != nil {
-					options.OnObjectDownloaded(objectName, &DownloadedObjectInfo{Size: n})
+				continue
+			}
+			g.Go(func() error {
+				if skipped, err := skipIfUnchanged(objectName, fullPath, size, objectETag); err != nil || skipped {
+					return err
 				}
-				return err
+				return downloadObject(objectName, fullPath)
 			})
 		}
 	}
 	if err = lister.Error(); err != nil {
 		return err
 	}
-	return g.Wait()
+	if err = flushArchivedToRestore(); err != nil {
+		return err
+	}
+	for _, archivedObject := range archivedToWait {
+		archivedObject := archivedObject
+		g.Go(func() error {
+			if err := downloadManager.waitForRestored(ctx, options.BucketName, archivedObject.objectName, archivedObject.restoreErr); err != nil {
+				return err
+			}
+			return downloadObject(archivedObject.objectName, archivedObject.fullPath)
+		})
+	}
+	if err = g.Wait(); err != nil {
+		return err
+	}
+	if options.DeleteExtraneousFiles {
+		return deleteExtraneousFiles(targetDirPath, filePathsToKeep, options.OnExtraneousFileDeleted)
+	}
+	return nil
 }
 
 func (downloadManager *DownloadManager) initDownloadURLsProvider(ctx context.Context) (err error) {
@@ -4,8 +4,14 @@
 package downloader_test
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"hash/crc64"
 	"io"
 	"math/rand"
 	"net/http"
@@ -14,13 +20,25 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/qiniu/go-sdk/v7/internal/etag"
+	internal_io "github.com/qiniu/go-sdk/v7/internal/io"
+	"github.com/qiniu/go-sdk/v7/storagev2/apis/batch_ops"
 	"github.com/qiniu/go-sdk/v7/storagev2/apis/get_objects"
+	"github.com/qiniu/go-sdk/v7/storagev2/apis/stat_object"
+	"github.com/qiniu/go-sdk/v7/storagev2/backoff"
 	"github.com/qiniu/go-sdk/v7/storagev2/credentials"
 	"github.com/qiniu/go-sdk/v7/storagev2/downloader"
+	"github.com/qiniu/go-sdk/v7/storagev2/downloader/destination"
+	resumablerecorder "github.com/qiniu/go-sdk/v7/storagev2/downloader/resumable_recorder"
+	"github.com/qiniu/go-sdk/v7/storagev2/encryption"
+	"github.com/qiniu/go-sdk/v7/storagev2/errors"
 	"github.com/qiniu/go-sdk/v7/storagev2/http_client"
+	"github.com/qiniu/go-sdk/v7/storagev2/objects"
 	"github.com/qiniu/go-sdk/v7/storagev2/region"
 )
 
@@ -356,3 +374,930 @@ func TestDownloadManagerDownloadDirectoryWithFilter(t *testing.T) {
 		t.Fatalf("unexpected test2/file2")
 	}
 }
+
+func TestDownloadManagerDownloadDirectoryWithSync(t *testing.T) {
+	remoteObjects := map[string][]byte{
+		"a.txt":     []byte("unchanged content"),
+		"dir/b.txt": []byte("changed content"),
+		"skip.log":  []byte("filtered object"),
+	}
+	remoteETags := make(map[string]string, len(remoteObjects))
+	for name, data := range remoteObjects {
+		objectETag, err := etag.FromReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		remoteETags[name] = objectETag
+	}
+
+	rsfMux := http.NewServeMux()
+	rsfMux.HandleFunc("/list", func(w http.ResponseWriter, r *http.Request) {
+		items := make([]get_objects.ListedObjectEntry, 0, len(remoteObjects))
+		for _, name := range []string{"a.txt", "dir/b.txt", "skip.log"} {
+			items = append(items, get_objects.ListedObjectEntry{
+				Key:      name,
+				PutTime:  time.Now().UnixNano() / 100,
+				Hash:     remoteETags[name],
+				Size:     int64(len(remoteObjects[name])),
+				MimeType: "text/plain",
+			})
+		}
+		jsonData, err := json.Marshal(&get_objects.Response{Items: items})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("X-ReqId", "fakereqid")
+		w.Write(jsonData)
+	})
+	rsfServer := httptest.NewServer(rsfMux)
+	defer rsfServer.Close()
+
+	var (
+		lock       sync.Mutex
+		downloaded = make(map[string]int)
+	)
+	ioServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		if r.Method == http.MethodGet {
+			lock.Lock()
+			downloaded[name] += 1
+			lock.Unlock()
+		}
+		w.Header().Set("ETag", strconv.Quote(remoteETags[name]))
+		w.Header().Add("X-ReqId", "fakereqid")
+		http.ServeContent(w, r, "", time.Now(), bytes.NewReader(remoteObjects[name]))
+	}))
+	defer ioServer.Close()
+
+	tmpDir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	localFiles := map[string]string{
+		"mapped/a.txt":     "unchanged content",
+		"mapped/dir/b.txt": "stale content which is longer",
+		"mapped/old.txt":   "extraneous",
+		"mapped/skip.log":  "local only",
+	}
+	for name, content := range localFiles {
+		filePath := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err = os.MkdirAll(filepath.Dir(filePath), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(filePath, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	downloadManager := downloader.NewDownloadManager(&downloader.DownloadManagerOptions{
+		Options: http_client.Options{
+			Regions: &region.Region{
+				Rsf: region.Endpoints{Preferred: []string{rsfServer.URL}},
+			},
+			Credentials:         credentials.NewCredentials("testaccesskey", "testsecretkey"),
+			UseInsecureProtocol: true,
+		},
+	})
+	var skipped, deleted []string
+	directoryOptions := downloader.DirectoryOptions{
+		UseInsecureProtocol:  true,
+		BucketName:           "bucket1",
+		DownloadURLsProvider: downloader.NewStaticDomainBasedURLsProvider([]string{ioServer.URL}),
+		ShouldDownloadObject: func(objectName string) bool {
+			return !strings.HasSuffix(objectName, ".log")
+		},
+		UpdateFilePath: func(relativePath string) string {
+			return filepath.Join("mapped", relativePath)
+		},
+		SkipUnchanged: true,
+		OnObjectSkipped: func(objectName string) {
+			lock.Lock()
+			defer lock.Unlock()
+			skipped = append(skipped, objectName)
+		},
+		DeleteExtraneousFiles: true,
+		OnExtraneousFileDeleted: func(filePath string) {
+			deleted = append(deleted, filePath)
+		},
+	}
+	if err = downloadManager.DownloadDirectory(context.Background(), tmpDir, &directoryOptions); err != nil {
+		t.Fatal(err)
+	}
+	if len(downloaded) != 1 || downloaded["dir/b.txt"] != 1 {
+		t.Fatalf("unexpected downloaded objects: %v", downloaded)
+	}
+	if len(skipped) != 1 || skipped[0] != "a.txt" {
+		t.Fatalf("unexpected skipped objects: %v", skipped)
+	}
+	if len(deleted) != 1 || deleted[0] != filepath.Join(tmpDir, "mapped", "old.txt") {
+		t.Fatalf("unexpected deleted files: %v", deleted)
+	}
+	for name, expected := range map[string]string{
+		"mapped/a.txt":     "unchanged content",
+		"mapped/dir/b.txt": "changed content",
+		"mapped/skip.log":  "local only",
+	} {
+		if content, err := os.ReadFile(filepath.Join(tmpDir, filepath.FromSlash(name))); err != nil {
+			t.Fatal(err)
+		} else if string(content) != expected {
+			t.Fatalf("unexpected content of %s: %s", name, content)
+		}
+	}
+
+	directoryOptions.UpdateFilePath = func(relativePath string) string {
+		return filepath.Join("..", relativePath)
+	}
+	if err = downloadManager.DownloadDirectory(context.Background(), tmpDir, &directoryOptions); err == nil {
+		t.Fatalf("local path outside of the target directory should be rejected")
+	}
+}
+
+func TestDownloadManagerDownloadEncryptedObject(t *testing.T) {
+	keyProvider, err := encryption.NewStaticKeyProvider("testkeyid", bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope, c, err := encryption.NewEnvelope(context.Background(), keyProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := make([]byte, 5*encryption.FrameSize+17)
+	rand.New(rand.NewSource(time.Now().UnixNano())).Read(plaintext)
+	ciphertext, err := internal_io.ReadAll(encryption.NewEncryptingReader(bytes.NewReader(plaintext), c))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ioMux := http.NewServeMux()
+	ioMux.HandleFunc("/encrypted", func(w http.ResponseWriter, r *http.Request) {
+		for k, v := range envelope.ToMetadata(nil) {
+			w.Header().Set(k, v)
+		}
+		w.Header().Set("ETag", `"testetag1"`)
+		w.Header().Add("X-ReqId", "fakereqid")
+		http.ServeContent(w, r, "", time.Now(), bytes.NewReader(ciphertext))
+	})
+	ioMux.HandleFunc("/plain", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"testetag2"`)
+		w.Header().Add("X-ReqId", "fakereqid")
+		http.ServeContent(w, r, "", time.Now(), bytes.NewReader(plaintext))
+	})
+	ioServer := httptest.NewServer(ioMux)
+	defer ioServer.Close()
+
+	tmpDir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	downloadManager := downloader.NewDownloadManager(&downloader.DownloadManagerOptions{
+		Options: http_client.Options{
+			Credentials:         credentials.NewCredentials("testaccesskey", "testsecretkey"),
+			UseInsecureProtocol: true,
+		},
+		DestinationDownloader: downloader.NewConcurrentDownloader(&downloader.ConcurrentDownloaderOptions{
+			Concurrency: 4,
+			PartSize:    2 * encryption.CiphertextFrameSize,
+		}),
+		EncryptionKeyProvider: keyProvider,
+	})
+
+	urlsProvider := downloader.NewStaticDomainBasedURLsProvider([]string{ioServer.URL})
+	for _, objectName := range []string{"encrypted", "plain"} {
+		filePath := filepath.Join(tmpDir, objectName)
+		n, err := downloadManager.DownloadToFile(context.Background(), objectName, filePath, &downloader.ObjectOptions{
+			GenerateOptions:      downloader.GenerateOptions{UseInsecureProtocol: true},
+			DownloadURLsProvider: urlsProvider,
+		})
+		if err != nil {
+			t.Fatal(err)
+		} else if n != uint64(len(plaintext)) {
+			t.Fatalf("unexpected downloaded size: %d", n)
+		}
+		if data, err := os.ReadFile(filePath); err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(data, plaintext) {
+			t.Fatalf("unexpected downloaded data of %s", objectName)
+		}
+	}
+
+	for _, r := range [][2]int{{0, 0}, {70000, 200000}, {encryption.FrameSize, encryption.FrameSize*2 - 1}, {300000, len(plaintext) + 100}} {
+		var buf bytes.Buffer
+		header := make(http.Header)
+		header.Set("Range", "bytes="+strconv.Itoa(r[0])+"-"+strconv.Itoa(r[1]))
+		n, err := downloadManager.DownloadToWriter(context.Background(), "encrypted", &buf, &downloader.ObjectOptions{
+			DestinationDownloadOptions: downloader.DestinationDownloadOptions{Header: header},
+			GenerateOptions:            downloader.GenerateOptions{UseInsecureProtocol: true},
+			DownloadURLsProvider:       urlsProvider,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		end := r[1] + 1
+		if end > len(plaintext) {
+			end = len(plaintext)
+		}
+		if n != uint64(end-r[0]) || !bytes.Equal(buf.Bytes(), plaintext[r[0]:end]) {
+			t.Fatalf("unexpected downloaded data of range %v", r)
+		}
+	}
+}
+
+func TestDownloadManagerResumeUnencryptedObjectWithEncryptionKeyProvider(t *testing.T) {
+	const partSize = 64 * 1024
+	data := make([]byte, 4*partSize+17)
+	rand.New(rand.NewSource(time.Now().UnixNano())).Read(data)
+
+	var requestedFirstPart int32
+	ioServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && strings.HasPrefix(r.Header.Get("Range"), "bytes=0-") {
+			atomic.StoreInt32(&requestedFirstPart, 1)
+		}
+		w.Header().Set("ETag", `"testetag1"`)
+		w.Header().Add("X-ReqId", "fakereqid")
+		http.ServeContent(w, r, "", time.Now(), bytes.NewReader(data))
+	}))
+	defer ioServer.Close()
+
+	tmpDir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// 上次下载已经完成了第一个分片
+	filePath := filepath.Join(tmpDir, "testfile")
+	if err = os.WriteFile(filePath, data[:partSize], 0600); err != nil {
+		t.Fatal(err)
+	}
+	absFilePath, err := filepath.Abs(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resumableRecorder := resumablerecorder.NewJsonFileSystemResumableRecorder(tmpDir)
+	writableMedium := resumableRecorder.OpenForCreatingNew(&resumablerecorder.ResumableRecorderOpenArgs{
+		ETag:          "testetag1",
+		DestinationID: absFilePath,
+		PartSize:      partSize,
+		TotalSize:     uint64(len(data)),
+	})
+	if err = writableMedium.Write(&resumablerecorder.ResumableRecord{
+		Offset:      0,
+		PartSize:    partSize,
+		PartWritten: partSize,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err = writableMedium.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	keyProvider, err := encryption.NewStaticKeyProvider("testkeyid", bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	downloadManager := downloader.NewDownloadManager(&downloader.DownloadManagerOptions{
+		Options: http_client.Options{
+			Credentials:         credentials.NewCredentials("testaccesskey", "testsecretkey"),
+			UseInsecureProtocol: true,
+		},
+		DestinationDownloader: downloader.NewConcurrentDownloader(&downloader.ConcurrentDownloaderOptions{
+			Concurrency:       4,
+			PartSize:          partSize,
+			ResumableRecorder: resumableRecorder,
+		}),
+		EncryptionKeyProvider: keyProvider,
+	})
+	n, err := downloadManager.DownloadToFile(context.Background(), "testfile", filePath, &downloader.ObjectOptions{
+		GenerateOptions:      downloader.GenerateOptions{UseInsecureProtocol: true},
+		DownloadURLsProvider: downloader.NewStaticDomainBasedURLsProvider([]string{ioServer.URL}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if n != uint64(len(data)) {
+		t.Fatalf("unexpected downloaded size: %d", n)
+	}
+	if atomic.LoadInt32(&requestedFirstPart) != 0 {
+		t.Fatalf("first part should not be downloaded again")
+	}
+	if content, err := os.ReadFile(filePath); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(content, data) {
+		t.Fatalf("unexpected file content of size %d", len(content))
+	}
+}
+
+func TestDownloadManagerDownloadToFileWithChecksums(t *testing.T) {
+	data := make([]byte, 1024*1024+17)
+	rand.New(rand.NewSource(time.Now().UnixNano())).Read(data)
+	md5Sum := md5.Sum(data)
+	crc64Sum := strconv.FormatUint(crc64.Checksum(data, crc64.MakeTable(crc64.ECMA)), 10)
+
+	ioMux := http.NewServeMux()
+	ioMux.HandleFunc("/good", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Qn-Meta-Crc64ecma", crc64Sum)
+		w.Header().Set("X-Qn-Meta-Md5", hex.EncodeToString(md5Sum[:]))
+		w.Header().Set("ETag", `"testetag1"`)
+		w.Header().Add("X-ReqId", "fakereqid")
+		http.ServeContent(w, r, "", time.Now(), bytes.NewReader(data))
+	})
+	ioMux.HandleFunc("/bad", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Qn-Meta-Crc64ecma", "0")
+		w.Header().Set("ETag", `"testetag2"`)
+		w.Header().Add("X-ReqId", "fakereqid")
+		http.ServeContent(w, r, "", time.Now(), bytes.NewReader(data))
+	})
+	ioServer := httptest.NewServer(ioMux)
+	defer ioServer.Close()
+
+	tmpDir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	downloadManager := downloader.NewDownloadManager(&downloader.DownloadManagerOptions{
+		Options: http_client.Options{
+			Credentials:         credentials.NewCredentials("testaccesskey", "testsecretkey"),
+			UseInsecureProtocol: true,
+		},
+		DestinationDownloader: downloader.NewConcurrentDownloader(&downloader.ConcurrentDownloaderOptions{
+			Concurrency: 4,
+			PartSize:    256 * 1024,
+		}),
+	})
+	urlsProvider := downloader.NewStaticDomainBasedURLsProvider([]string{ioServer.URL})
+	objectOptions := downloader.ObjectOptions{
+		GenerateOptions:      downloader.GenerateOptions{UseInsecureProtocol: true},
+		DownloadURLsProvider: urlsProvider,
+		VerifyChecksums:      true,
+	}
+	if n, err := downloadManager.DownloadToFile(context.Background(), "good", filepath.Join(tmpDir, "good"), &objectOptions); err != nil {
+		t.Fatal(err)
+	} else if n != uint64(len(data)) {
+		t.Fatalf("unexpected downloaded size: %d", n)
+	}
+
+	_, err = downloadManager.DownloadToFile(context.Background(), "bad", filepath.Join(tmpDir, "bad"), &objectOptions)
+	if mismatchErr, ok := err.(errors.ChecksumMismatchError); !ok {
+		t.Fatalf("ChecksumMismatchError is expected, got %v", err)
+	} else if mismatchErr.Algorithm != "CRC64" || mismatchErr.Expected != "0" {
+		t.Fatalf("unexpected error: %v", mismatchErr)
+	}
+
+	objectOptions.VerifyChecksums = false
+	if _, err = downloadManager.DownloadToFile(context.Background(), "bad", filepath.Join(tmpDir, "bad"), &objectOptions); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDownloadManagerDownloadToFileWithShrunkObject(t *testing.T) {
+	data := make([]byte, 300*1024+17)
+	rand.New(rand.NewSource(time.Now().UnixNano())).Read(data)
+
+	ioServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"testetag1"`)
+		w.Header().Add("X-ReqId", "fakereqid")
+		http.ServeContent(w, r, "", time.Now(), bytes.NewReader(data))
+	}))
+	defer ioServer.Close()
+
+	tmpDir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	downloadManager := downloader.NewDownloadManager(&downloader.DownloadManagerOptions{
+		Options: http_client.Options{
+			Credentials:         credentials.NewCredentials("testaccesskey", "testsecretkey"),
+			UseInsecureProtocol: true,
+		},
+		DestinationDownloader: downloader.NewConcurrentDownloader(&downloader.ConcurrentDownloaderOptions{
+			Concurrency: 4,
+			PartSize:    64 * 1024,
+		}),
+	})
+	objectOptions := downloader.ObjectOptions{
+		GenerateOptions:      downloader.GenerateOptions{UseInsecureProtocol: true},
+		DownloadURLsProvider: downloader.NewStaticDomainBasedURLsProvider([]string{ioServer.URL}),
+		Conditional:          &downloader.ConditionalOptions{},
+	}
+
+	// 本地文件是对象变小之前下载的，条件下载后多余的内容应当被截断
+	filePath := filepath.Join(tmpDir, "testfile")
+	if err = os.WriteFile(filePath, bytes.Repeat([]byte{'x'}, len(data)*2), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := downloadManager.DownloadToFile(context.Background(), "testfile", filePath, &objectOptions); err != nil {
+		t.Fatal(err)
+	} else if n != uint64(len(data)) {
+		t.Fatalf("unexpected downloaded size: %d", n)
+	}
+	if content, err := os.ReadFile(filePath); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(content, data) {
+		t.Fatalf("unexpected file content of size %d", len(content))
+	}
+
+	// 范围下载时不截断
+	rangeFilePath := filepath.Join(tmpDir, "rangefile")
+	if err = os.WriteFile(rangeFilePath, bytes.Repeat([]byte{'x'}, len(data)*2), 0600); err != nil {
+		t.Fatal(err)
+	}
+	objectOptions.Header = http.Header{"Range": []string{"bytes=0-99"}}
+	if n, err := downloadManager.DownloadToFile(context.Background(), "testfile", rangeFilePath, &objectOptions); err != nil {
+		t.Fatal(err)
+	} else if n != 100 {
+		t.Fatalf("unexpected downloaded size: %d", n)
+	}
+	if fileInfo, err := os.Stat(rangeFilePath); err != nil {
+		t.Fatal(err)
+	} else if fileInfo.Size() != int64(len(data)*2) {
+		t.Fatalf("range download should not truncate the file: %d", fileInfo.Size())
+	}
+}
+
+func TestDownloadManagerDownloadToFileWithTransformer(t *testing.T) {
+	var gzipped bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	if _, err := gzipWriter.Write([]byte("decompressed content")); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	md5Sum := md5.Sum(gzipped.Bytes())
+
+	ioMux := http.NewServeMux()
+	ioMux.HandleFunc("/good.gz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Qn-Meta-Md5", hex.EncodeToString(md5Sum[:]))
+		w.Header().Set("ETag", `"testetag1"`)
+		w.Header().Add("X-ReqId", "fakereqid")
+		http.ServeContent(w, r, "", time.Now(), bytes.NewReader(gzipped.Bytes()))
+	})
+	ioMux.HandleFunc("/bad.gz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Qn-Meta-Md5", strings.Repeat("0", 32))
+		w.Header().Set("ETag", `"testetag2"`)
+		w.Header().Add("X-ReqId", "fakereqid")
+		http.ServeContent(w, r, "", time.Now(), bytes.NewReader(gzipped.Bytes()))
+	})
+	ioServer := httptest.NewServer(ioMux)
+	defer ioServer.Close()
+
+	tmpDir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	downloadManager := downloader.NewDownloadManager(&downloader.DownloadManagerOptions{
+		Options: http_client.Options{
+			Credentials:         credentials.NewCredentials("testaccesskey", "testsecretkey"),
+			UseInsecureProtocol: true,
+		},
+	})
+	rawFilePath, decompressedFilePath := filepath.Join(tmpDir, "good.gz"), filepath.Join(tmpDir, "good")
+	// 已经存在的更大的文件应当被截断，避免残留数据影响转换
+	if err = os.WriteFile(rawFilePath, bytes.Repeat([]byte{'x'}, gzipped.Len()*2), 0600); err != nil {
+		t.Fatal(err)
+	}
+	objectOptions := downloader.ObjectOptions{
+		GenerateOptions:      downloader.GenerateOptions{UseInsecureProtocol: true},
+		DownloadURLsProvider: downloader.NewStaticDomainBasedURLsProvider([]string{ioServer.URL}),
+		VerifyChecksums:      true,
+		Transformer:          destination.NewGzipTransformer(destination.NewFileTransformer(decompressedFilePath)),
+	}
+	if _, err = downloadManager.DownloadToFile(context.Background(), "good.gz", rawFilePath, &objectOptions); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := os.ReadFile(rawFilePath); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(content, gzipped.Bytes()) {
+		t.Fatalf("unexpected raw file content")
+	}
+	if content, err := os.ReadFile(decompressedFilePath); err != nil {
+		t.Fatal(err)
+	} else if string(content) != "decompressed content" {
+		t.Fatalf("unexpected decompressed content: %s", content)
+	}
+
+	// 完整性校验失败时不执行转换
+	objectOptions.Transformer = destination.NewGzipTransformer(destination.NewFileTransformer(filepath.Join(tmpDir, "bad")))
+	if _, err = downloadManager.DownloadToFile(context.Background(), "bad.gz", filepath.Join(tmpDir, "bad.gz"), &objectOptions); err == nil {
+		t.Fatalf("checksum mismatch is expected")
+	}
+	if _, err = os.Stat(filepath.Join(tmpDir, "bad")); !os.IsNotExist(err) {
+		t.Fatalf("transformer should not be executed")
+	}
+}
+
+func TestDownloadManagerOpenObject(t *testing.T) {
+	data := make([]byte, 4*1024*1024+17)
+	rand.New(rand.NewSource(time.Now().UnixNano())).Read(data)
+
+	var rangeRequests int64
+	ioMux := http.NewServeMux()
+	ioMux.HandleFunc("/testfile", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			if r.Header.Get("Range") == "" {
+				t.Fatalf("object reader should always use range requests")
+			}
+			atomic.AddInt64(&rangeRequests, 1)
+		}
+		w.Header().Set("ETag", `"testetag1"`)
+		w.Header().Add("X-ReqId", "fakereqid")
+		http.ServeContent(w, r, "", time.Now(), bytes.NewReader(data))
+	})
+	ioServer := httptest.NewServer(ioMux)
+	defer ioServer.Close()
+
+	downloadManager := downloader.NewDownloadManager(&downloader.DownloadManagerOptions{
+		Options: http_client.Options{
+			Credentials:         credentials.NewCredentials("testaccesskey", "testsecretkey"),
+			UseInsecureProtocol: true,
+		},
+	})
+	urlsProvider := downloader.NewStaticDomainBasedURLsProvider([]string{ioServer.URL})
+
+	// 不缓存时每次读取都直接请求所需范围，ctx 仅用于打开对象，取消后仍然可以读取
+	ctx, cancel := context.WithCancel(context.Background())
+	reader, err := downloadManager.OpenObject(ctx, "testfile", &downloader.ObjectReaderOptions{
+		GenerateOptions:      downloader.GenerateOptions{UseInsecureProtocol: true},
+		DownloadURLsProvider: urlsProvider,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if reader.Size() != int64(len(data)) || reader.ETag() != "testetag1" {
+		t.Fatalf("unexpected object info")
+	}
+	buf := make([]byte, 100)
+	if n, err := reader.ReadAt(buf, 12345); err != nil || n != 100 || !bytes.Equal(buf, data[12345:12445]) {
+		t.Fatalf("unexpected ReadAt result: %d, %v", n, err)
+	}
+	if n, err := reader.ReadAt(buf, int64(len(data)-10)); err != io.EOF || n != 10 || !bytes.Equal(buf[:10], data[len(data)-10:]) {
+		t.Fatalf("unexpected ReadAt result at the end: %d, %v", n, err)
+	}
+	if _, err = reader.Seek(-1000, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	if tail, err := io.ReadAll(reader); err != nil || !bytes.Equal(tail, data[len(data)-1000:]) {
+		t.Fatalf("unexpected tail: %v", err)
+	}
+	if err = reader.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = reader.ReadAt(buf, 0); err == nil {
+		t.Fatalf("reading closed reader should fail")
+	}
+
+	// 缓存和预读
+	reader, err = downloadManager.OpenObject(context.Background(), "testfile", &downloader.ObjectReaderOptions{
+		GenerateOptions:      downloader.GenerateOptions{UseInsecureProtocol: true},
+		DownloadURLsProvider: urlsProvider,
+		BlockSize:            256 * 1024,
+		CacheBlocks:          4,
+		ReadAheadBlocks:      2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	atomic.StoreInt64(&rangeRequests, 0)
+	all, err := io.ReadAll(io.LimitReader(reader, int64(len(data))))
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(all, data) {
+		t.Fatalf("unexpected data read")
+	}
+	// 每个块只会被下载一次
+	if n := atomic.LoadInt64(&rangeRequests); n != 17 {
+		t.Fatalf("unexpected range requests: %d", n)
+	}
+	atomic.StoreInt64(&rangeRequests, 0)
+	if n, err := reader.ReadAt(buf, int64(len(data)-50)); err != io.EOF || n != 50 || !bytes.Equal(buf[:50], data[len(data)-50:]) {
+		t.Fatalf("unexpected ReadAt result: %d, %v", n, err)
+	}
+	if n := atomic.LoadInt64(&rangeRequests); n != 0 {
+		t.Fatalf("cached block should not be downloaded again")
+	}
+}
+
+func TestDownloadManagerDownloadToFileConditionally(t *testing.T) {
+	var (
+		data          = []byte(strings.Repeat("hello world\n", 1000))
+		dataETag, _   = etag.FromReader(bytes.NewReader(data))
+		getRequests   int64
+		modifiedAt    = time.Now().Add(-time.Hour)
+		dataMutex     sync.Mutex
+		currentData   = data
+		currentETag   = dataETag
+		currentModify = modifiedAt
+	)
+	ioServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			atomic.AddInt64(&getRequests, 1)
+		}
+		dataMutex.Lock()
+		body, objectETag, modTime := currentData, currentETag, currentModify
+		dataMutex.Unlock()
+		w.Header().Set("ETag", strconv.Quote(objectETag))
+		w.Header().Add("X-ReqId", "fakereqid")
+		http.ServeContent(w, r, "", modTime, bytes.NewReader(body))
+	}))
+	defer ioServer.Close()
+
+	tmpDir := t.TempDir()
+	filePath := filepath.Join(tmpDir, "artifact")
+	downloadManager := downloader.NewDownloadManager(&downloader.DownloadManagerOptions{
+		Options: http_client.Options{
+			Credentials: credentials.NewCredentials("testaccesskey", "testsecretkey"),
+		},
+	})
+	var notModified int
+	objectOptions := downloader.ObjectOptions{
+		GenerateOptions:      downloader.GenerateOptions{UseInsecureProtocol: true},
+		DownloadURLsProvider: downloader.NewStaticDomainBasedURLsProvider([]string{ioServer.URL}),
+		Conditional: &downloader.ConditionalOptions{
+			SidecarFilePath: filePath + ".etag",
+			OnNotModified:   func() { notModified += 1 },
+		},
+	}
+	download := func(expectedN int, expectedNotModified int) {
+		if n, err := downloadManager.DownloadToFile(context.Background(), "artifact", filePath, &objectOptions); err != nil {
+			t.Fatal(err)
+		} else if n != uint64(expectedN) {
+			t.Fatalf("unexpected downloaded size: %d", n)
+		} else if notModified != expectedNotModified {
+			t.Fatalf("unexpected not modified count: %d", notModified)
+		}
+		dataMutex.Lock()
+		expected := currentData
+		dataMutex.Unlock()
+		if content, err := os.ReadFile(filePath); err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(content, expected) {
+			t.Fatalf("unexpected file content")
+		}
+	}
+
+	// 本地文件不存在，正常下载并记录 Etag
+	download(len(data), 0)
+	getCount := atomic.LoadInt64(&getRequests)
+
+	// 对象没有变化，不再下载
+	download(0, 1)
+	if atomic.LoadInt64(&getRequests) != getCount {
+		t.Fatalf("object should not be downloaded again")
+	}
+
+	// 对象变小，重新下载并截断本地文件
+	dataMutex.Lock()
+	currentData = []byte("changed")
+	currentETag = "changedetag"
+	currentModify = time.Now()
+	dataMutex.Unlock()
+	download(len("changed"), 1)
+	download(0, 2)
+
+	// 不使用附属文件时根据本地文件内容计算 Etag
+	dataMutex.Lock()
+	currentData, currentETag, currentModify = data, dataETag, modifiedAt
+	dataMutex.Unlock()
+	objectOptions.Conditional = &downloader.ConditionalOptions{OnNotModified: func() { notModified += 1 }}
+	download(len(data), 2)
+	download(0, 3)
+
+	// 使用本地文件修改时间
+	dataMutex.Lock()
+	currentETag = "anotheretag"
+	dataMutex.Unlock()
+	objectOptions.Conditional = &downloader.ConditionalOptions{
+		SidecarFilePath: filepath.Join(tmpDir, "not-exists.etag"),
+		IfModifiedSince: true,
+		OnNotModified:   func() { notModified += 1 },
+	}
+	download(0, 4)
+}
+
+func TestDownloadManagerDownloadArchivedObjects(t *testing.T) {
+	type archivedObject struct {
+		data          []byte
+		storageClass  objects.StorageClass
+		restoreStatus objects.RestoreStatus
+	}
+	var (
+		lock     sync.Mutex
+		archived = map[string]*archivedObject{
+			"normal.txt":    {data: []byte("normal object")},
+			"archived1.txt": {data: []byte("archived object 1"), storageClass: objects.ArchiveStorageClass},
+			"archived2.txt": {data: []byte("archived object 2"), storageClass: objects.DeepArchiveStorageClass},
+		}
+		restoreRequests, batchRequests int64
+	)
+	decodeEntry := func(encoded string) string {
+		entry, err := base64.URLEncoding.DecodeString(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimPrefix(string(entry), "bucket1:")
+	}
+	// 返回值表示是否成功发起解冻
+	restore := func(objectName string) bool {
+		lock.Lock()
+		defer lock.Unlock()
+		if object := archived[objectName]; object.restoreStatus == objects.FrozenStatus {
+			object.restoreStatus = objects.RestoringStatus
+			return true
+		}
+		return false
+	}
+
+	rsMux := http.NewServeMux()
+	rsMux.HandleFunc("/stat/", func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		object := archived[decodeEntry(strings.TrimPrefix(r.URL.Path, "/stat/"))]
+		response := stat_object.Response{
+			Size:            int64(len(object.data)),
+			Hash:            "testhash",
+			MimeType:        "text/plain",
+			PutTime:         time.Now().UnixNano() / 100,
+			Type:            int64(object.storageClass),
+			RestoringStatus: int64(object.restoreStatus),
+		}
+		// 每次查询后推进解冻进度
+		if object.restoreStatus == objects.RestoringStatus {
+			object.restoreStatus = objects.RestoredStatus
+		}
+		lock.Unlock()
+		jsonData, err := json.Marshal(&response)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("X-ReqId", "fakereqid")
+		w.Write(jsonData)
+	})
+	rsMux.HandleFunc("/restoreAr/", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&restoreRequests, 1)
+		encoded := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/restoreAr/"), "/freezeAfterDays/2")
+		if !restore(decodeEntry(encoded)) {
+			t.Fatalf("unexpected restore request: %s", r.URL.Path)
+		}
+		w.Header().Add("X-ReqId", "fakereqid")
+	})
+	rsMux.HandleFunc("/batch", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&batchRequests, 1)
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		responses := make([]batch_ops.OperationResponse, 0, len(r.PostForm["op"]))
+		for _, op := range r.PostForm["op"] {
+			if !strings.HasPrefix(op, "restoreAr/") || !strings.HasSuffix(op, "/freezeAfterDays/2") {
+				t.Fatalf("unexpected op: %s", op)
+			}
+			if restore(decodeEntry(strings.TrimSuffix(strings.TrimPrefix(op, "restoreAr/"), "/freezeAfterDays/2"))) {
+				responses = append(responses, batch_ops.OperationResponse{Code: 200})
+			} else {
+				responses = append(responses, batch_ops.OperationResponse{Code: 400, Data: batch_ops.OperationResponseData{Error: "already restored"}})
+			}
+		}
+		jsonData, err := json.Marshal(&batch_ops.Response{OperationResponses: responses})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("X-ReqId", "fakereqid")
+		w.Write(jsonData)
+	})
+	rsServer := httptest.NewServer(rsMux)
+	defer rsServer.Close()
+
+	rsfMux := http.NewServeMux()
+	rsfMux.HandleFunc("/list", func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		items := make([]get_objects.ListedObjectEntry, 0, len(archived))
+		for _, name := range []string{"archived1.txt", "archived2.txt", "normal.txt"} {
+			// 列举结果不包含解冻状态
+			items = append(items, get_objects.ListedObjectEntry{
+				Key:      name,
+				PutTime:  time.Now().UnixNano() / 100,
+				Hash:     "testhash",
+				Size:     int64(len(archived[name].data)),
+				MimeType: "text/plain",
+				Type:     int64(archived[name].storageClass),
+			})
+		}
+		lock.Unlock()
+		jsonData, err := json.Marshal(&get_objects.Response{Items: items})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("X-ReqId", "fakereqid")
+		w.Write(jsonData)
+	})
+	rsfServer := httptest.NewServer(rsfMux)
+	defer rsfServer.Close()
+
+	ioServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		object := archived[strings.TrimPrefix(r.URL.Path, "/")]
+		frozen := object.storageClass != objects.StandardStorageClass && object.restoreStatus != objects.RestoredStatus
+		lock.Unlock()
+		w.Header().Add("X-ReqId", "fakereqid")
+		if frozen {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error":"file is frozen"}`))
+			return
+		}
+		w.Header().Set("ETag", `"testhash"`)
+		http.ServeContent(w, r, "", time.Now(), bytes.NewReader(object.data))
+	}))
+	defer ioServer.Close()
+
+	var restoreRequested, restored []string
+	downloadManager := downloader.NewDownloadManager(&downloader.DownloadManagerOptions{
+		Options: http_client.Options{
+			Regions: &region.Region{
+				Rs:  region.Endpoints{Preferred: []string{rsServer.URL}},
+				Rsf: region.Endpoints{Preferred: []string{rsfServer.URL}},
+			},
+			Credentials:         credentials.NewCredentials("testaccesskey", "testsecretkey"),
+			UseInsecureProtocol: true,
+		},
+		ArchiveRestore: &downloader.ArchiveRestoreOptions{
+			FreezeAfterDays: 2,
+			PollBackoff:     backoff.NewFixedBackoff(time.Millisecond),
+			OnRestoreRequested: func(bucketName, objectName string) {
+				lock.Lock()
+				defer lock.Unlock()
+				restoreRequested = append(restoreRequested, objectName)
+			},
+			OnRestored: func(bucketName, objectName string) {
+				lock.Lock()
+				defer lock.Unlock()
+				restored = append(restored, objectName)
+			},
+		},
+	})
+	urlsProvider := downloader.NewStaticDomainBasedURLsProvider([]string{ioServer.URL})
+
+	tmpDir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	filePath := filepath.Join(tmpDir, "archived1.txt")
+	if _, err = downloadManager.DownloadToFile(context.Background(), "archived1.txt", filePath, &downloader.ObjectOptions{
+		GenerateOptions:      downloader.GenerateOptions{BucketName: "bucket1", UseInsecureProtocol: true},
+		DownloadURLsProvider: urlsProvider,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filePath); err != nil {
+		t.Fatal(err)
+	} else if string(data) != "archived object 1" {
+		t.Fatalf("unexpected file content: %s", data)
+	}
+	if atomic.LoadInt64(&restoreRequests) != 1 || len(restoreRequested) != 1 || len(restored) != 1 {
+		t.Fatalf("unexpected restore requests: %d, %v, %v", restoreRequests, restoreRequested, restored)
+	}
+
+	// 没有指定空间名称时无法解冻
+	if _, err = downloadManager.DownloadToFile(context.Background(), "archived2.txt", filepath.Join(tmpDir, "archived2.txt"), &downloader.ObjectOptions{
+		GenerateOptions:      downloader.GenerateOptions{UseInsecureProtocol: true},
+		DownloadURLsProvider: urlsProvider,
+	}); err == nil {
+		t.Fatalf("expected error for frozen object without bucket name")
+	}
+
+	dirPath := filepath.Join(tmpDir, "dir")
+	if err = downloadManager.DownloadDirectory(context.Background(), dirPath, &downloader.DirectoryOptions{
+		UseInsecureProtocol:  true,
+		BucketName:           "bucket1",
+		DownloadURLsProvider: urlsProvider,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for name, object := range archived {
+		if data, err := os.ReadFile(filepath.Join(dirPath, name)); err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(data, object.data) {
+			t.Fatalf("unexpected file content of %s: %s", name, data)
+		}
+	}
+	if atomic.LoadInt64(&restoreRequests) != 1 || atomic.LoadInt64(&batchRequests) != 1 {
+		t.Fatalf("archived objects should be restored in batch: %d, %d", restoreRequests, batchRequests)
+	}
+	if len(restoreRequested) != 2 || restoreRequested[1] != "archived2.txt" || len(restored) != 3 {
+		t.Fatalf("unexpected restore callbacks: %v, %v", restoreRequested, restored)
+	}
+}
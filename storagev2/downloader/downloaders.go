@@ -30,6 +30,7 @@ type (
 		partSize          uint64
 		client            clientv2.Client
 		resumableRecorder resumablerecorder.ResumableRecorder
+		spreadURLs        bool
 	}
 
 	// 下载器选项
@@ -97,8 +98,8 @@ func NewConcurrentDownloader(options *ConcurrentDownloaderOptions) DestinationDo
 	if partSize == 0 {
 		partSize = 16 * 1024 * 1024
 	}
-	client := clientv2.NewClient(options.Client, clientv2.NewSimpleRetryInterceptor(options.toSimpleRetryConfig()), retryWhenTokenOutOfDateInterceptor{})
-	return &concurrentDownloader{concurrency, partSize, client, options.ResumableRecorder}
+	client := clientv2.NewClient(options.Client, clientv2.NewSimpleRetryInterceptor(options.toSimpleRetryConfig()), retryWhenTokenOutOfDateInterceptor{}, clientv2.NewBandwidthLimitInterceptor(nil))
+	return &concurrentDownloader{concurrency, partSize, client, options.ResumableRecorder, false}
 }
 
 func (downloader concurrentDownloader) Download(ctx context.Context, urlsIter URLsIter, dest destination.Destination, options *DestinationDownloadOptions) (uint64, error) {
@@ -191,9 +192,13 @@ func (downloader concurrentDownloader) Download(ctx context.Context, urlsIter UR
 		downloadingProgress      = newDownloadingPartsProgress()
 		downloadingProgressMutex sync.Mutex
 	)
-	for _, part := range parts {
+	urlsCount := 1
+	if downloader.spreadURLs {
+		urlsCount = countURLs(urlsIter)
+	}
+	for i, part := range parts {
 		p := part
-		urlsIterClone := urlsIter.Clone()
+		urlsIterClone := rotateURLsIter(urlsIter, i%urlsCount)
 		g.Go(func() error {
 			n, err := downloader.downloadToPart(ctx, urlsIterClone, etag, offset, options.Header, p, writeableMedium, &downloadingProgressMutex, func(downloaded uint64) {
 				downloadingProgress.setPartDownloadingProgress(p.Offset(), downloaded)
@@ -315,13 +320,16 @@ func _downloadToPartReader(
 			Body:   http.NoBody,
 		}
 		ctx = context.WithValue(ctx, urlsIterContextKey{}, urlsIter)
+		startedAt := time.Now()
 		if response, err = client.Do(req.WithContext(ctx)); err != nil {
+			feedbackURLsIter(urlsIter, &u, time.Since(startedAt), 0, 0, err)
 			if !retrier.IsErrorRetryable(err) {
 				return 0, err
 			}
 			urlsIter.Next()
 			continue
 		}
+		latency := time.Since(startedAt)
 		var (
 			bodyReader io.Reader = response.Body
 			bodyCloser io.Closer = response.Body
@@ -337,16 +345,19 @@ func _downloadToPartReader(
 			case "":
 				n, err = part.CopyFrom(bodyReader, onDownloadingProgress)
 				bodyCloser.Close()
+				feedbackURLsIter(urlsIter, &u, latency, time.Since(startedAt)-latency, n, err)
 				if n > 0 {
 					return n, err
 				}
 			default:
 				bodyCloser.Close()
 				err = errors.New("unrecognized content-encoding")
+				feedbackURLsIter(urlsIter, &u, latency, 0, 0, err)
 			}
 		} else {
 			bodyCloser.Close()
 			err = errors.New("etag dismatch")
+			feedbackURLsIter(urlsIter, &u, latency, 0, 0, err)
 		}
 		urlsIter.Next()
 	}
@@ -380,12 +391,18 @@ func headRequest(ctx context.Context, urlsIter URLsIter, headers http.Header, cl
 			Header: headers,
 			Body:   http.NoBody,
 		}
+		startedAt := time.Now()
 		if response, err = client.Do(req.WithContext(ctx)); err != nil {
+			feedbackURLsIter(urlsIter, &u, time.Since(startedAt), 0, 0, err)
 			if !retrier.IsErrorRetryable(err) {
 				return
 			}
 			urlsIter.Next()
 			continue
+		} else if response.StatusCode >= 500 {
+			feedbackURLsIter(urlsIter, &u, time.Since(startedAt), 0, 0, &clientv1.ErrorInfo{Code: response.StatusCode})
+		} else {
+			feedbackURLsIter(urlsIter, &u, time.Since(startedAt), 0, 0, nil)
 		}
 		break
 	}
@@ -582,3 +582,73 @@ type closableBuffer struct {
 func (w closableBuffer) Close() error {
 	return nil
 }
+
+func TestParallelRangeDownloader(t *testing.T) {
+	const SIZE = 8*1024*1024 + 17
+	data := make([]byte, SIZE)
+	rand.New(rand.NewSource(time.Now().UnixNano())).Read(data)
+
+	var counts [3]uint64
+	handler := func(id int, w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			atomic.AddUint64(&counts[id-1], 1)
+			if id == 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.Header().Set("Etag", "testetag1")
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+	}
+	urls := make([]*url.URL, 0, 3)
+	for id := 1; id <= 3; id++ {
+		server := newTestServer(id, handler)
+		defer server.Close()
+		u, err := url.Parse(server.URL + "/testfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		urls = append(urls, u)
+	}
+
+	dstFilePath := filepath.Join(t.TempDir(), "testfile")
+	dest, err := destination.NewFileDestination(dstFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dest.Close()
+
+	d := downloader.NewParallelRangeDownloader(&downloader.ParallelRangeDownloaderOptions{
+		Concurrency: 4,
+		PartSize:    1024 * 1024,
+		DownloaderOptions: downloader.DownloaderOptions{
+			RetryMax: 1,
+			Backoff:  backoff.NewFixedBackoff(0),
+			Resolver: resolver.NewDefaultResolver(),
+			Chooser:  chooser.NewDirectChooser(),
+		},
+	})
+	var lastDownloaded uint64
+	n, err := d.Download(context.Background(), downloader.NewURLsIter(urls), dest, &downloader.DestinationDownloadOptions{
+		OnDownloadingProgress: func(progress *downloader.DownloadingProgress) {
+			if progress.Downloaded < atomic.LoadUint64(&lastDownloaded) || progress.TotalSize != SIZE {
+				t.Fatalf("unexpected downloaded progress")
+			}
+			atomic.StoreUint64(&lastDownloaded, progress.Downloaded)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if n != SIZE || lastDownloaded != SIZE {
+		t.Fatalf("unexpected downloaded size")
+	}
+	if downloaded, err := os.ReadFile(dstFilePath); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(downloaded, data) {
+		t.Fatalf("unexpected downloaded data")
+	}
+	// 分片应当分散到各个 URL 上，失败的分片切换到其他 URL
+	if counts[0] == 0 || counts[1] == 0 || counts[2] == 0 {
+		t.Fatalf("parts should be spread across all urls: %v", counts)
+	}
+}
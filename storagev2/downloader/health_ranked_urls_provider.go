@@ -0,0 +1,269 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	clientv1 "github.com/qiniu/go-sdk/v7/client"
+	"github.com/qiniu/go-sdk/v7/internal/freezer"
+	"github.com/qiniu/go-sdk/v7/storagev2/retrier"
+)
+
+type (
+	// 基于健康度排序的下载 URL 生成器
+	HealthRankedURLsProvider interface {
+		DownloadURLsProvider
+
+		// 获取域名的健康状况，尚未记录过该域名时返回 false
+		DomainHealth(host string) (DomainHealth, bool)
+	}
+
+	// 基于健康度排序的下载 URL 生成器选项
+	HealthRankedURLsProviderOptions struct {
+		// 发生网络错误或服务端错误后域名的冻结时间，默认为 1 分钟，冻结的域名将被排在最后
+		FreezeDuration time.Duration
+
+		// 统计数据的平滑系数，取值范围为 (0, 1]，越大则越看重最近的请求，默认为 0.3
+		SmoothingFactor float64
+	}
+
+	// 域名健康状况
+	DomainHealth struct {
+		Latency    time.Duration // 平均响应时间
+		Throughput float64       // 平均吞吐量，单位为字节每秒，尚未成功传输过数据时为 0
+		ErrorRate  float64       // 平均错误率，取值范围为 [0, 1]
+		Requests   uint64        // 记录的请求次数
+		Frozen     bool          // 是否被冻结
+	}
+
+	healthRankedURLsProvider struct {
+		provider        DownloadURLsProvider
+		freezer         freezer.Freezer
+		freezeDuration  time.Duration
+		smoothingFactor float64
+		mutex           sync.Mutex
+		domains         map[string]*DomainHealth
+	}
+
+	healthRankedURLsIter struct {
+		provider *healthRankedURLsProvider
+		urlsIter URLsIter
+		order    []int
+		cursor   int
+	}
+
+	// URL 迭代器可选实现的接口，用于接收每次请求的结果
+	urlsIterFeedback interface {
+		feedback(u *url.URL, latency, transferTime time.Duration, transferred uint64, err error)
+	}
+)
+
+// 估算域名得分时使用的传输数据量
+const healthRankReferenceSize = 1 << 20
+
+// 创建基于健康度排序的下载 URL 生成器
+//
+// 下载器会将每次请求的响应时间、传输速度和错误反馈给生成的 URL 迭代器，
+// 据此按照域名统计健康状况，之后生成的 URL 将优先使用响应最快、吞吐量最大且错误率最低的域名，
+// 请求失败的域名将被冻结一段时间并排在最后。尚未记录过的域名排在最前，以便尽快获得统计数据。
+// 应当作为最外层的 URL 生成器使用，例如包裹 SignURLsProvider 的返回值。
+func NewHealthRankedURLsProvider(provider DownloadURLsProvider, options *HealthRankedURLsProviderOptions) HealthRankedURLsProvider {
+	if options == nil {
+		options = &HealthRankedURLsProviderOptions{}
+	}
+	freezeDuration := options.FreezeDuration
+	if freezeDuration == 0 {
+		freezeDuration = time.Minute
+	}
+	smoothingFactor := options.SmoothingFactor
+	if smoothingFactor <= 0 || smoothingFactor > 1 {
+		smoothingFactor = 0.3
+	}
+	return &healthRankedURLsProvider{
+		provider:        provider,
+		freezer:         freezer.New(),
+		freezeDuration:  freezeDuration,
+		smoothingFactor: smoothingFactor,
+		domains:         make(map[string]*DomainHealth),
+	}
+}
+
+func (provider *healthRankedURLsProvider) GetURLsIter(ctx context.Context, objectName string, options *GenerateOptions) (URLsIter, error) {
+	urlsIter, err := provider.provider.GetURLsIter(ctx, objectName, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		u      url.URL
+		hosts  []string
+		cloned = urlsIter.Clone()
+	)
+	cloned.Reset()
+	for {
+		if ok, err := cloned.Peek(&u); err != nil {
+			return nil, err
+		} else if !ok {
+			break
+		}
+		hosts = append(hosts, u.Host)
+		cloned.Next()
+	}
+
+	order := make([]int, len(hosts))
+	frozen := make([]bool, len(hosts))
+	scores := make([]float64, len(hosts))
+	for i, host := range hosts {
+		order[i] = i
+		frozen[i] = !provider.freezer.Available(host)
+		scores[i] = provider.score(host)
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		left, right := order[i], order[j]
+		if frozen[left] != frozen[right] {
+			return !frozen[left]
+		}
+		return scores[left] < scores[right]
+	})
+	return &healthRankedURLsIter{provider: provider, urlsIter: urlsIter, order: order}, nil
+}
+
+func (provider *healthRankedURLsProvider) DomainHealth(host string) (DomainHealth, bool) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	health, ok := provider.domains[host]
+	if !ok {
+		return DomainHealth{}, false
+	}
+	result := *health
+	result.Frozen = !provider.freezer.Available(host)
+	return result, true
+}
+
+// 预计传输参考数据量所需的时间，并按照错误率加权，越小越好
+func (provider *healthRankedURLsProvider) score(host string) float64 {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	health, ok := provider.domains[host]
+	if !ok {
+		return 0
+	}
+	expected := health.Latency.Seconds()
+	if health.Throughput > 0 {
+		expected += healthRankReferenceSize / health.Throughput
+	}
+	return expected * (1 + 10*health.ErrorRate)
+}
+
+func (provider *healthRankedURLsProvider) record(host string, latency, transferTime time.Duration, transferred uint64, err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+
+	provider.mutex.Lock()
+	health, ok := provider.domains[host]
+	if !ok {
+		health = &DomainHealth{}
+		provider.domains[host] = health
+	}
+	alpha := provider.smoothingFactor
+	if health.Requests == 0 {
+		alpha = 1
+	}
+	health.Requests += 1
+	health.Latency = time.Duration(float64(health.Latency)*(1-alpha) + float64(latency)*alpha)
+	if transferred > 0 && transferTime > 0 {
+		throughput := float64(transferred) / transferTime.Seconds()
+		if health.Throughput == 0 {
+			health.Throughput = throughput
+		} else {
+			health.Throughput = health.Throughput*(1-alpha) + throughput*alpha
+		}
+	}
+	hostFailed := isHostFailure(err)
+	var failed float64
+	if hostFailed {
+		failed = 1
+	}
+	health.ErrorRate = health.ErrorRate*(1-alpha) + failed*alpha
+	provider.mutex.Unlock()
+
+	if hostFailed {
+		provider.freezer.Freeze(host, provider.freezeDuration)
+	} else if err == nil {
+		provider.freezer.Unfreeze(host)
+	}
+}
+
+// 错误是否说明域名不可用
+//
+// 仅网络错误和可重试的服务端状态码计入域名的错误率，例如 404 或 403 等客户端错误与域名健康状况无关
+func isHostFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var errorInfo *clientv1.ErrorInfo
+	if errors.As(err, &errorInfo) {
+		return retrier.IsStatusCodeRetryable(errorInfo.Code)
+	}
+	return retrier.IsErrorRetryable(err)
+}
+
+func (iter *healthRankedURLsIter) Peek(u *url.URL) (bool, error) {
+	if iter.cursor >= len(iter.order) {
+		return false, nil
+	}
+	// 每次都从内部迭代器中取出 URL，使得签名等操作仍然在使用时才进行
+	cloned := iter.urlsIter.Clone()
+	cloned.Reset()
+	for i := 0; i < iter.order[iter.cursor]; i++ {
+		cloned.Next()
+	}
+	return cloned.Peek(u)
+}
+
+func (iter *healthRankedURLsIter) Next() {
+	if iter.cursor < len(iter.order) {
+		iter.cursor += 1
+	}
+}
+
+func (iter *healthRankedURLsIter) Reset() {
+	iter.cursor = 0
+}
+
+func (iter *healthRankedURLsIter) Clone() URLsIter {
+	return &healthRankedURLsIter{
+		provider: iter.provider,
+		urlsIter: iter.urlsIter.Clone(),
+		order:    iter.order,
+		cursor:   iter.cursor,
+	}
+}
+
+func (iter *healthRankedURLsIter) feedback(u *url.URL, latency, transferTime time.Duration, transferred uint64, err error) {
+	iter.provider.record(u.Host, latency, transferTime, transferred, err)
+}
+
+func (s *signedURLsIter) feedback(u *url.URL, latency, transferTime time.Duration, transferred uint64, err error) {
+	feedbackURLsIter(s.urlsIter, u, latency, transferTime, transferred, err)
+}
+
+func (c *combinedURLsIter) feedback(u *url.URL, latency, transferTime time.Duration, transferred uint64, err error) {
+	if len(c.iters) > 0 {
+		feedbackURLsIter(c.iters[0], u, latency, transferTime, transferred, err)
+	}
+}
+
+// 将请求结果反馈给 URL 迭代器，迭代器不接收反馈时忽略
+func feedbackURLsIter(urlsIter URLsIter, u *url.URL, latency, transferTime time.Duration, transferred uint64, err error) {
+	if f, ok := urlsIter.(urlsIterFeedback); ok {
+		f.feedback(u, latency, transferTime, transferred, err)
+	}
+}
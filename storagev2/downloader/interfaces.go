@@ -58,7 +58,7 @@ type (
 		// 空间名称，可选
 		BucketName string
 
-		// 文件处理命令，可选
+		// 文件处理命令，可选，可以通过 fop 包构建
 		Command string
 
 		// 是否使用 HTTP 协议，默认为不使用
@@ -69,5 +69,9 @@ type (
 	SignOptions struct {
 		// 签名有效期，如果不填写，默认为 3 分钟
 		TTL time.Duration
+
+		// 签名时间窗口，如果设置，过期时间将向上对齐到窗口的整数倍，实际有效期介于 TTL 和 TTL + TimeBucket 之间，
+		// 同一窗口内对同一 URL 的签名结果保持不变，有利于提高 CDN 缓存命中率，最小为 1 秒
+		TimeBucket time.Duration
 	}
 )
@@ -0,0 +1,387 @@
+package downloader
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	clientv1 "github.com/qiniu/go-sdk/v7/client"
+	"github.com/qiniu/go-sdk/v7/internal/clientv2"
+	"github.com/qiniu/go-sdk/v7/storagev2/bandwidth"
+	"github.com/qiniu/go-sdk/v7/storagev2/encryption"
+)
+
+type (
+	// 对象读取器，支持随机读取
+	//
+	// 所有读取都通过 HTTP Range 请求完成，不会下载整个对象。ReadAt 可以被并发调用，Read 和 Seek 共享同一个读取位置。
+	ObjectReader interface {
+		io.ReadSeekCloser
+		io.ReaderAt
+
+		// 对象大小
+		Size() int64
+
+		// 对象 Etag
+		ETag() string
+	}
+
+	// 对象读取器选项
+	ObjectReaderOptions struct {
+		GenerateOptions
+
+		// 下载 URL 生成器
+		DownloadURLsProvider DownloadURLsProvider
+
+		// 对象下载附加 HTTP Header，不能包含 Range
+		Header http.Header
+
+		// 带宽限制器，如果设置，则覆盖 HTTP 客户端选项中的带宽限制器
+		BandwidthLimiter *bandwidth.Limiter
+
+		// 块大小，读取时按块对齐请求数据，默认为 1 MB
+		BlockSize uint64
+
+		// LRU 缓存的最大块数，默认为 0，表示不缓存，每次读取都直接请求所需范围
+		CacheBlocks int
+
+		// 预读块数，读取某个块后在后台预先下载其后的块，默认为 0，表示不预读
+		// 预读的块保存在缓存中，因此缓存块数至少为预读块数加一
+		ReadAheadBlocks int
+	}
+
+	objectReader struct {
+		ctx       context.Context
+		cancel    context.CancelFunc
+		client    clientv2.Client
+		urlsIter  URLsIter
+		header    http.Header
+		etag      string
+		size      int64
+		blockSize int64
+		capacity  int
+		readAhead int
+
+		cacheMutex sync.Mutex
+		blocks     map[int64]*list.Element
+		lru        *list.List
+
+		offsetMutex sync.Mutex
+		offset      int64
+		closed      int32
+	}
+
+	objectReaderBlock struct {
+		index int64
+		done  chan struct{}
+		data  []byte
+		err   error
+	}
+
+	bytesPartWriter struct {
+		buf   *bytes.Buffer
+		limit int64
+	}
+)
+
+var errObjectReaderClosed = errors.New("object reader is closed")
+
+// 打开对象用于随机读取
+//
+// 打开时发出 HEAD 请求获取对象大小和 Etag，之后的每次读取都发出 Range 请求，并校验 Etag 确保读取的是同一个对象。
+// 要求服务器支持范围请求，不支持客户端加密的对象。使用完毕后必须调用 Close 关闭。
+// ctx 仅用于打开对象时的请求，之后的读取不受其影响，调用 Close 时取消所有正在进行的读取和预读。
+func (downloadManager *DownloadManager) OpenObject(ctx context.Context, objectName string, options *ObjectReaderOptions) (ObjectReader, error) {
+	if options == nil {
+		options = &ObjectReaderOptions{}
+	}
+	if options.Header.Get("Range") != "" {
+		return nil, errors.New("range header is not allowed for object reader")
+	}
+	if limiter := options.BandwidthLimiter; limiter != nil {
+		ctx = bandwidth.WithLimiter(ctx, limiter)
+	} else if limiter = downloadManager.options.BandwidthLimiter; limiter != nil && bandwidth.LimiterFromContext(ctx) == nil {
+		ctx = bandwidth.WithLimiter(ctx, limiter)
+	}
+	urlsIter, err := downloadManager.getURLsIter(ctx, objectName, options.DownloadURLsProvider, &options.GenerateOptions)
+	if err != nil {
+		return nil, err
+	}
+	client := downloadManager.newObjectReaderClient()
+	headResponse, err := headRequest(ctx, urlsIter, options.Header, client)
+	if err != nil {
+		return nil, err
+	} else if headResponse == nil {
+		return nil, errors.New("no url tried")
+	} else if headResponse.StatusCode != http.StatusOK {
+		return nil, clientv1.ResponseError(headResponse)
+	}
+	if _, err = encryption.EnvelopeFromHeader(headResponse.Header); err != encryption.ErrNotEncrypted {
+		if err == nil {
+			err = errors.New("client-side encrypted object does not support random access")
+		}
+		return nil, err
+	}
+	if headResponse.ContentLength < 0 {
+		return nil, errors.New("unable to determine object size")
+	} else if headResponse.ContentLength > 0 && headResponse.Header.Get("Accept-Ranges") != "bytes" {
+		return nil, errors.New("server does not support range requests")
+	}
+
+	blockSize := int64(options.BlockSize)
+	if blockSize == 0 {
+		blockSize = 1024 * 1024
+	}
+	readAhead := options.ReadAheadBlocks
+	if readAhead < 0 {
+		readAhead = 0
+	}
+	capacity := options.CacheBlocks
+	if capacity < 0 {
+		capacity = 0
+	}
+	if readAhead > 0 && capacity < readAhead+1 {
+		capacity = readAhead + 1
+	}
+	// 读取可能远晚于打开，因此不使用打开时的 ctx，仅沿用其中的带宽限制器
+	readCtx := context.Background()
+	if limiter := bandwidth.LimiterFromContext(ctx); limiter != nil {
+		readCtx = bandwidth.WithLimiter(readCtx, limiter)
+	}
+	readCtx, cancel := context.WithCancel(readCtx)
+	return &objectReader{
+		ctx:       readCtx,
+		cancel:    cancel,
+		client:    client,
+		urlsIter:  urlsIter,
+		header:    cloneHeader(options.Header),
+		etag:      parseEtag(headResponse.Header.Get("Etag")),
+		size:      headResponse.ContentLength,
+		blockSize: blockSize,
+		capacity:  capacity,
+		readAhead: readAhead,
+		blocks:    make(map[int64]*list.Element),
+		lru:       list.New(),
+	}, nil
+}
+
+func (downloadManager *DownloadManager) newObjectReaderClient() clientv2.Client {
+	options := downloadManager.options
+	downloaderOptions := DownloaderOptions{
+		Resolver:      options.Resolver,
+		Chooser:       options.Chooser,
+		BeforeResolve: options.BeforeResolve,
+		AfterResolve:  options.AfterResolve,
+		ResolveError:  options.ResolveError,
+		BeforeBackoff: options.BeforeBackoff,
+		AfterBackoff:  options.AfterBackoff,
+		BeforeRequest: options.BeforeRequest,
+		AfterResponse: options.AfterResponse,
+	}
+	if hostRetryConfig := options.HostRetryConfig; hostRetryConfig != nil {
+		downloaderOptions.RetryMax = hostRetryConfig.RetryMax
+		downloaderOptions.Backoff = hostRetryConfig.Backoff
+	}
+	return clientv2.NewClient(options.BasicHTTPClient, clientv2.NewSimpleRetryInterceptor(downloaderOptions.toSimpleRetryConfig()), retryWhenTokenOutOfDateInterceptor{}, clientv2.NewBandwidthLimitInterceptor(nil))
+}
+
+func (reader *objectReader) Size() int64 {
+	return reader.size
+}
+
+func (reader *objectReader) ETag() string {
+	return reader.etag
+}
+
+func (reader *objectReader) Read(p []byte) (int, error) {
+	reader.offsetMutex.Lock()
+	defer reader.offsetMutex.Unlock()
+
+	n, err := reader.ReadAt(p, reader.offset)
+	reader.offset += int64(n)
+	if n > 0 && err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
+
+func (reader *objectReader) Seek(offset int64, whence int) (int64, error) {
+	reader.offsetMutex.Lock()
+	defer reader.offsetMutex.Unlock()
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += reader.offset
+	case io.SeekEnd:
+		offset += reader.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	reader.offset = offset
+	return offset, nil
+}
+
+func (reader *objectReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	} else if err := reader.err(); err != nil {
+		return 0, err
+	} else if off >= reader.size {
+		return 0, io.EOF
+	}
+	end := off + int64(len(p))
+	if end > reader.size {
+		end = reader.size
+	}
+
+	var n int
+	if reader.capacity == 0 {
+		data, err := reader.fetch(off, end)
+		n = copy(p, data)
+		if err != nil {
+			return n, err
+		}
+	} else {
+		lastIndex := (end - 1) / reader.blockSize
+		for index := off / reader.blockSize; index <= lastIndex; index++ {
+			data, err := reader.getBlock(index)
+			if err != nil {
+				return n, err
+			}
+			blockOffset := index * reader.blockSize
+			from, to := int64(0), int64(len(data))
+			if off > blockOffset {
+				from = off - blockOffset
+			}
+			if end < blockOffset+to {
+				to = end - blockOffset
+			}
+			n += copy(p[n:], data[from:to])
+		}
+		reader.prefetch(lastIndex)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// 关闭读取器，取消所有正在进行的预读并清空缓存
+func (reader *objectReader) Close() error {
+	atomic.StoreInt32(&reader.closed, 1)
+	reader.cancel()
+
+	reader.cacheMutex.Lock()
+	defer reader.cacheMutex.Unlock()
+
+	reader.blocks = make(map[int64]*list.Element)
+	reader.lru.Init()
+	return nil
+}
+
+func (reader *objectReader) err() error {
+	if atomic.LoadInt32(&reader.closed) != 0 {
+		return errObjectReaderClosed
+	}
+	return reader.ctx.Err()
+}
+
+// 从缓存中获取块，不存在时下载并加入缓存，下载失败的块不会被缓存
+func (reader *objectReader) getBlock(index int64) ([]byte, error) {
+	reader.cacheMutex.Lock()
+	elem, ok := reader.blocks[index]
+	if ok {
+		reader.lru.MoveToFront(elem)
+		reader.cacheMutex.Unlock()
+	} else {
+		elem = reader.addBlock(index)
+		reader.cacheMutex.Unlock()
+		reader.fetchBlock(elem.Value.(*objectReaderBlock))
+	}
+
+	block := elem.Value.(*objectReaderBlock)
+	select {
+	case <-block.done:
+	case <-reader.ctx.Done():
+		return nil, reader.err()
+	}
+	if block.err != nil {
+		reader.cacheMutex.Lock()
+		if reader.blocks[index] == elem {
+			delete(reader.blocks, index)
+			reader.lru.Remove(elem)
+		}
+		reader.cacheMutex.Unlock()
+		return nil, block.err
+	}
+	return block.data, nil
+}
+
+// 在后台预先下载 lastIndex 之后的块
+func (reader *objectReader) prefetch(lastIndex int64) {
+	reader.cacheMutex.Lock()
+	defer reader.cacheMutex.Unlock()
+
+	for index := lastIndex + 1; index <= lastIndex+int64(reader.readAhead) && index*reader.blockSize < reader.size; index++ {
+		if _, ok := reader.blocks[index]; !ok {
+			go reader.fetchBlock(reader.addBlock(index).Value.(*objectReaderBlock))
+		}
+	}
+}
+
+// 加入缓存并淘汰最久未使用的块，调用前必须持有 cacheMutex
+func (reader *objectReader) addBlock(index int64) *list.Element {
+	elem := reader.lru.PushFront(&objectReaderBlock{index: index, done: make(chan struct{})})
+	reader.blocks[index] = elem
+	for reader.lru.Len() > reader.capacity {
+		oldest := reader.lru.Back()
+		reader.lru.Remove(oldest)
+		delete(reader.blocks, oldest.Value.(*objectReaderBlock).index)
+	}
+	return elem
+}
+
+func (reader *objectReader) fetchBlock(block *objectReaderBlock) {
+	defer close(block.done)
+
+	from := block.index * reader.blockSize
+	end := from + reader.blockSize
+	if end > reader.size {
+		end = reader.size
+	}
+	block.data, block.err = reader.fetch(from, end)
+}
+
+// 下载 [from, end) 范围内的数据，连接中断时从中断处继续下载
+func (reader *objectReader) fetch(from, end int64) ([]byte, error) {
+	size := end - from
+	writer := bytesPartWriter{buf: bytes.NewBuffer(make([]byte, 0, size)), limit: size}
+	urlsIter := reader.urlsIter.Clone()
+	for haveRead := int64(0); haveRead < size; {
+		n, err := downloadToPartReaderWithOffsetAndSize(reader.ctx, urlsIter, reader.etag, uint64(from+haveRead), uint64(size-haveRead),
+			reader.header, reader.client, &writer, nil)
+		if n > 0 {
+			haveRead += int64(n)
+			continue
+		}
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		return writer.buf.Bytes(), err
+	}
+	return writer.buf.Bytes(), nil
+}
+
+func (writer *bytesPartWriter) CopyFrom(r io.Reader, _ func(uint64)) (uint64, error) {
+	n, err := writer.buf.ReadFrom(io.LimitReader(r, writer.limit-int64(writer.buf.Len())))
+	return uint64(n), err
+}
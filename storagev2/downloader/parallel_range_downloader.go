@@ -0,0 +1,76 @@
+package downloader
+
+import (
+	"net/url"
+
+	"github.com/qiniu/go-sdk/v7/internal/clientv2"
+	resumablerecorder "github.com/qiniu/go-sdk/v7/storagev2/downloader/resumable_recorder"
+)
+
+type (
+	// 并行范围下载器选项
+	ParallelRangeDownloaderOptions struct {
+		DownloaderOptions
+		Concurrency       uint                                // 并发度，默认为 8
+		PartSize          uint64                              // 分片大小，默认为 16 MB
+		ResumableRecorder resumablerecorder.ResumableRecorder // 可恢复记录仪
+	}
+)
+
+// 最多在这么多个 URL 之间分散分片
+const maxSpreadURLs = 64
+
+// 创建并行范围下载器
+//
+// 与并发下载器一样将对象按照范围切分为多个分片并行下载，区别在于分片会被均匀分散到 URL 迭代器中的各个 URL 上同时下载，
+// 从而同时利用多个下载域名的带宽。每个分片独立地在 URL 之间切换重试，下载进度汇总所有分片。
+// 仅当数据目标支持随机写入（例如文件）且服务器支持范围请求时才会并行下载。
+func NewParallelRangeDownloader(options *ParallelRangeDownloaderOptions) DestinationDownloader {
+	if options == nil {
+		options = &ParallelRangeDownloaderOptions{}
+	}
+	concurrency := options.Concurrency
+	if concurrency == 0 {
+		concurrency = 8
+	}
+	partSize := options.PartSize
+	if partSize == 0 {
+		partSize = 16 * 1024 * 1024
+	}
+	client := clientv2.NewClient(options.Client, clientv2.NewSimpleRetryInterceptor(options.toSimpleRetryConfig()), retryWhenTokenOutOfDateInterceptor{}, clientv2.NewBandwidthLimitInterceptor(nil))
+	return &concurrentDownloader{concurrency, partSize, client, options.ResumableRecorder, true}
+}
+
+// 计算 URL 迭代器中的 URL 数量，至少为 1
+func countURLs(urlsIter URLsIter) int {
+	var (
+		u     url.URL
+		count int
+	)
+	iter := urlsIter.Clone()
+	iter.Reset()
+	for count < maxSpreadURLs {
+		if ok, err := iter.Peek(&u); err != nil || !ok {
+			break
+		}
+		count += 1
+		iter.Next()
+	}
+	if count == 0 {
+		count = 1
+	}
+	return count
+}
+
+// 复制 URL 迭代器并向后切换 steps 次，越过末尾时从头开始
+func rotateURLsIter(urlsIter URLsIter, steps int) URLsIter {
+	var u url.URL
+	iter := urlsIter.Clone()
+	for i := 0; i < steps; i++ {
+		iter.Next()
+		if ok, err := iter.Peek(&u); err != nil || !ok {
+			iter.Reset()
+		}
+	}
+	return iter
+}
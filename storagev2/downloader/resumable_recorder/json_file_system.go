@@ -135,9 +135,13 @@ func (frr jsonFileSystemResumableRecorder) tryToClearPath(createdBefore time.Dur
 	}
 	defer file.Close()
 
-	decoder := json.NewDecoder(file)
+	return jsonBasedResumableRecorderCheckOutdated(json.NewDecoder(file), createdBefore)
+}
+
+// 检查记录是否在 createdBefore 之前创建，过期时返回错误，无法识别的记录不做处理
+func jsonBasedResumableRecorderCheckOutdated(decoder *json.Decoder, createdBefore time.Duration) error {
 	var lineOptions jsonBasedResumableRecorderOpenArgs
-	if err = decoder.Decode(&lineOptions); err != nil {
+	if err := decoder.Decode(&lineOptions); err != nil {
 		return nil
 	}
 	if lineOptions.Version != fileSystemResumableRecorderVersion {
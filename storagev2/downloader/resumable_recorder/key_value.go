@@ -0,0 +1,185 @@
+package resumablerecorder
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/qiniu/go-sdk/v7/internal/kvstore"
+)
+
+type (
+	// 键值存储接口，可以基于 Redis 等外部存储实现
+	KeyValueStore interface {
+		// 获取值，键不存在时返回 false
+		Get(key string) ([]byte, bool, error)
+
+		// 设置值
+		Set(key string, value []byte) error
+
+		// 在值的末尾追加数据，键不存在时创建
+		Append(key string, value []byte) error
+
+		// 删除键，键不存在时不返回错误
+		Delete(key string) error
+
+		// 遍历具有指定前缀的键值，fn 返回 false 时停止遍历
+		Range(prefix string, fn func(key string, value []byte) bool) error
+	}
+
+	// 单文件可恢复记录仪，使用完毕后应当调用 Close 关闭记录文件
+	SingleFileResumableRecorder interface {
+		ResumableRecorder
+		io.Closer
+	}
+
+	keyValueResumableRecorder struct {
+		store     KeyValueStore
+		keyPrefix string
+	}
+	singleFileResumableRecorder struct {
+		keyValueResumableRecorder
+		store *kvstore.FileStore
+	}
+	keyValueResumableRecorderReadableMedium struct {
+		decoder *json.Decoder
+	}
+	keyValueResumableRecorderWritableMedium struct {
+		store KeyValueStore
+		key   string
+	}
+)
+
+// 创建基于键值存储的可恢复记录仪
+//
+// 每个数据目标的记录保存在一个键中，键名为 keyPrefix 加上记录参数的哈希值，记录以追加的方式写入。
+// ClearOutdated 仅清理具有 keyPrefix 前缀且已经过期的键。
+func NewKeyValueResumableRecorder(store KeyValueStore, keyPrefix string) ResumableRecorder {
+	return keyValueResumableRecorder{store, keyPrefix}
+}
+
+// 创建内存可恢复记录仪，记录仅在当前进程内有效
+func NewMemoryResumableRecorder() ResumableRecorder {
+	return NewKeyValueResumableRecorder(kvstore.NewMemoryStore(), "")
+}
+
+// 创建单文件可恢复记录仪
+//
+// 所有记录保存在同一个文件中，避免大量下载时为每个数据目标创建一个记录文件，可以被多个进程同时使用。
+func NewSingleFileResumableRecorder(filePath string) SingleFileResumableRecorder {
+	_ = os.MkdirAll(filepath.Dir(filePath), 0700)
+	store := kvstore.NewFileStore(filePath)
+	return singleFileResumableRecorder{keyValueResumableRecorder{store, ""}, store}
+}
+
+// 关闭记录文件
+func (sfrr singleFileResumableRecorder) Close() error {
+	return sfrr.store.Close()
+}
+
+func (kvrr keyValueResumableRecorder) OpenForReading(options *ResumableRecorderOpenArgs) ReadableResumableRecorderMedium {
+	if options == nil {
+		options = &ResumableRecorderOpenArgs{}
+	}
+	if options.DestinationID == "" {
+		return nil
+	}
+
+	value, ok, err := kvrr.store.Get(kvrr.getKey(options))
+	if err != nil || !ok {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	if verified, err := jsonFileSystemResumableRecorderVerifyHeaderLine(decoder, options); err != nil || !verified {
+		return nil
+	}
+	return keyValueResumableRecorderReadableMedium{decoder}
+}
+
+func (kvrr keyValueResumableRecorder) OpenForAppending(options *ResumableRecorderOpenArgs) WriteableResumableRecorderMedium {
+	if options == nil {
+		options = &ResumableRecorderOpenArgs{}
+	}
+	if options.DestinationID == "" {
+		return nil
+	}
+
+	key := kvrr.getKey(options)
+	if _, ok, err := kvrr.store.Get(key); err != nil || !ok {
+		return nil
+	}
+	return keyValueResumableRecorderWritableMedium{kvrr.store, key}
+}
+
+func (kvrr keyValueResumableRecorder) OpenForCreatingNew(options *ResumableRecorderOpenArgs) WriteableResumableRecorderMedium {
+	if options == nil {
+		options = &ResumableRecorderOpenArgs{}
+	}
+	if options.DestinationID == "" {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := jsonFileSystemResumableRecorderWriteHeaderLine(json.NewEncoder(&buf), options); err != nil {
+		return nil
+	}
+	key := kvrr.getKey(options)
+	if err := kvrr.store.Set(key, buf.Bytes()); err != nil {
+		return nil
+	}
+	return keyValueResumableRecorderWritableMedium{kvrr.store, key}
+}
+
+func (kvrr keyValueResumableRecorder) Delete(options *ResumableRecorderOpenArgs) error {
+	return kvrr.store.Delete(kvrr.getKey(options))
+}
+
+func (kvrr keyValueResumableRecorder) ClearOutdated(createdBefore time.Duration) error {
+	var outdatedKeys []string
+	if err := kvrr.store.Range(kvrr.keyPrefix, func(key string, value []byte) bool {
+		if jsonBasedResumableRecorderCheckOutdated(json.NewDecoder(bytes.NewReader(value)), createdBefore) != nil {
+			outdatedKeys = append(outdatedKeys, key)
+		}
+		return true
+	}); err != nil {
+		return err
+	}
+	for _, key := range outdatedKeys {
+		if err := kvrr.store.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (kvrr keyValueResumableRecorder) getKey(options *ResumableRecorderOpenArgs) string {
+	return kvrr.keyPrefix + jsonFileSystemResumableRecorder{}.fileName(options)
+}
+
+func (medium keyValueResumableRecorderReadableMedium) Next(rr *ResumableRecord) error {
+	var jrr jsonBasedResumableRecord
+	if err := medium.decoder.Decode(&jrr); err != nil {
+		return err
+	}
+	*rr = ResumableRecord(jrr)
+	return nil
+}
+
+func (medium keyValueResumableRecorderReadableMedium) Close() error {
+	return nil
+}
+
+func (medium keyValueResumableRecorderWritableMedium) Write(rr *ResumableRecord) error {
+	line, err := json.Marshal(jsonBasedResumableRecord(*rr))
+	if err != nil {
+		return err
+	}
+	return medium.store.Append(medium.key, append(line, '\n'))
+}
+
+func (medium keyValueResumableRecorderWritableMedium) Close() error {
+	return nil
+}
@@ -0,0 +1,87 @@
+//go:build unit
+// +build unit
+
+package resumablerecorder_test
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	resumablerecorder "github.com/qiniu/go-sdk/v7/storagev2/downloader/resumable_recorder"
+)
+
+func TestKeyValueResumableRecorder(t *testing.T) {
+	singleFileRecorder := resumablerecorder.NewSingleFileResumableRecorder(filepath.Join(t.TempDir(), "records", "recorder.db"))
+	defer func() {
+		if err := singleFileRecorder.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	for name, recorder := range map[string]resumablerecorder.ResumableRecorder{
+		"memory":      resumablerecorder.NewMemoryResumableRecorder(),
+		"single-file": singleFileRecorder,
+	} {
+		t.Run(name, func(t *testing.T) {
+			options := resumablerecorder.ResumableRecorderOpenArgs{
+				ETag:          "testetag1",
+				DestinationID: "/tmp/fakeFile",
+				PartSize:      16 * 1024 * 1024,
+				TotalSize:     100 * 1024 * 1024,
+			}
+			if recorder.OpenForAppending(&options) != nil {
+				t.Fatalf("medium should not be appendable before created")
+			}
+			writableMedium := recorder.OpenForCreatingNew(&options)
+			for i := uint64(0); i < 2; i++ {
+				if err := writableMedium.Write(&resumablerecorder.ResumableRecord{
+					Offset:      i * 16 * 1024 * 1024,
+					PartSize:    16 * 1024 * 1024,
+					PartWritten: 16 * 1024 * 1024,
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			writableMedium.Close()
+			writableMedium = recorder.OpenForAppending(&options)
+			if err := writableMedium.Write(&resumablerecorder.ResumableRecord{
+				Offset:      2 * 16 * 1024 * 1024,
+				PartSize:    16 * 1024 * 1024,
+				PartWritten: 1024,
+			}); err != nil {
+				t.Fatal(err)
+			}
+			writableMedium.Close()
+
+			readableMedium := recorder.OpenForReading(&options)
+			for i := uint64(0); i < 3; i++ {
+				var rr resumablerecorder.ResumableRecord
+				if err := readableMedium.Next(&rr); err != nil {
+					t.Fatal(err)
+				} else if rr.Offset != i*16*1024*1024 {
+					t.Fatalf("unexpected record: %#v", rr)
+				}
+			}
+			var rr resumablerecorder.ResumableRecord
+			if err := readableMedium.Next(&rr); err != io.EOF {
+				t.Fatalf("io.EOF is expected, got %v", err)
+			}
+			readableMedium.Close()
+
+			if err := recorder.ClearOutdated(time.Hour); err != nil {
+				t.Fatal(err)
+			}
+			if readableMedium = recorder.OpenForReading(&options); readableMedium == nil {
+				t.Fatalf("records should be kept")
+			}
+			readableMedium.Close()
+			if err := recorder.ClearOutdated(-time.Hour); err != nil {
+				t.Fatal(err)
+			}
+			if recorder.OpenForReading(&options) != nil {
+				t.Fatalf("outdated records should be cleared")
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package downloader
 
 import (
 	"context"
+	"crypto/md5"
 	"fmt"
 	"net/url"
 	"strings"
@@ -10,9 +11,20 @@ import (
 	"github.com/qiniu/go-sdk/v7/storagev2/credentials"
 )
 
-type credentialsSigner struct {
-	credentials credentials.CredentialsProvider
-}
+type (
+	credentialsSigner struct {
+		credentials credentials.CredentialsProvider
+	}
+
+	cdnTimestampAntiLeechSigner struct {
+		encryptKey string
+	}
+
+	domainBasedSigner struct {
+		signers       map[string]Signer
+		defaultSigner Signer
+	}
+)
 
 // 创建基于七牛鉴权的下载 URL 签名
 func NewCredentialsSigner(credentials credentials.CredentialsProvider) Signer {
@@ -23,19 +35,78 @@ func (signer credentialsSigner) Sign(ctx context.Context, u *url.URL, options *S
 	if options == nil {
 		options = &SignOptions{}
 	}
-	ttl := options.TTL
-	if ttl == 0 {
-		ttl = 3 * time.Minute
-	}
-
 	cred, err := signer.credentials.Get(ctx)
 	if err != nil {
 		return err
 	}
-	u.RawQuery += signURL(u.String(), cred, time.Now().Add(ttl).Unix())
+	u.RawQuery += signURL(u.String(), cred, signDeadline(options))
+	return nil
+}
+
+// 创建基于 CDN 时间戳防盗链的下载 URL 签名
+//
+// encryptKey 为 CDN 域名配置的时间戳防盗链密钥，签名算法与 cdn.CreateTimestampAntileechURL 一致。
+func NewCDNTimestampAntiLeechSigner(encryptKey string) Signer {
+	return &cdnTimestampAntiLeechSigner{encryptKey}
+}
+
+func (signer cdnTimestampAntiLeechSigner) Sign(ctx context.Context, u *url.URL, options *SignOptions) error {
+	if options == nil {
+		options = &SignOptions{}
+	}
+	if query := u.Query(); query.Has("sign") && query.Has("t") {
+		return nil
+	}
+	deadline := signDeadline(options)
+	sign := md5.Sum([]byte(fmt.Sprintf("%s%s%x", signer.encryptKey, u.EscapedPath(), deadline)))
+	appendQuery := fmt.Sprintf("sign=%x&t=%x", sign, deadline)
+	if u.RawQuery == "" {
+		u.RawQuery = appendQuery
+	} else {
+		u.RawQuery += "&" + appendQuery
+	}
 	return nil
 }
 
+// 创建按域名选择的下载 URL 签名
+//
+// signers 的键为域名，可以包含端口，URL 将优先使用完全匹配主机和端口的签名，其次使用仅匹配域名的签名，
+// 都不匹配时使用 defaultSigner，defaultSigner 为 nil 时不对 URL 签名。
+func NewDomainBasedSigner(signers map[string]Signer, defaultSigner Signer) Signer {
+	copied := make(map[string]Signer, len(signers))
+	for domain, signer := range signers {
+		copied[strings.ToLower(domain)] = signer
+	}
+	return &domainBasedSigner{copied, defaultSigner}
+}
+
+func (signer domainBasedSigner) Sign(ctx context.Context, u *url.URL, options *SignOptions) error {
+	if s, ok := signer.signers[strings.ToLower(u.Host)]; ok {
+		return signSkipNil(ctx, s, u, options)
+	} else if s, ok = signer.signers[strings.ToLower(u.Hostname())]; ok {
+		return signSkipNil(ctx, s, u, options)
+	}
+	return signSkipNil(ctx, signer.defaultSigner, u, options)
+}
+
+func signSkipNil(ctx context.Context, signer Signer, u *url.URL, options *SignOptions) error {
+	if signer == nil {
+		return nil
+	}
+	return signer.Sign(ctx, u, options)
+}
+
+// 计算签名过期时间，如果设置了签名时间窗口，则向上对齐到窗口的整数倍
+func signDeadline(options *SignOptions) int64 {
+	deadline := time.Now().Add(signTTL(options)).Unix()
+	if bucket := int64(options.TimeBucket / time.Second); bucket > 1 {
+		if remainder := deadline % bucket; remainder != 0 {
+			deadline += bucket - remainder
+		}
+	}
+	return deadline
+}
+
 func signURL(url string, cred *credentials.Credentials, deadline int64) string {
 	var appendUrl string
 
@@ -60,3 +131,22 @@ func isURLSigned(url string) bool {
 	return (strings.Contains(url, "&e=") || strings.Contains(url, "?e=")) &&
 		strings.Contains(url, "&token=")
 }
+
+// 计算签名结果可以复用的截止时间，在此之前重新签名将得到相同的 URL
+func signReusableUntil(options *SignOptions) time.Time {
+	minimum := time.Now().Add(1 * time.Second)
+	if options == nil || options.TimeBucket <= time.Second {
+		return minimum
+	}
+	if until := time.Unix(signDeadline(options), 0).Add(-signTTL(options)); until.After(minimum) {
+		return until
+	}
+	return minimum
+}
+
+func signTTL(options *SignOptions) time.Duration {
+	if options.TTL == 0 {
+		return 3 * time.Minute
+	}
+	return options.TTL
+}
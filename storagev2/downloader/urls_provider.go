@@ -157,7 +157,7 @@ func (s *signedURLsIter) Peek(u *url.URL) (bool, error) {
 			if err = s.signer.Sign(s.ctx, &signedURL, s.options); err != nil {
 				return nil, err
 			}
-			return signingCacheValue{&signedURL, time.Now().Add(1 * time.Second)}, nil
+			return signingCacheValue{&signedURL, signReusableUntil(s.options)}, nil
 		})
 		if status == cache.NoResultGot {
 			return false, err
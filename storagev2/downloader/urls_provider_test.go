@@ -4,15 +4,23 @@
 package downloader_test
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/qiniu/go-sdk/v7/storagev2/backoff"
 	"github.com/qiniu/go-sdk/v7/storagev2/credentials"
 	"github.com/qiniu/go-sdk/v7/storagev2/downloader"
 	"github.com/qiniu/go-sdk/v7/storagev2/http_client"
@@ -167,3 +175,184 @@ func TestDomainsQueryURLsProvider(t *testing.T) {
 		t.Fatalf("unexpected call count")
 	}
 }
+
+func TestHealthRankedURLsProvider(t *testing.T) {
+	data := []byte("hello world")
+	newServer := func(handler func(w http.ResponseWriter, r *http.Request) bool) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !handler(w, r) {
+				return
+			}
+			w.Header().Set("ETag", `"testetag1"`)
+			w.Header().Add("X-ReqId", "fakereqid")
+			http.ServeContent(w, r, "", time.Now(), bytes.NewReader(data))
+		}))
+	}
+	failingServer := newServer(func(w http.ResponseWriter, r *http.Request) bool {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return false
+	})
+	defer failingServer.Close()
+	slowServer := newServer(func(w http.ResponseWriter, r *http.Request) bool {
+		time.Sleep(100 * time.Millisecond)
+		return true
+	})
+	defer slowServer.Close()
+	fastServer := newServer(func(w http.ResponseWriter, r *http.Request) bool {
+		return true
+	})
+	defer fastServer.Close()
+
+	urlsProvider := downloader.NewHealthRankedURLsProvider(
+		downloader.NewStaticDomainBasedURLsProvider([]string{failingServer.URL, slowServer.URL, fastServer.URL}),
+		&downloader.HealthRankedURLsProviderOptions{FreezeDuration: time.Hour},
+	)
+	downloadManager := downloader.NewDownloadManager(&downloader.DownloadManagerOptions{
+		Options: http_client.Options{
+			Credentials: credentials.NewCredentials("testaccesskey", "testsecretkey"),
+		},
+		DestinationDownloader: downloader.NewConcurrentDownloader(&downloader.ConcurrentDownloaderOptions{
+			DownloaderOptions: downloader.DownloaderOptions{
+				RetryMax: 1,
+				Backoff:  backoff.NewFixedBackoff(0),
+			},
+		}),
+	})
+	for i := 0; i < 3; i++ {
+		var buf bytes.Buffer
+		if _, err := downloadManager.DownloadToWriter(context.Background(), "testfile", &buf, &downloader.ObjectOptions{
+			GenerateOptions:      downloader.GenerateOptions{UseInsecureProtocol: true},
+			DownloadURLsProvider: urlsProvider,
+		}); err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(buf.Bytes(), data) {
+			t.Fatalf("unexpected data")
+		}
+	}
+
+	urls, err := downloader.GetURLStrings(context.Background(), urlsProvider, "testfile", &downloader.GenerateOptions{UseInsecureProtocol: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != 3 ||
+		!strings.HasPrefix(urls[0], fastServer.URL) ||
+		!strings.HasPrefix(urls[1], slowServer.URL) ||
+		!strings.HasPrefix(urls[2], failingServer.URL) {
+		t.Fatalf("unexpected urls order: %v", urls)
+	}
+	failingURL, err := url.Parse(failingServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if health, ok := urlsProvider.DomainHealth(failingURL.Host); !ok || !health.Frozen || health.ErrorRate != 1 {
+		t.Fatalf("failing domain should be frozen: %#v", health)
+	}
+	fastURL, err := url.Parse(fastServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if health, ok := urlsProvider.DomainHealth(fastURL.Host); !ok || health.Frozen || health.ErrorRate != 0 || health.Requests == 0 {
+		t.Fatalf("unexpected health of fast domain: %#v", health)
+	}
+}
+
+func TestHealthRankedURLsProviderIgnoresClientErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("X-ReqId", "fakereqid")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	urlsProvider := downloader.NewHealthRankedURLsProvider(
+		downloader.NewStaticDomainBasedURLsProvider([]string{server.URL}),
+		&downloader.HealthRankedURLsProviderOptions{FreezeDuration: time.Hour},
+	)
+	downloadManager := downloader.NewDownloadManager(&downloader.DownloadManagerOptions{
+		Options: http_client.Options{
+			Credentials: credentials.NewCredentials("testaccesskey", "testsecretkey"),
+		},
+		DestinationDownloader: downloader.NewConcurrentDownloader(&downloader.ConcurrentDownloaderOptions{
+			DownloaderOptions: downloader.DownloaderOptions{
+				RetryMax: 1,
+				Backoff:  backoff.NewFixedBackoff(0),
+			},
+		}),
+	})
+	for i := 0; i < 3; i++ {
+		var buf bytes.Buffer
+		if _, err := downloadManager.DownloadToWriter(context.Background(), "testfile", &buf, &downloader.ObjectOptions{
+			GenerateOptions:      downloader.GenerateOptions{UseInsecureProtocol: true},
+			DownloadURLsProvider: urlsProvider,
+		}); err == nil {
+			t.Fatalf("expected error")
+		}
+	}
+
+	// 404 说明对象不存在，与域名健康状况无关
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if health, ok := urlsProvider.DomainHealth(serverURL.Host); !ok || health.Frozen || health.ErrorRate != 0 || health.Requests == 0 {
+		t.Fatalf("domain should not be frozen by client errors: %#v", health)
+	}
+}
+
+func TestDomainBasedSigner(t *testing.T) {
+	signer := downloader.NewDomainBasedSigner(map[string]downloader.Signer{
+		"CDN.example.com":      downloader.NewCDNTimestampAntiLeechSigner("encrypt-key"),
+		"src.example.com:8080": downloader.NewCredentialsSigner(credentials.NewCredentials("ak", "sk")),
+	}, nil)
+	options := downloader.SignOptions{TTL: time.Minute, TimeBucket: time.Hour}
+
+	sign := func(rawURL string) *url.URL {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = signer.Sign(context.Background(), u, &options); err != nil {
+			t.Fatal(err)
+		}
+		return u
+	}
+	checkDeadline := func(deadline int64) {
+		if deadline%3600 != 0 {
+			t.Fatalf("deadline should be aligned to time bucket: %d", deadline)
+		} else if minimum := time.Now().Add(time.Minute).Unix(); deadline < minimum || deadline > minimum+3600 {
+			t.Fatalf("unexpected deadline: %d", deadline)
+		}
+	}
+
+	cdnURL := sign("https://cdn.example.com/dir/%E4%B8%83%E7%89%9B.jpg?imageView2/2/w/200")
+	if !strings.HasPrefix(cdnURL.RawQuery, "imageView2/2/w/200&sign=") {
+		t.Fatalf("unexpected cdn url: %s", cdnURL)
+	}
+	query := cdnURL.Query()
+	deadline, err := strconv.ParseInt(query.Get("t"), 16, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDeadline(deadline)
+	if expected := fmt.Sprintf("%x", md5.Sum([]byte("encrypt-key/dir/%E4%B8%83%E7%89%9B.jpg"+query.Get("t")))); query.Get("sign") != expected {
+		t.Fatalf("unexpected sign: %s, expected: %s", query.Get("sign"), expected)
+	}
+	if again := sign("https://cdn.example.com/dir/%E4%B8%83%E7%89%9B.jpg?imageView2/2/w/200"); again.String() != cdnURL.String() {
+		t.Fatalf("signed url should be stable within time bucket: %s != %s", again, cdnURL)
+	}
+	if resigned := sign(cdnURL.String()); resigned.String() != cdnURL.String() {
+		t.Fatalf("signed url should not be signed again: %s", resigned)
+	}
+
+	srcURL := sign("http://src.example.com:8080/file")
+	if deadline, err = strconv.ParseInt(srcURL.Query().Get("e"), 10, 64); err != nil {
+		t.Fatal(err)
+	}
+	checkDeadline(deadline)
+	if !strings.HasPrefix(srcURL.Query().Get("token"), "ak:") {
+		t.Fatalf("unexpected src url: %s", srcURL)
+	}
+
+	if otherURL := sign("http://src.example.com/file"); otherURL.RawQuery != "" {
+		t.Fatalf("url of unknown domain should not be signed: %s", otherURL)
+	}
+}
@@ -0,0 +1,27 @@
+// Package encryption 提供七牛云对象存储的客户端加密。
+//
+// 数据在离开本机之前使用 AES-256-GCM 分帧加密，每帧明文固定为 [FrameSize] 字节（最后一帧可能更短），
+// 每帧密文比明文多 [TagSize] 字节的认证标签。每个对象使用独立生成的数据密钥加密，
+// 数据密钥本身由 [KeyProvider] 加密（即信封加密）后，与算法名称一起保存在对象的自定义元数据中。
+//
+// 由于每帧可以独立解密，因此加密后的对象依然支持分片下载与范围下载。
+//
+// # 配置加密上传与解密下载
+//
+//	keyProvider, err := encryption.NewStaticKeyProvider("my-key-id", masterKey) // masterKey 为 32 字节
+//
+//	uploadManager := uploader.NewUploadManager(&uploader.UploadManagerOptions{
+//	    Options:               http_client.Options{Credentials: cred},
+//	    EncryptionKeyProvider: keyProvider,
+//	})
+//
+//	downloadManager := downloader.NewDownloadManager(&downloader.DownloadManagerOptions{
+//	    Options:               http_client.Options{Credentials: cred},
+//	    EncryptionKeyProvider: keyProvider,
+//	})
+//
+// # 自定义密钥管理
+//
+// 实现 [KeyProvider] 接口即可对接外部密钥管理服务（KMS），SDK 只会保存被加密后的数据密钥，
+// 主密钥不会离开密钥管理服务。
+package encryption
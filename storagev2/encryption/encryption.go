@@ -0,0 +1,202 @@
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// 加密算法名称，AES-256-GCM 分帧加密，每帧明文 64 KB
+	AlgorithmAES256GCM64K = "AES-256-GCM-64K"
+
+	// 保存加密算法名称的元数据键
+	MetadataKeyAlgorithm = "x-qn-meta-encryption-algorithm"
+
+	// 保存被加密后的数据密钥的元数据键，值为 URL 安全的 Base64 编码
+	MetadataKeyWrappedKey = "x-qn-meta-encryption-wrapped-key"
+
+	// 保存主密钥 ID 的元数据键
+	MetadataKeyKeyID = "x-qn-meta-encryption-key-id"
+
+	// 数据密钥长度
+	DataKeySize = 32
+)
+
+type (
+	// 数据密钥
+	DataKey struct {
+		// 主密钥 ID
+		KeyID string
+
+		// 明文数据密钥，用于加密对象数据，长度必须为 DataKeySize
+		Plaintext []byte
+
+		// 被主密钥加密后的数据密钥，将被保存在对象元数据中
+		Wrapped []byte
+	}
+
+	// 密钥提供者
+	KeyProvider interface {
+		// 生成新的数据密钥
+		GenerateDataKey(context.Context) (*DataKey, error)
+
+		// 解密被加密的数据密钥，返回明文数据密钥
+		DecryptDataKey(ctx context.Context, keyID string, wrappedKey []byte) ([]byte, error)
+	}
+
+	// 加密信封，描述对象的加密方式
+	Envelope struct {
+		// 加密算法名称
+		Algorithm string
+
+		// 主密钥 ID
+		KeyID string
+
+		// 被主密钥加密后的数据密钥
+		WrappedKey []byte
+	}
+
+	staticKeyProvider struct {
+		keyID string
+		aead  cipher.AEAD
+	}
+)
+
+var (
+	// 不支持的加密算法
+	ErrUnsupportedAlgorithm = errors.New("unsupported encryption algorithm")
+
+	// 对象未加密
+	ErrNotEncrypted = errors.New("object is not encrypted")
+)
+
+// 创建静态密钥提供者
+//
+// 使用 32 字节的主密钥通过 AES-256-GCM 加密数据密钥，适用于主密钥由应用自行保管的场景。
+func NewStaticKeyProvider(keyID string, masterKey []byte) (KeyProvider, error) {
+	if len(masterKey) != 32 {
+		return nil, fmt.Errorf("invalid master key size: %d", len(masterKey))
+	}
+	block, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &staticKeyProvider{keyID: keyID, aead: aead}, nil
+}
+
+func (provider *staticKeyProvider) GenerateDataKey(context.Context) (*DataKey, error) {
+	plaintext := make([]byte, DataKeySize)
+	if _, err := io.ReadFull(rand.Reader, plaintext); err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, provider.aead.NonceSize(), provider.aead.NonceSize()+DataKeySize+provider.aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	wrapped := provider.aead.Seal(nonce, nonce, plaintext, []byte(provider.keyID))
+	return &DataKey{KeyID: provider.keyID, Plaintext: plaintext, Wrapped: wrapped}, nil
+}
+
+func (provider *staticKeyProvider) DecryptDataKey(_ context.Context, keyID string, wrappedKey []byte) ([]byte, error) {
+	if keyID != provider.keyID {
+		return nil, fmt.Errorf("unknown key id: %s", keyID)
+	}
+	nonceSize := provider.aead.NonceSize()
+	if len(wrappedKey) < nonceSize+provider.aead.Overhead() {
+		return nil, errors.New("invalid wrapped key")
+	}
+	return provider.aead.Open(nil, wrappedKey[:nonceSize], wrappedKey[nonceSize:], []byte(keyID))
+}
+
+// 将加密信封写入元数据
+//
+// 返回一个新的元数据，原有的元数据不会被修改
+func (envelope *Envelope) ToMetadata(metadata map[string]string) map[string]string {
+	newMetadata := make(map[string]string, len(metadata)+3)
+	for k, v := range metadata {
+		newMetadata[k] = v
+	}
+	newMetadata[MetadataKeyAlgorithm] = envelope.Algorithm
+	newMetadata[MetadataKeyWrappedKey] = base64.URLEncoding.EncodeToString(envelope.WrappedKey)
+	if envelope.KeyID != "" {
+		newMetadata[MetadataKeyKeyID] = envelope.KeyID
+	}
+	return newMetadata
+}
+
+// 从对象元数据中解析加密信封
+//
+// 如果对象未加密，返回 ErrNotEncrypted
+func EnvelopeFromMetadata(metadata map[string]string) (*Envelope, error) {
+	return parseEnvelope(func(key string) string {
+		if value, ok := metadata[key]; ok {
+			return value
+		}
+		for k, v := range metadata {
+			if strings.EqualFold(k, key) {
+				return v
+			}
+		}
+		return ""
+	})
+}
+
+// 从对象下载响应的 HTTP Header 中解析加密信封
+//
+// 如果对象未加密，返回 ErrNotEncrypted
+func EnvelopeFromHeader(header http.Header) (*Envelope, error) {
+	return parseEnvelope(header.Get)
+}
+
+func parseEnvelope(get func(string) string) (*Envelope, error) {
+	algorithm := get(MetadataKeyAlgorithm)
+	if algorithm == "" {
+		return nil, ErrNotEncrypted
+	} else if algorithm != AlgorithmAES256GCM64K {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	wrappedKey, err := base64.URLEncoding.DecodeString(get(MetadataKeyWrappedKey))
+	if err != nil {
+		return nil, err
+	} else if len(wrappedKey) == 0 {
+		return nil, errors.New("missing wrapped key")
+	}
+	return &Envelope{Algorithm: algorithm, KeyID: get(MetadataKeyKeyID), WrappedKey: wrappedKey}, nil
+}
+
+// 生成数据密钥，并创建对应的加密信封和分帧加密器
+func NewEnvelope(ctx context.Context, keyProvider KeyProvider) (*Envelope, *Cipher, error) {
+	dataKey, err := keyProvider.GenerateDataKey(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := NewCipher(dataKey.Plaintext)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Envelope{Algorithm: AlgorithmAES256GCM64K, KeyID: dataKey.KeyID, WrappedKey: dataKey.Wrapped}, c, nil
+}
+
+// 解密加密信封中的数据密钥，并创建对应的分帧解密器
+func (envelope *Envelope) OpenCipher(ctx context.Context, keyProvider KeyProvider) (*Cipher, error) {
+	if envelope.Algorithm != AlgorithmAES256GCM64K {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	dataKey, err := keyProvider.DecryptDataKey(ctx, envelope.KeyID, envelope.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	return NewCipher(dataKey)
+}
@@ -0,0 +1,121 @@
+//go:build unit
+// +build unit
+
+package encryption_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"testing"
+	"time"
+
+	internal_io "github.com/qiniu/go-sdk/v7/internal/io"
+	"github.com/qiniu/go-sdk/v7/storagev2/encryption"
+)
+
+func TestEncryptAndDecrypt(t *testing.T) {
+	keyProvider, err := encryption.NewStaticKeyProvider("testkeyid", bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope, c, err := encryption.NewEnvelope(context.Background(), keyProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for _, size := range []int{0, 1, encryption.FrameSize - 1, encryption.FrameSize, encryption.FrameSize + 1, 3*encryption.FrameSize + 17} {
+		plaintext := make([]byte, size)
+		r.Read(plaintext)
+
+		ciphertext, err := internal_io.ReadAll(encryption.NewEncryptingReader(bytes.NewReader(plaintext), c))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uint64(len(ciphertext)) != encryption.CiphertextSize(uint64(size)) {
+			t.Fatalf("unexpected ciphertext size: %d", len(ciphertext))
+		}
+		if plaintextSize, err := encryption.PlaintextSize(uint64(len(ciphertext))); err != nil {
+			t.Fatal(err)
+		} else if plaintextSize != uint64(size) {
+			t.Fatalf("unexpected plaintext size: %d", plaintextSize)
+		}
+
+		readerAt, cipherSize := encryption.NewEncryptingReaderAt(bytes.NewReader(plaintext), uint64(size), c)
+		if cipherSize != uint64(len(ciphertext)) {
+			t.Fatalf("unexpected ciphertext size: %d", cipherSize)
+		}
+		ciphertext2, err := internal_io.ReadAll(io.NewSectionReader(readerAt, 0, int64(cipherSize)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(ciphertext, ciphertext2) {
+			t.Fatalf("ciphertext from reader and reader at are inequal")
+		}
+
+		c2, err := envelope.OpenCipher(context.Background(), keyProvider)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decrypted, err := internal_io.ReadAll(encryption.NewDecryptingReader(bytes.NewReader(ciphertext), c2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(plaintext, decrypted) {
+			t.Fatalf("decrypted data is inequal")
+		}
+
+		if size > encryption.FrameSize {
+			if _, err = internal_io.ReadAll(encryption.NewDecryptingReader(bytes.NewReader(ciphertext[:encryption.CiphertextFrameSize]), c2)); err != encryption.ErrAuthenticationFailed {
+				t.Fatalf("expected truncation to be detected, actual: %v", err)
+			}
+		}
+		if size > 0 {
+			ciphertext[0] ^= 0xff
+			if _, err = internal_io.ReadAll(encryption.NewDecryptingReader(bytes.NewReader(ciphertext), c2)); err != encryption.ErrAuthenticationFailed {
+				t.Fatalf("expected modification to be detected, actual: %v", err)
+			}
+		}
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	keyProvider, err := encryption.NewStaticKeyProvider("testkeyid", bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope, _, err := encryption.NewEnvelope(context.Background(), keyProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata := envelope.ToMetadata(map[string]string{"a": "b"})
+	if len(metadata) != 4 || metadata["a"] != "b" || metadata[encryption.MetadataKeyKeyID] != "testkeyid" {
+		t.Fatalf("unexpected metadata: %v", metadata)
+	}
+
+	header := make(http.Header)
+	for k, v := range metadata {
+		header.Set(k, v)
+	}
+	parsed, err := encryption.EnvelopeFromHeader(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Algorithm != envelope.Algorithm || parsed.KeyID != envelope.KeyID || !bytes.Equal(parsed.WrappedKey, envelope.WrappedKey) {
+		t.Fatalf("unexpected envelope")
+	}
+	if _, err = encryption.EnvelopeFromMetadata(map[string]string{"a": "b"}); err != encryption.ErrNotEncrypted {
+		t.Fatalf("expected not encrypted error, actual: %v", err)
+	}
+
+	otherKeyProvider, err := encryption.NewStaticKeyProvider("testkeyid", bytes.Repeat([]byte{2}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = parsed.OpenCipher(context.Background(), otherKeyProvider); err == nil {
+		t.Fatalf("expected wrong master key to be rejected")
+	}
+}
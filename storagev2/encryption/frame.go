@@ -0,0 +1,252 @@
+package encryption
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// 每帧明文大小
+	FrameSize = 64 * 1024
+
+	// 每帧认证标签大小
+	TagSize = 16
+
+	// 每帧密文大小（最后一帧可能更短）
+	CiphertextFrameSize = FrameSize + TagSize
+)
+
+type (
+	// 分帧加密器
+	//
+	// 每帧使用帧序号作为 Nonce，并将是否为最后一帧作为附加认证数据，因此帧的重排与截断都能被检测出来。
+	// 由于每个对象都使用独立的数据密钥，Nonce 不会被重复使用。
+	Cipher struct {
+		aead cipher.AEAD
+	}
+
+	encryptingReader struct {
+		r     *bufio.Reader
+		c     *Cipher
+		index uint64
+		plain []byte
+		frame []byte
+		pos   int
+		final bool
+	}
+
+	encryptingReaderAt struct {
+		r          io.ReaderAt
+		c          *Cipher
+		plainSize  uint64
+		cipherSize uint64
+		frames     uint64
+	}
+
+	decryptingReader struct {
+		r     *bufio.Reader
+		c     *Cipher
+		index uint64
+		frame []byte
+		plain []byte
+		pos   int
+		final bool
+	}
+)
+
+// 数据已经被篡改或截断
+var ErrAuthenticationFailed = errors.New("encrypted data authentication failed")
+
+// 创建分帧加密器，数据密钥长度必须为 DataKeySize
+func NewCipher(dataKey []byte) (*Cipher, error) {
+	if len(dataKey) != DataKeySize {
+		return nil, fmt.Errorf("invalid data key size: %d", len(dataKey))
+	}
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// 加密一帧数据，并将密文追加到 dst 后返回
+func (c *Cipher) SealFrame(dst, plaintext []byte, index uint64, final bool) []byte {
+	var nonce [12]byte
+	binary.BigEndian.PutUint64(nonce[4:], index)
+	return c.aead.Seal(dst, nonce[:], plaintext, frameAdditionalData(final))
+}
+
+// 解密一帧数据，并将明文追加到 dst 后返回
+func (c *Cipher) OpenFrame(dst, ciphertext []byte, index uint64, final bool) ([]byte, error) {
+	var nonce [12]byte
+	binary.BigEndian.PutUint64(nonce[4:], index)
+	plaintext, err := c.aead.Open(dst, nonce[:], ciphertext, frameAdditionalData(final))
+	if err != nil {
+		return nil, ErrAuthenticationFailed
+	}
+	return plaintext, nil
+}
+
+func frameAdditionalData(final bool) []byte {
+	if final {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+// 获取明文对应的帧数，空数据也会被加密为一帧
+func FrameCount(plaintextSize uint64) uint64 {
+	if plaintextSize == 0 {
+		return 1
+	}
+	return (plaintextSize + FrameSize - 1) / FrameSize
+}
+
+// 获取明文加密后的密文大小
+func CiphertextSize(plaintextSize uint64) uint64 {
+	return plaintextSize + FrameCount(plaintextSize)*TagSize
+}
+
+// 根据密文大小计算明文大小
+func PlaintextSize(ciphertextSize uint64) (uint64, error) {
+	frames := (ciphertextSize + CiphertextFrameSize - 1) / CiphertextFrameSize
+	if frames == 0 || ciphertextSize-(frames-1)*CiphertextFrameSize < TagSize {
+		return 0, fmt.Errorf("invalid ciphertext size: %d", ciphertextSize)
+	}
+	return ciphertextSize - frames*TagSize, nil
+}
+
+// 创建加密 io.Reader，读取到的数据为明文加密后的密文
+func NewEncryptingReader(r io.Reader, c *Cipher) io.Reader {
+	return &encryptingReader{
+		r:     bufio.NewReaderSize(r, FrameSize),
+		c:     c,
+		plain: make([]byte, FrameSize),
+		frame: make([]byte, 0, CiphertextFrameSize),
+	}
+}
+
+func (er *encryptingReader) Read(p []byte) (int, error) {
+	for er.pos >= len(er.frame) {
+		if er.final {
+			return 0, io.EOF
+		}
+		n, err := io.ReadFull(er.r, er.plain)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			er.final = true
+		} else if err != nil {
+			return 0, err
+		} else if _, err = er.r.Peek(1); err == io.EOF {
+			er.final = true
+		} else if err != nil {
+			return 0, err
+		}
+		er.frame = er.c.SealFrame(er.frame[:0], er.plain[:n], er.index, er.final)
+		er.pos = 0
+		er.index += 1
+	}
+	n := copy(p, er.frame[er.pos:])
+	er.pos += n
+	return n, nil
+}
+
+// 创建加密 io.ReaderAt，可以随机读取明文加密后的密文，返回值中包含密文大小
+//
+// 每次读取都会重新加密所涉及的完整帧，适合以分片为单位的大块读取
+func NewEncryptingReaderAt(r io.ReaderAt, plaintextSize uint64, c *Cipher) (io.ReaderAt, uint64) {
+	cipherSize := CiphertextSize(plaintextSize)
+	return &encryptingReaderAt{
+		r:          r,
+		c:          c,
+		plainSize:  plaintextSize,
+		cipherSize: cipherSize,
+		frames:     FrameCount(plaintextSize),
+	}, cipherSize
+}
+
+func (er *encryptingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	var (
+		n      int
+		cur    = uint64(off)
+		plain  = make([]byte, FrameSize)
+		sealed = make([]byte, 0, CiphertextFrameSize)
+	)
+	for n < len(p) && cur < er.cipherSize {
+		index := cur / CiphertextFrameSize
+		frameOffset := index * FrameSize
+		frameSize := er.plainSize - frameOffset
+		if frameSize > FrameSize {
+			frameSize = FrameSize
+		}
+		if frameSize > 0 {
+			haveRead, err := er.r.ReadAt(plain[:frameSize], int64(frameOffset))
+			if uint64(haveRead) < frameSize {
+				if err == nil || err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return n, err
+			}
+		}
+		sealed = er.c.SealFrame(sealed[:0], plain[:frameSize], index, index+1 == er.frames)
+		copied := copy(p[n:], sealed[cur-index*CiphertextFrameSize:])
+		n += copied
+		cur += uint64(copied)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// 创建解密 io.Reader，读取到的数据为密文解密后的明文
+//
+// 如果密文被篡改或截断，读取时将返回 ErrAuthenticationFailed 或 io.ErrUnexpectedEOF
+func NewDecryptingReader(r io.Reader, c *Cipher) io.Reader {
+	return &decryptingReader{
+		r:     bufio.NewReaderSize(r, CiphertextFrameSize),
+		c:     c,
+		frame: make([]byte, CiphertextFrameSize),
+		plain: make([]byte, 0, FrameSize),
+	}
+}
+
+func (dr *decryptingReader) Read(p []byte) (int, error) {
+	for dr.pos >= len(dr.plain) {
+		if dr.final {
+			return 0, io.EOF
+		}
+		n, err := io.ReadFull(dr.r, dr.frame)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			if n < TagSize {
+				return 0, io.ErrUnexpectedEOF
+			}
+			dr.final = true
+		} else if err != nil {
+			return 0, err
+		} else if _, err = dr.r.Peek(1); err == io.EOF {
+			dr.final = true
+		} else if err != nil {
+			return 0, err
+		}
+		if dr.plain, err = dr.c.OpenFrame(dr.plain[:0], dr.frame[:n], dr.index, dr.final); err != nil {
+			return 0, err
+		}
+		dr.pos = 0
+		dr.index += 1
+	}
+	n := copy(p, dr.plain[dr.pos:])
+	dr.pos += n
+	return n, nil
+}
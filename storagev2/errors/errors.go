@@ -11,3 +11,51 @@ type (
 func (err MissingRequiredFieldError) Error() string {
 	return fmt.Sprintf("missing required field `%s`", err.Name)
 }
+
+type (
+	// 校验和不匹配
+	ChecksumMismatchError struct {
+		Algorithm  string // 校验算法，如 Etag、CRC64、MD5、Size
+		PartNumber uint64 // 分片编号，为 0 表示整个对象
+		Expected   string // 本地计算的校验和
+		Actual     string // 服务器返回的校验和
+	}
+)
+
+func (err ChecksumMismatchError) Error() string {
+	if err.PartNumber > 0 {
+		return fmt.Sprintf("%s checksum mismatch of part %d: expected `%s`, actual `%s`", err.Algorithm, err.PartNumber, err.Expected, err.Actual)
+	}
+	return fmt.Sprintf("%s checksum mismatch: expected `%s`, actual `%s`", err.Algorithm, err.Expected, err.Actual)
+}
+
+type (
+	// 前置条件不满足
+	PreconditionFailedError struct {
+		Condition string // 不满足的前置条件，如 IfNotExists、IfMatchETag
+		Err       error  // 服务器返回的原始错误，可能为空
+	}
+)
+
+func (err PreconditionFailedError) Error() string {
+	if err.Err != nil {
+		return fmt.Sprintf("precondition `%s` failed: %s", err.Condition, err.Err)
+	}
+	return fmt.Sprintf("precondition `%s` failed", err.Condition)
+}
+
+func (err PreconditionFailedError) Unwrap() error {
+	return err.Err
+}
+
+type (
+	// 参数无效
+	InvalidArgumentError struct {
+		Name   string // 参数名称
+		Reason string // 无效原因
+	}
+)
+
+func (err InvalidArgumentError) Error() string {
+	return fmt.Sprintf("invalid argument `%s`: %s", err.Name, err.Reason)
+}
@@ -0,0 +1,90 @@
+package fop
+
+import (
+	"strconv"
+	"strings"
+)
+
+type (
+	// 音视频元信息（avinfo）
+	AvInfo struct{}
+
+	// 视频帧缩略图（vframe）
+	Vframe struct {
+		format        string
+		offset        float64
+		width, height int
+		rotate        string
+	}
+)
+
+// 创建音视频元信息指令
+func NewAvInfo() AvInfo {
+	return AvInfo{}
+}
+
+// 生成音视频元信息指令字符串
+func (AvInfo) Command() (string, error) {
+	return "avinfo", nil
+}
+
+// 创建视频帧缩略图指令，format 为 jpg 或 png，offset 为截取的时间点，单位为秒
+func NewVframe(format string, offset float64) Vframe {
+	return Vframe{format: format, offset: offset}
+}
+
+// 设置缩略图宽度，取值范围为 1 到 3840
+func (vframe Vframe) Width(width int) Vframe {
+	vframe.width = width
+	return vframe
+}
+
+// 设置缩略图高度，取值范围为 1 到 3840
+func (vframe Vframe) Height(height int) Vframe {
+	vframe.height = height
+	return vframe
+}
+
+// 顺时针旋转，取值为 90、180 或 270
+func (vframe Vframe) Rotate(degrees int) Vframe {
+	vframe.rotate = strconv.Itoa(degrees)
+	return vframe
+}
+
+// 根据视频元信息自动旋转
+func (vframe Vframe) AutoRotate() Vframe {
+	vframe.rotate = "auto"
+	return vframe
+}
+
+// 生成视频帧缩略图指令字符串
+func (vframe Vframe) Command() (string, error) {
+	if vframe.format != "jpg" && vframe.format != "png" {
+		return "", invalidArgument("vframe.format", "must be jpg or png")
+	} else if vframe.offset < 0 {
+		return "", invalidArgument("vframe.offset", "must not be negative")
+	}
+
+	var builder strings.Builder
+	builder.WriteString("vframe/" + vframe.format + "/offset/" + strconv.FormatFloat(vframe.offset, 'f', -1, 64))
+	if vframe.width != 0 {
+		if vframe.width < 1 || vframe.width > 3840 {
+			return "", invalidArgument("vframe.w", "must be between 1 and 3840")
+		}
+		builder.WriteString("/w/" + strconv.Itoa(vframe.width))
+	}
+	if vframe.height != 0 {
+		if vframe.height < 1 || vframe.height > 3840 {
+			return "", invalidArgument("vframe.h", "must be between 1 and 3840")
+		}
+		builder.WriteString("/h/" + strconv.Itoa(vframe.height))
+	}
+	switch vframe.rotate {
+	case "":
+	case "90", "180", "270", "auto":
+		builder.WriteString("/rotate/" + vframe.rotate)
+	default:
+		return "", invalidArgument("vframe.rotate", "must be 90, 180, 270 or auto")
+	}
+	return builder.String(), nil
+}
@@ -0,0 +1,25 @@
+// Package fop 提供七牛云数据处理指令的类型化构建器。
+//
+// 构建器生成的指令字符串可以用于下载时的 downloader.GenerateOptions.Command，
+// 也可以用于上传策略的 persistentOps。所有参数在生成指令时校验，参数无效时返回 errors.InvalidArgumentError。
+// 构建器都是值类型，每次调用返回新的副本，因此可以安全地复用。
+//
+// # 下载时处理
+//
+//	command, err := fop.NewImageView2(2).Width(200).Format("webp").Command()
+//	urls, err := downloader.GetURLStrings(ctx, urlsProvider, "photo.jpg", &downloader.GenerateOptions{Command: command})
+//
+// # 管道
+//
+// 多个指令通过 [Pipeline] 依次执行，并可以通过 SaveAs 将结果另存为指定对象：
+//
+//	pipeline := fop.NewPipeline(
+//	    fop.NewImageMogr2().AutoOrient().Thumbnail("!50p").Strip(),
+//	    fop.NewTextWatermark("qiniu").Gravity(fop.GravitySouthEast).Offset(10, 10),
+//	).SaveAs("my-bucket", "photo-thumbnail.jpg")
+//
+// # 持久化数据处理
+//
+//	persistentOps, err := fop.PersistentOps(pipeline, fop.NewPipeline(fop.NewVframe("jpg", 1.5)).SaveAs("my-bucket", "cover.jpg"))
+//	putPolicy = putPolicy.SetPersistentOps(persistentOps)
+package fop
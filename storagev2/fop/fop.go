@@ -0,0 +1,126 @@
+package fop
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/qiniu/go-sdk/v7/storagev2/errors"
+)
+
+type (
+	// 数据处理指令
+	Command interface {
+		// 生成指令字符串，参数无效时返回错误
+		Command() (string, error)
+	}
+
+	// 数据处理管道
+	//
+	// 管道中的指令依次执行，前一个指令的输出作为后一个指令的输入，生成的指令之间使用 | 连接。
+	Pipeline struct {
+		commands []Command
+		saveAs   *saveAs
+	}
+
+	// 未经校验的原始指令
+	RawCommand string
+
+	saveAs struct {
+		bucketName, objectName string
+	}
+)
+
+// 创建数据处理管道
+func NewPipeline(commands ...Command) Pipeline {
+	return Pipeline{commands: append([]Command(nil), commands...)}
+}
+
+// 在管道末尾追加指令
+func (pipeline Pipeline) Then(command Command) Pipeline {
+	pipeline.commands = append(append(make([]Command, 0, len(pipeline.commands)+1), pipeline.commands...), command)
+	return pipeline
+}
+
+// 将处理结果另存为指定对象，objectName 为空时由服务器决定对象名称
+func (pipeline Pipeline) SaveAs(bucketName, objectName string) Pipeline {
+	pipeline.saveAs = &saveAs{bucketName: bucketName, objectName: objectName}
+	return pipeline
+}
+
+// 生成管道指令字符串
+func (pipeline Pipeline) Command() (string, error) {
+	if len(pipeline.commands) == 0 {
+		return "", errors.MissingRequiredFieldError{Name: "Commands"}
+	}
+	commands := make([]string, 0, len(pipeline.commands)+1)
+	for _, command := range pipeline.commands {
+		if command == nil {
+			return "", errors.MissingRequiredFieldError{Name: "Command"}
+		}
+		c, err := command.Command()
+		if err != nil {
+			return "", err
+		}
+		commands = append(commands, c)
+	}
+	if pipeline.saveAs != nil {
+		if pipeline.saveAs.bucketName == "" {
+			return "", errors.MissingRequiredFieldError{Name: "saveas.BucketName"}
+		}
+		commands = append(commands, "saveas/"+EncodedEntryURI(pipeline.saveAs.bucketName, pipeline.saveAs.objectName))
+	}
+	return strings.Join(commands, "|"), nil
+}
+
+// 原始指令，不做任何校验，用于尚未支持的指令
+func (command RawCommand) Command() (string, error) {
+	if command == "" {
+		return "", errors.MissingRequiredFieldError{Name: "Command"}
+	}
+	return string(command), nil
+}
+
+// 生成持久化数据处理指令列表，可用于上传策略的 persistentOps 字段，多个指令之间使用 ; 连接
+func PersistentOps(commands ...Command) (string, error) {
+	if len(commands) == 0 {
+		return "", errors.MissingRequiredFieldError{Name: "Commands"}
+	}
+	ops := make([]string, 0, len(commands))
+	for _, command := range commands {
+		if command == nil {
+			return "", errors.MissingRequiredFieldError{Name: "Command"}
+		}
+		op, err := command.Command()
+		if err != nil {
+			return "", err
+		}
+		ops = append(ops, op)
+	}
+	return strings.Join(ops, ";"), nil
+}
+
+// 生成 EncodedEntryURI，objectName 为空时仅编码空间名称
+func EncodedEntryURI(bucketName, objectName string) string {
+	if objectName == "" {
+		return urlSafeBase64(bucketName)
+	}
+	return urlSafeBase64(bucketName + ":" + objectName)
+}
+
+func urlSafeBase64(s string) string {
+	return base64.URLEncoding.EncodeToString([]byte(s))
+}
+
+func invalidArgument(name, reason string) error {
+	return errors.InvalidArgumentError{Name: name, Reason: reason}
+}
+
+// 参数将作为指令的一段路径，不能包含路径分隔符和管道分隔符
+func checkSegment(name, value string) error {
+	if value == "" {
+		return errors.MissingRequiredFieldError{Name: name}
+	} else if strings.ContainsAny(value, "/|;") {
+		return invalidArgument(name, "must not contain `/`, `|` or `;`")
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+//go:build unit
+// +build unit
+
+package fop_test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/qiniu/go-sdk/v7/storagev2/errors"
+	"github.com/qiniu/go-sdk/v7/storagev2/fop"
+)
+
+func TestCommands(t *testing.T) {
+	encode := func(s string) string {
+		return base64.URLEncoding.EncodeToString([]byte(s))
+	}
+	testCases := []struct {
+		command  fop.Command
+		expected string
+	}{
+		{fop.NewImageView2(2).Width(200), "imageView2/2/w/200"},
+		{fop.NewImageView2(1).Width(200).Height(100).Format("webp").Interlace(true).Quality(75).IgnoreError(),
+			"imageView2/1/w/200/h/100/format/webp/interlace/1/q/75/ignore-error/1"},
+		{fop.NewImageMogr2().AutoOrient().Thumbnail("!50p").Strip().Gravity(fop.GravityCenter).Crop("200x200").Rotate(90).Blur(3, 5).Format("png").Quality(80),
+			"imageMogr2/auto-orient/thumbnail/!50p/strip/gravity/Center/crop/200x200/rotate/90/blur/3x5/format/png/quality/80"},
+		{fop.NewImageWatermark("https://example.com/logo.png").Dissolve(50).Gravity(fop.GravitySouthEast).Offset(10, 20).Scale(0.2),
+			"watermark/1/image/" + encode("https://example.com/logo.png") + "/dissolve/50/gravity/SouthEast/dx/10/dy/20/ws/0.2"},
+		{fop.NewTextWatermark("七牛云").Font("宋体").FontSize(500).Fill("#FF0000"),
+			"watermark/2/text/" + encode("七牛云") + "/font/" + encode("宋体") + "/fontsize/500/fill/" + encode("#FF0000")},
+		{fop.NewAvInfo(), "avinfo"},
+		{fop.NewVframe("jpg", 1.5).Width(480).Height(360).AutoRotate(), "vframe/jpg/offset/1.5/w/480/h/360/rotate/auto"},
+		{fop.NewPipeline(fop.NewImageView2(2).Width(200), fop.RawCommand("imageslim")).SaveAs("bucket", "thumb.jpg"),
+			"imageView2/2/w/200|imageslim|saveas/" + encode("bucket:thumb.jpg")},
+		{fop.NewPipeline(fop.NewAvInfo()).SaveAs("bucket", ""), "avinfo|saveas/" + encode("bucket")},
+	}
+	for _, testCase := range testCases {
+		if command, err := testCase.command.Command(); err != nil {
+			t.Fatal(err)
+		} else if command != testCase.expected {
+			t.Fatalf("unexpected command: %s, expected: %s", command, testCase.expected)
+		}
+	}
+
+	persistentOps, err := fop.PersistentOps(
+		fop.NewPipeline(fop.NewVframe("png", 0)).SaveAs("bucket", "cover.png"),
+		fop.NewAvInfo(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	} else if expected := "vframe/png/offset/0|saveas/" + encode("bucket:cover.png") + ";avinfo"; persistentOps != expected {
+		t.Fatalf("unexpected persistent ops: %s", persistentOps)
+	}
+
+	// 构建器是值类型，修改副本不影响原值
+	base := fop.NewImageMogr2().AutoOrient()
+	_ = base.Strip()
+	if command, err := base.Command(); err != nil || command != "imageMogr2/auto-orient" {
+		t.Fatalf("builder should not be modified: %s", command)
+	}
+}
+
+func TestInvalidCommands(t *testing.T) {
+	invalidCommands := []fop.Command{
+		fop.NewImageView2(6).Width(100),
+		fop.NewImageView2(2),
+		fop.NewImageView2(2).Width(10000),
+		fop.NewImageView2(2).Width(100).Format("exe"),
+		fop.NewImageView2(2).Width(100).Quality(101),
+		fop.NewImageMogr2().Thumbnail("200x/strip"),
+		fop.NewImageMogr2().Rotate(0).Strip(),
+		fop.NewImageMogr2().Gravity("Middle"),
+		fop.NewImageMogr2().Blur(0, 1),
+		fop.NewImageWatermark("ftp://example.com/logo.png"),
+		fop.NewImageWatermark("https://example.com/logo.png").Font("宋体"),
+		fop.NewTextWatermark("text").Scale(0.5),
+		fop.NewTextWatermark("text").Fill("red;"),
+		fop.NewTextWatermark("text").Dissolve(101),
+		fop.NewVframe("gif", 1),
+		fop.NewVframe("jpg", -1),
+		fop.NewVframe("jpg", 1).Rotate(45),
+		fop.NewPipeline(fop.NewAvInfo(), fop.NewImageView2(7).Width(1)),
+	}
+	for i, command := range invalidCommands {
+		if _, err := command.Command(); err == nil {
+			t.Fatalf("command %d should be invalid", i)
+		} else if _, ok := err.(errors.InvalidArgumentError); !ok {
+			t.Fatalf("command %d: unexpected error type: %v", i, err)
+		}
+	}
+
+	missingCommands := []fop.Command{
+		fop.NewImageMogr2(),
+		fop.NewPipeline(),
+		fop.NewPipeline(fop.NewAvInfo()).SaveAs("", "key"),
+		fop.NewTextWatermark(""),
+		fop.RawCommand(""),
+	}
+	for i, command := range missingCommands {
+		if _, err := command.Command(); err == nil {
+			t.Fatalf("command %d should be invalid", i)
+		} else if _, ok := err.(errors.MissingRequiredFieldError); !ok {
+			t.Fatalf("command %d: unexpected error type: %v", i, err)
+		}
+	}
+	if _, err := fop.PersistentOps(); err == nil {
+		t.Fatalf("empty persistent ops should be invalid")
+	}
+}
@@ -0,0 +1,392 @@
+package fop
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/qiniu/go-sdk/v7/storagev2/errors"
+)
+
+type (
+	// 图片处理中的方位
+	Gravity string
+
+	// 图片基本处理（imageView2）
+	ImageView2 struct {
+		mode          int
+		width, height int
+		quality       int
+		format        string
+		interlace     *bool
+		ignoreError   bool
+	}
+
+	// 图片高级处理（imageMogr2）
+	//
+	// 参数按照调用的顺序生成，服务器也将按照该顺序处理。
+	ImageMogr2 struct {
+		params []string
+		err    error
+	}
+
+	// 图片水印（watermark）
+	Watermark struct {
+		mode        int
+		image, text string
+		font, fill  string
+		fontSize    int
+		dissolve    int
+		gravity     Gravity
+		dx, dy      *int
+		scale       float64
+		hasScale    bool
+	}
+)
+
+const (
+	GravityNorthWest Gravity = "NorthWest"
+	GravityNorth     Gravity = "North"
+	GravityNorthEast Gravity = "NorthEast"
+	GravityWest      Gravity = "West"
+	GravityCenter    Gravity = "Center"
+	GravityEast      Gravity = "East"
+	GravitySouthWest Gravity = "SouthWest"
+	GravitySouth     Gravity = "South"
+	GravitySouthEast Gravity = "SouthEast"
+)
+
+const maxImageEdge = 9999
+
+var (
+	imageFormats = []string{"jpg", "gif", "png", "webp", "tiff", "bmp", "avif", "heic"}
+	colorPattern = regexp.MustCompile(`^(#[0-9A-Fa-f]{6}|#[0-9A-Fa-f]{8}|[A-Za-z]+)$`)
+)
+
+// 创建图片基本处理指令，mode 取值范围为 0 到 5
+func NewImageView2(mode int) ImageView2 {
+	return ImageView2{mode: mode}
+}
+
+// 设置宽度（或长边），单位为像素
+func (imageView2 ImageView2) Width(width int) ImageView2 {
+	imageView2.width = width
+	return imageView2
+}
+
+// 设置高度（或短边），单位为像素
+func (imageView2 ImageView2) Height(height int) ImageView2 {
+	imageView2.height = height
+	return imageView2
+}
+
+// 设置输出格式
+func (imageView2 ImageView2) Format(format string) ImageView2 {
+	imageView2.format = format
+	return imageView2
+}
+
+// 设置图片质量，取值范围为 1 到 100
+func (imageView2 ImageView2) Quality(quality int) ImageView2 {
+	imageView2.quality = quality
+	return imageView2
+}
+
+// 设置是否渐进显示，仅对 jpg 格式有效
+func (imageView2 ImageView2) Interlace(interlace bool) ImageView2 {
+	imageView2.interlace = &interlace
+	return imageView2
+}
+
+// 处理失败时返回原图
+func (imageView2 ImageView2) IgnoreError() ImageView2 {
+	imageView2.ignoreError = true
+	return imageView2
+}
+
+// 生成图片基本处理指令字符串
+func (imageView2 ImageView2) Command() (string, error) {
+	if imageView2.mode < 0 || imageView2.mode > 5 {
+		return "", invalidArgument("imageView2.mode", "must be between 0 and 5")
+	}
+	if imageView2.width == 0 && imageView2.height == 0 {
+		return "", invalidArgument("imageView2.w", "at least one of width and height must be set")
+	}
+	if err := checkEdge("imageView2.w", imageView2.width); err != nil {
+		return "", err
+	}
+	if err := checkEdge("imageView2.h", imageView2.height); err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+	builder.WriteString("imageView2/")
+	builder.WriteString(strconv.Itoa(imageView2.mode))
+	if imageView2.width > 0 {
+		builder.WriteString("/w/" + strconv.Itoa(imageView2.width))
+	}
+	if imageView2.height > 0 {
+		builder.WriteString("/h/" + strconv.Itoa(imageView2.height))
+	}
+	if imageView2.format != "" {
+		if err := checkImageFormat("imageView2.format", imageView2.format); err != nil {
+			return "", err
+		}
+		builder.WriteString("/format/" + imageView2.format)
+	}
+	if imageView2.interlace != nil {
+		builder.WriteString("/interlace/" + boolParam(*imageView2.interlace))
+	}
+	if imageView2.quality != 0 {
+		if err := checkQuality("imageView2.q", imageView2.quality); err != nil {
+			return "", err
+		}
+		builder.WriteString("/q/" + strconv.Itoa(imageView2.quality))
+	}
+	if imageView2.ignoreError {
+		builder.WriteString("/ignore-error/1")
+	}
+	return builder.String(), nil
+}
+
+// 创建图片高级处理指令
+func NewImageMogr2() ImageMogr2 {
+	return ImageMogr2{}
+}
+
+// 根据原图 EXIF 信息自动旋正
+func (imageMogr2 ImageMogr2) AutoOrient() ImageMogr2 {
+	return imageMogr2.add(nil, "auto-orient")
+}
+
+// 缩放，spec 为缩放参数，例如 `!50p`、`200x`、`200x300!`
+func (imageMogr2 ImageMogr2) Thumbnail(spec string) ImageMogr2 {
+	return imageMogr2.add(checkSegment("imageMogr2.thumbnail", spec), "thumbnail", spec)
+}
+
+// 去除图片中的元信息
+func (imageMogr2 ImageMogr2) Strip() ImageMogr2 {
+	return imageMogr2.add(nil, "strip")
+}
+
+// 设置裁剪锚点，影响其后的 Crop
+func (imageMogr2 ImageMogr2) Gravity(gravity Gravity) ImageMogr2 {
+	return imageMogr2.add(checkGravity("imageMogr2.gravity", gravity), "gravity", string(gravity))
+}
+
+// 裁剪，spec 为裁剪参数，例如 `200x300`、`!300x400a10a10`
+func (imageMogr2 ImageMogr2) Crop(spec string) ImageMogr2 {
+	return imageMogr2.add(checkSegment("imageMogr2.crop", spec), "crop", spec)
+}
+
+// 顺时针旋转，取值范围为 1 到 360
+func (imageMogr2 ImageMogr2) Rotate(degrees int) ImageMogr2 {
+	var err error
+	if degrees < 1 || degrees > 360 {
+		err = invalidArgument("imageMogr2.rotate", "must be between 1 and 360")
+	}
+	return imageMogr2.add(err, "rotate", strconv.Itoa(degrees))
+}
+
+// 设置输出格式
+func (imageMogr2 ImageMogr2) Format(format string) ImageMogr2 {
+	return imageMogr2.add(checkImageFormat("imageMogr2.format", format), "format", format)
+}
+
+// 高斯模糊，radius 取值范围为 1 到 50，sigma 必须大于 0
+func (imageMogr2 ImageMogr2) Blur(radius, sigma int) ImageMogr2 {
+	var err error
+	if radius < 1 || radius > 50 {
+		err = invalidArgument("imageMogr2.blur", "radius must be between 1 and 50")
+	} else if sigma <= 0 {
+		err = invalidArgument("imageMogr2.blur", "sigma must be positive")
+	}
+	return imageMogr2.add(err, "blur", strconv.Itoa(radius)+"x"+strconv.Itoa(sigma))
+}
+
+// 设置图片质量，取值范围为 1 到 100
+func (imageMogr2 ImageMogr2) Quality(quality int) ImageMogr2 {
+	return imageMogr2.add(checkQuality("imageMogr2.quality", quality), "quality", strconv.Itoa(quality))
+}
+
+// 设置是否渐进显示，仅对 jpg 格式有效
+func (imageMogr2 ImageMogr2) Interlace(interlace bool) ImageMogr2 {
+	return imageMogr2.add(nil, "interlace", boolParam(interlace))
+}
+
+// 处理失败时返回原图
+func (imageMogr2 ImageMogr2) IgnoreError() ImageMogr2 {
+	return imageMogr2.add(nil, "ignore-error", "1")
+}
+
+// 生成图片高级处理指令字符串
+func (imageMogr2 ImageMogr2) Command() (string, error) {
+	if imageMogr2.err != nil {
+		return "", imageMogr2.err
+	} else if len(imageMogr2.params) == 0 {
+		return "", errors.MissingRequiredFieldError{Name: "imageMogr2.params"}
+	}
+	return "imageMogr2/" + strings.Join(imageMogr2.params, "/"), nil
+}
+
+// 追加参数，保留第一个参数错误
+func (imageMogr2 ImageMogr2) add(err error, params ...string) ImageMogr2 {
+	if imageMogr2.err == nil {
+		imageMogr2.err = err
+	}
+	imageMogr2.params = append(append(make([]string, 0, len(imageMogr2.params)+len(params)), imageMogr2.params...), params...)
+	return imageMogr2
+}
+
+// 创建图片水印指令，imageURL 为水印图片的 URL
+func NewImageWatermark(imageURL string) Watermark {
+	return Watermark{mode: 1, image: imageURL}
+}
+
+// 创建文字水印指令
+func NewTextWatermark(text string) Watermark {
+	return Watermark{mode: 2, text: text}
+}
+
+// 设置透明度，取值范围为 1 到 100
+func (watermark Watermark) Dissolve(dissolve int) Watermark {
+	watermark.dissolve = dissolve
+	return watermark
+}
+
+// 设置水印位置
+func (watermark Watermark) Gravity(gravity Gravity) Watermark {
+	watermark.gravity = gravity
+	return watermark
+}
+
+// 设置水印相对于位置的横向和纵向偏移，单位为像素
+func (watermark Watermark) Offset(dx, dy int) Watermark {
+	watermark.dx, watermark.dy = &dx, &dy
+	return watermark
+}
+
+// 设置水印图片相对于原图短边的缩放比例，取值范围为 (0, 1]，仅对图片水印有效
+func (watermark Watermark) Scale(scale float64) Watermark {
+	watermark.scale, watermark.hasScale = scale, true
+	return watermark
+}
+
+// 设置字体，仅对文字水印有效
+func (watermark Watermark) Font(font string) Watermark {
+	watermark.font = font
+	return watermark
+}
+
+// 设置字体大小，单位为缇，仅对文字水印有效
+func (watermark Watermark) FontSize(fontSize int) Watermark {
+	watermark.fontSize = fontSize
+	return watermark
+}
+
+// 设置字体颜色，例如 `white`、`#FF0000`，仅对文字水印有效
+func (watermark Watermark) Fill(color string) Watermark {
+	watermark.fill = color
+	return watermark
+}
+
+// 生成水印指令字符串
+func (watermark Watermark) Command() (string, error) {
+	var builder strings.Builder
+	switch watermark.mode {
+	case 1:
+		if watermark.image == "" {
+			return "", errors.MissingRequiredFieldError{Name: "watermark.image"}
+		} else if !strings.HasPrefix(watermark.image, "http://") && !strings.HasPrefix(watermark.image, "https://") {
+			return "", invalidArgument("watermark.image", "must be an http or https url")
+		} else if watermark.font != "" || watermark.fontSize != 0 || watermark.fill != "" {
+			return "", invalidArgument("watermark.font", "is only valid for text watermark")
+		}
+		builder.WriteString("watermark/1/image/" + urlSafeBase64(watermark.image))
+	case 2:
+		if watermark.text == "" {
+			return "", errors.MissingRequiredFieldError{Name: "watermark.text"}
+		} else if watermark.hasScale {
+			return "", invalidArgument("watermark.ws", "is only valid for image watermark")
+		}
+		builder.WriteString("watermark/2/text/" + urlSafeBase64(watermark.text))
+		if watermark.font != "" {
+			builder.WriteString("/font/" + urlSafeBase64(watermark.font))
+		}
+		if watermark.fontSize != 0 {
+			if watermark.fontSize < 0 {
+				return "", invalidArgument("watermark.fontsize", "must be positive")
+			}
+			builder.WriteString("/fontsize/" + strconv.Itoa(watermark.fontSize))
+		}
+		if watermark.fill != "" {
+			if !colorPattern.MatchString(watermark.fill) {
+				return "", invalidArgument("watermark.fill", "must be a color name or #RRGGBB")
+			}
+			builder.WriteString("/fill/" + urlSafeBase64(watermark.fill))
+		}
+	default:
+		return "", invalidArgument("watermark.mode", "must be 1 or 2")
+	}
+	if watermark.dissolve != 0 {
+		if watermark.dissolve < 1 || watermark.dissolve > 100 {
+			return "", invalidArgument("watermark.dissolve", "must be between 1 and 100")
+		}
+		builder.WriteString("/dissolve/" + strconv.Itoa(watermark.dissolve))
+	}
+	if watermark.gravity != "" {
+		if err := checkGravity("watermark.gravity", watermark.gravity); err != nil {
+			return "", err
+		}
+		builder.WriteString("/gravity/" + string(watermark.gravity))
+	}
+	if watermark.dx != nil {
+		builder.WriteString("/dx/" + strconv.Itoa(*watermark.dx) + "/dy/" + strconv.Itoa(*watermark.dy))
+	}
+	if watermark.hasScale {
+		if watermark.scale <= 0 || watermark.scale > 1 {
+			return "", invalidArgument("watermark.ws", "must be in (0, 1]")
+		}
+		builder.WriteString("/ws/" + strconv.FormatFloat(watermark.scale, 'f', -1, 64))
+	}
+	return builder.String(), nil
+}
+
+func checkEdge(name string, value int) error {
+	if value < 0 || value > maxImageEdge {
+		return invalidArgument(name, "must be between 0 and 9999")
+	}
+	return nil
+}
+
+func checkQuality(name string, quality int) error {
+	if quality < 1 || quality > 100 {
+		return invalidArgument(name, "must be between 1 and 100")
+	}
+	return nil
+}
+
+func checkImageFormat(name, format string) error {
+	for _, f := range imageFormats {
+		if f == format {
+			return nil
+		}
+	}
+	return invalidArgument(name, "must be one of "+strings.Join(imageFormats, ", "))
+}
+
+func checkGravity(name string, gravity Gravity) error {
+	switch gravity {
+	case GravityNorthWest, GravityNorth, GravityNorthEast, GravityWest, GravityCenter, GravityEast,
+		GravitySouthWest, GravitySouth, GravitySouthEast:
+		return nil
+	}
+	return invalidArgument(name, "unknown gravity `"+string(gravity)+"`")
+}
+
+func boolParam(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
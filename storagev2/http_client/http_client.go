@@ -16,6 +16,7 @@ import (
 	"github.com/qiniu/go-sdk/v7/internal/clientv2"
 	"github.com/qiniu/go-sdk/v7/internal/hostprovider"
 	compatible_io "github.com/qiniu/go-sdk/v7/internal/io"
+	"github.com/qiniu/go-sdk/v7/storagev2/bandwidth"
 	"github.com/qiniu/go-sdk/v7/storagev2/chooser"
 	"github.com/qiniu/go-sdk/v7/storagev2/credentials"
 	"github.com/qiniu/go-sdk/v7/storagev2/defaults"
@@ -63,6 +64,7 @@ type (
 		afterBackoff        func(*http.Request, *retrier.RetrierOptions, time.Duration)
 		beforeRequest       func(*http.Request, *retrier.RetrierOptions)
 		afterResponse       func(*http.Response, *retrier.RetrierOptions, error)
+		bandwidthLimiter    *bandwidth.Limiter
 	}
 
 	// Options 为构建 Client 提供了可选参数
@@ -138,6 +140,10 @@ type (
 
 		// 是否加速上传
 		AccelerateUploading bool
+
+		// 带宽限制器，限制所有请求的请求体与响应体的总传输速度
+		// 可以被多个客户端共享，请求 context 上附加的带宽限制器优先级更高
+		BandwidthLimiter *bandwidth.Limiter
 	}
 
 	// Request 包含一个具体的 HTTP 请求的参数
@@ -238,6 +244,7 @@ func NewClient(options *Options) *Client {
 		afterBackoff:        options.AfterBackoff,
 		beforeRequest:       options.BeforeRequest,
 		afterResponse:       options.AfterResponse,
+		bandwidthLimiter:    options.BandwidthLimiter,
 	}
 }
 
@@ -247,7 +254,7 @@ func (httpClient *Client) Do(ctx context.Context, request *Request) (*http.Respo
 	if err != nil {
 		return nil, err
 	}
-	req = clientv2.WithInterceptors(req, clientv2.NewAntiHijackingInterceptor())
+	req = clientv2.WithInterceptors(req, clientv2.NewAntiHijackingInterceptor(), clientv2.NewBandwidthLimitInterceptor(httpClient.bandwidthLimiter))
 	if !isSignatureDisabled(ctx) {
 		if upTokenProvider := request.UpToken; upTokenProvider != nil {
 			req = clientv2.WithInterceptors(req, clientv2.NewUpTokenInterceptor(clientv2.UpTokenConfig{
@@ -324,6 +331,10 @@ func (httpClient *Client) GetHostsRetryConfig() *RetryConfig {
 	return httpClient.hostsRetryConfig
 }
 
+func (httpClient *Client) GetBandwidthLimiter() *bandwidth.Limiter {
+	return httpClient.bandwidthLimiter
+}
+
 func (httpClient *Client) GetResolver() resolver.Resolver {
 	return httpClient.resolver
 }
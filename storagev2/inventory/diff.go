@@ -0,0 +1,125 @@
+package inventory
+
+import (
+	"fmt"
+
+	"github.com/qiniu/go-sdk/v7/storagev2/objects"
+)
+
+type (
+	// 清单比较选项
+	DiffOptions struct {
+		// 读取清单的选项
+		ListerOptions
+
+		// 新增对象回调
+		OnAdded func(object *objects.ObjectDetails)
+
+		// 删除对象回调
+		OnRemoved func(object *objects.ObjectDetails)
+
+		// 变更对象回调，对象的哈希值或大小发生变化时视为变更
+		OnChanged func(from, to *objects.ObjectDetails)
+	}
+
+	// 清单比较结果
+	DiffReport struct {
+		Added     uint64 // 新增的对象数量
+		Removed   uint64 // 删除的对象数量
+		Changed   uint64 // 变更的对象数量
+		Unchanged uint64 // 没有变化的对象数量
+	}
+)
+
+// 比较 fromDirPath 和 toDirPath 目录中的两份清单
+func DiffInventories(fromDirPath, toDirPath string, options *DiffOptions) (*DiffReport, error) {
+	if options == nil {
+		options = &DiffOptions{}
+	}
+	from, err := NewLister(fromDirPath, &options.ListerOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer from.Close()
+	to, err := NewLister(toDirPath, &options.ListerOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer to.Close()
+	return Diff(from, to, options)
+}
+
+// 比较两个列举器列举的对象
+//
+// 两个列举器都必须按照对象名称升序列举，空间列举器和清单列举器均满足该要求，因此可以直接比较清单与空间的当前状态。
+// 比较过程只需要常数内存，适用于超大规模的清单。
+func Diff(from, to objects.Lister, options *DiffOptions) (*DiffReport, error) {
+	if options == nil {
+		options = &DiffOptions{}
+	}
+	var (
+		report           DiffReport
+		fromObject       objects.ObjectDetails
+		toObject         objects.ObjectDetails
+		fromOk, toOk     bool
+		fromErr, toErr   error
+		lastFrom, lastTo string
+		fromRead, toRead bool
+	)
+	nextFrom := func() {
+		if fromOk = from.Next(&fromObject); fromOk {
+			if fromRead && fromObject.Name <= lastFrom {
+				fromOk, fromErr = false, fmt.Errorf("objects are not listed in ascending order: %q after %q", fromObject.Name, lastFrom)
+			}
+			fromRead, lastFrom = true, fromObject.Name
+		} else {
+			fromErr = from.Error()
+		}
+	}
+	nextTo := func() {
+		if toOk = to.Next(&toObject); toOk {
+			if toRead && toObject.Name <= lastTo {
+				toOk, toErr = false, fmt.Errorf("objects are not listed in ascending order: %q after %q", toObject.Name, lastTo)
+			}
+			toRead, lastTo = true, toObject.Name
+		} else {
+			toErr = to.Error()
+		}
+	}
+	nextFrom()
+	nextTo()
+	for {
+		if fromErr != nil {
+			return &report, fromErr
+		} else if toErr != nil {
+			return &report, toErr
+		} else if !fromOk && !toOk {
+			return &report, nil
+		}
+		switch {
+		case !toOk || (fromOk && fromObject.Name < toObject.Name):
+			report.Removed += 1
+			if options.OnRemoved != nil {
+				options.OnRemoved(&fromObject)
+			}
+			nextFrom()
+		case !fromOk || toObject.Name < fromObject.Name:
+			report.Added += 1
+			if options.OnAdded != nil {
+				options.OnAdded(&toObject)
+			}
+			nextTo()
+		default:
+			if fromObject.ETag != toObject.ETag || fromObject.Size != toObject.Size {
+				report.Changed += 1
+				if options.OnChanged != nil {
+					options.OnChanged(&fromObject, &toObject)
+				}
+			} else {
+				report.Unchanged += 1
+			}
+			nextFrom()
+			nextTo()
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// Package inventory 提供七牛云存储空间的对象清单生成与比较。
+//
+// [Snapshot] 通过 objects.Lister 列举空间中的对象，将对象详情写入压缩的分片文件，并维护清单描述文件 manifest.json。
+// 每个分片文件写入完成后都会在清单描述文件中记录列举的位置标记，生成过程被中断后再次调用 [Snapshot] 即可从中断的位置继续，
+// 适用于包含数亿对象的空间的每日清单。
+//
+// # 生成清单
+//
+//	bucket := objectsManager.Bucket("my-bucket")
+//	manifest, err := inventory.Snapshot(ctx, bucket, "/data/inventory/2024-06-01", &inventory.SnapshotOptions{
+//	    Format:    inventory.NewJSONLinesFormat(),
+//	    ShardSize: 1000000,
+//	})
+//
+// 对于包含大量对象的空间，可以通过 [SnapshotOptions.Lister] 传入 objects.Bucket.ListParallel 返回的有序并行列举器加快列举：
+//
+//	lister := bucket.ListParallel(ctx, &objects.ParallelListObjectsOptions{Depth: 2, Concurrency: 16, Ordered: true})
+//	defer lister.Close()
+//	manifest, err := inventory.Snapshot(ctx, bucket, "/data/inventory/2024-06-01", &inventory.SnapshotOptions{Lister: lister})
+//
+// # 清单格式
+//
+// 内置支持 CSV（[NewCSVFormat]，默认）和 JSON Lines（[NewJSONLinesFormat]）格式，以及 gzip 压缩（[NewGzipCompression]，默认）。
+// 标准库不包含 Parquet 和 zstd 的实现，可以通过实现 [Format] 和 [Compression] 接口，借助第三方库支持这些格式，
+// 读取清单时通过 [ListerOptions] 传入相同的实现即可。
+//
+// # 读取与比较清单
+//
+// [NewLister] 读取生成完毕的清单，返回的列举器实现了 objects.Lister 接口。
+// [Diff] 按照对象名称归并比较两个列举器，通过哈希值和大小判断对象是否变更，只需要常数内存：
+//
+//	report, err := inventory.DiffInventories("/data/inventory/2024-05-31", "/data/inventory/2024-06-01", &inventory.DiffOptions{
+//	    OnAdded:   func(object *objects.ObjectDetails) { fmt.Println("+", object.Name) },
+//	    OnRemoved: func(object *objects.ObjectDetails) { fmt.Println("-", object.Name) },
+//	    OnChanged: func(from, to *objects.ObjectDetails) { fmt.Println("~", to.Name) },
+//	})
+package inventory
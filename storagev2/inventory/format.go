@@ -0,0 +1,276 @@
+package inventory
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/qiniu/go-sdk/v7/storagev2/objects"
+)
+
+type (
+	// 清单文件格式
+	//
+	// 除内置的 CSV 和 JSON Lines 格式外，可以实现该接口支持其他格式，例如通过第三方库支持 Parquet
+	Format interface {
+		// 格式名称，同时作为分片文件的扩展名
+		Name() string
+
+		// 创建编码器
+		NewEncoder(io.Writer) Encoder
+
+		// 创建解码器
+		NewDecoder(io.Reader) Decoder
+	}
+
+	// 清单编码器
+	Encoder interface {
+		// 编码对象详情
+		Encode(*objects.ObjectDetails) error
+
+		// 将缓存的数据写入底层 Writer
+		Flush() error
+	}
+
+	// 清单解码器
+	Decoder interface {
+		// 解码对象详情，没有更多数据时返回 io.EOF
+		Decode(*objects.ObjectDetails) error
+	}
+
+	// 清单文件压缩方式
+	//
+	// 除内置的 gzip 外，可以实现该接口支持其他压缩方式，例如通过第三方库支持 zstd
+	Compression interface {
+		// 压缩方式名称，同时作为分片文件的扩展名
+		Name() string
+
+		// 创建压缩 Writer
+		NewWriter(io.Writer) (io.WriteCloser, error)
+
+		// 创建解压缩 Reader
+		NewReader(io.Reader) (io.ReadCloser, error)
+	}
+
+	csvFormat struct{}
+
+	csvEncoder struct {
+		w             *csv.Writer
+		headerWritten bool
+	}
+
+	csvDecoder struct {
+		r       *csv.Reader
+		columns map[string]int
+	}
+
+	jsonLinesFormat struct{}
+
+	jsonLinesEncoder struct {
+		w       *bufio.Writer
+		encoder *json.Encoder
+	}
+
+	jsonLinesDecoder struct {
+		decoder *json.Decoder
+	}
+
+	gzipCompression struct{}
+
+	record struct {
+		Name         string            `json:"name"`
+		Size         int64             `json:"size"`
+		ETag         string            `json:"etag"`
+		UploadedAt   string            `json:"uploaded_at"`
+		MimeType     string            `json:"mime_type,omitempty"`
+		StorageClass int64             `json:"storage_class"`
+		Status       int64             `json:"status"`
+		EndUser      string            `json:"end_user,omitempty"`
+		MD5          string            `json:"md5,omitempty"`
+		Metadata     map[string]string `json:"metadata,omitempty"`
+	}
+)
+
+var csvColumns = []string{"name", "size", "etag", "uploaded_at", "mime_type", "storage_class", "status", "end_user", "md5"}
+
+// 创建 CSV 格式，第一行为列名，不包含对象的自定义元数据
+func NewCSVFormat() Format {
+	return csvFormat{}
+}
+
+func (csvFormat) Name() string {
+	return "csv"
+}
+
+func (csvFormat) NewEncoder(w io.Writer) Encoder {
+	return &csvEncoder{w: csv.NewWriter(w)}
+}
+
+func (csvFormat) NewDecoder(r io.Reader) Decoder {
+	return &csvDecoder{r: csv.NewReader(r)}
+}
+
+func (e *csvEncoder) Encode(object *objects.ObjectDetails) error {
+	if !e.headerWritten {
+		if err := e.w.Write(csvColumns); err != nil {
+			return err
+		}
+		e.headerWritten = true
+	}
+	var r record
+	r.fromObjectDetails(object)
+	return e.w.Write([]string{
+		r.Name, strconv.FormatInt(r.Size, 10), r.ETag, r.UploadedAt, r.MimeType,
+		strconv.FormatInt(r.StorageClass, 10), strconv.FormatInt(r.Status, 10), r.EndUser, r.MD5,
+	})
+}
+
+func (e *csvEncoder) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (d *csvDecoder) Decode(object *objects.ObjectDetails) error {
+	if d.columns == nil {
+		header, err := d.r.Read()
+		if err != nil {
+			return err
+		}
+		d.columns = make(map[string]int, len(header))
+		for i, column := range header {
+			d.columns[column] = i
+		}
+	}
+	values, err := d.r.Read()
+	if err != nil {
+		return err
+	}
+	column := func(name string) string {
+		if i, ok := d.columns[name]; ok && i < len(values) {
+			return values[i]
+		}
+		return ""
+	}
+	r := record{
+		Name:       column("name"),
+		ETag:       column("etag"),
+		UploadedAt: column("uploaded_at"),
+		MimeType:   column("mime_type"),
+		EndUser:    column("end_user"),
+		MD5:        column("md5"),
+	}
+	for name, value := range map[string]*int64{"size": &r.Size, "storage_class": &r.StorageClass, "status": &r.Status} {
+		if s := column(name); s != "" {
+			if *value, err = strconv.ParseInt(s, 10, 64); err != nil {
+				return fmt.Errorf("invalid %s of %q: %w", name, r.Name, err)
+			}
+		}
+	}
+	return r.toObjectDetails(object)
+}
+
+// 创建 JSON Lines 格式，每行为一个对象的 JSON，包含对象的自定义元数据
+func NewJSONLinesFormat() Format {
+	return jsonLinesFormat{}
+}
+
+func (jsonLinesFormat) Name() string {
+	return "jsonl"
+}
+
+func (jsonLinesFormat) NewEncoder(w io.Writer) Encoder {
+	bufferedWriter := bufio.NewWriter(w)
+	return &jsonLinesEncoder{w: bufferedWriter, encoder: json.NewEncoder(bufferedWriter)}
+}
+
+func (jsonLinesFormat) NewDecoder(r io.Reader) Decoder {
+	return &jsonLinesDecoder{json.NewDecoder(r)}
+}
+
+func (e *jsonLinesEncoder) Encode(object *objects.ObjectDetails) error {
+	var r record
+	r.fromObjectDetails(object)
+	return e.encoder.Encode(&r)
+}
+
+func (e *jsonLinesEncoder) Flush() error {
+	return e.w.Flush()
+}
+
+func (d *jsonLinesDecoder) Decode(object *objects.ObjectDetails) error {
+	var r record
+	if err := d.decoder.Decode(&r); err != nil {
+		return err
+	}
+	return r.toObjectDetails(object)
+}
+
+// 创建 gzip 压缩方式
+func NewGzipCompression() Compression {
+	return gzipCompression{}
+}
+
+func (gzipCompression) Name() string {
+	return "gz"
+}
+
+func (gzipCompression) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func (gzipCompression) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+func (r *record) fromObjectDetails(object *objects.ObjectDetails) {
+	*r = record{
+		Name:         object.Name,
+		Size:         object.Size,
+		ETag:         object.ETag,
+		MimeType:     object.MimeType,
+		StorageClass: int64(object.StorageClass),
+		Status:       int64(object.Status),
+		EndUser:      object.EndUser,
+		Metadata:     object.Metadata,
+	}
+	if !object.UploadedAt.IsZero() {
+		r.UploadedAt = object.UploadedAt.UTC().Format(time.RFC3339Nano)
+	}
+	if object.MD5 != [16]byte{} {
+		r.MD5 = hex.EncodeToString(object.MD5[:])
+	}
+}
+
+func (r *record) toObjectDetails(object *objects.ObjectDetails) error {
+	*object = objects.ObjectDetails{
+		Name:         r.Name,
+		Size:         r.Size,
+		ETag:         r.ETag,
+		MimeType:     r.MimeType,
+		StorageClass: objects.StorageClass(r.StorageClass),
+		Status:       objects.Status(r.Status),
+		EndUser:      r.EndUser,
+		Metadata:     r.Metadata,
+	}
+	if r.UploadedAt != "" {
+		uploadedAt, err := time.Parse(time.RFC3339Nano, r.UploadedAt)
+		if err != nil {
+			return fmt.Errorf("invalid uploaded_at of %q: %w", r.Name, err)
+		}
+		object.UploadedAt = uploadedAt
+	}
+	if r.MD5 != "" {
+		md5, err := hex.DecodeString(r.MD5)
+		if err != nil || len(md5) != len(object.MD5) {
+			return fmt.Errorf("invalid md5 of %q", r.Name)
+		}
+		copy(object.MD5[:], md5)
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/qiniu/go-sdk/v7/storagev2/objects"
+)
+
+type (
+	// 清单生成选项
+	SnapshotOptions struct {
+		// 对象前缀，如果不填写，则列举整个空间
+		Prefix string
+
+		// 清单文件格式，默认为 CSV
+		Format Format
+
+		// 清单文件压缩方式，默认为 gzip
+		Compression Compression
+
+		// 每个分片文件最多包含的对象数量，默认为 1000000
+		ShardSize uint64
+
+		// 每个分片文件写入完成后回调
+		OnShardCompleted func(manifest *Manifest, shard *Shard)
+
+		// 对象列举器，如果填写，则从该列举器读取对象，不再通过 Bucket.List 列举，例如可以使用 Bucket.ListParallel 返回的有序并行列举器
+		//
+		// 列举器必须按照对象名称升序输出且只列举 Prefix 下的对象，由调用方负责关闭。
+		// 从中断的位置继续生成时不会使用清单描述文件中的位置标记，而是跳过名称不大于 Manifest.LastKey 的对象。
+		Lister objects.Lister
+	}
+
+	// 清单描述文件
+	Manifest struct {
+		Bucket       string     `json:"bucket"`                 // 空间名称
+		Prefix       string     `json:"prefix"`                 // 对象前缀
+		Format       string     `json:"format"`                 // 清单文件格式名称
+		Compression  string     `json:"compression"`            // 清单文件压缩方式名称
+		StartedAt    time.Time  `json:"started_at"`             // 开始生成的时间
+		CompletedAt  *time.Time `json:"completed_at,omitempty"` // 生成完毕的时间，未生成完毕时为空
+		ObjectsCount uint64     `json:"objects_count"`          // 已经写入的对象数量
+		TotalSize    int64      `json:"total_size"`             // 已经写入的对象总大小，单位为字节
+		Shards       []*Shard   `json:"shards"`                 // 已经写入完成的分片文件
+		Marker       string     `json:"marker,omitempty"`       // 恢复列举的位置标记
+		LastKey      string     `json:"last_key,omitempty"`     // 最后一个写入的对象名称
+	}
+
+	// 清单分片文件
+	Shard struct {
+		FileName     string `json:"file_name"`     // 分片文件名称
+		ObjectsCount uint64 `json:"objects_count"` // 对象数量
+		TotalSize    int64  `json:"total_size"`    // 对象总大小，单位为字节
+		FirstKey     string `json:"first_key"`     // 第一个对象名称
+		LastKey      string `json:"last_key"`      // 最后一个对象名称
+	}
+
+	shardWriter struct {
+		filePath string
+		file     *os.File
+		writer   interface{ Close() error }
+		encoder  Encoder
+		shard    Shard
+	}
+)
+
+const (
+	// 清单描述文件名称
+	ManifestFileName = "manifest.json"
+
+	defaultShardSize = 1000000
+)
+
+var (
+	// 已经存在的清单描述文件与本次生成的参数不一致
+	ErrManifestMismatch = errors.New("inventory manifest mismatch")
+
+	// 清单尚未生成完毕
+	ErrInventoryNotCompleted = errors.New("inventory is not completed")
+)
+
+// 清单是否已经生成完毕
+func (manifest *Manifest) Completed() bool {
+	return manifest.CompletedAt != nil
+}
+
+// 读取 dirPath 目录中的清单描述文件
+func LoadManifest(dirPath string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dirPath, ManifestFileName))
+	if err != nil {
+		return nil, err
+	}
+	var manifest Manifest
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// 列举空间中的对象，生成清单到 dirPath 目录
+//
+// 对象按照列举顺序写入分片文件，每个分片文件写入完成后更新清单描述文件，记录恢复列举所需的位置标记。
+// 如果 dirPath 目录中已经存在未生成完毕的清单，则从上次中断的位置继续生成，已经存在的清单的空间、前缀、格式和压缩方式必须与本次一致，否则返回 ErrManifestMismatch；
+// 如果已经存在生成完毕的清单，则直接返回该清单描述文件。
+func Snapshot(ctx context.Context, bucket *objects.Bucket, dirPath string, options *SnapshotOptions) (*Manifest, error) {
+	if options == nil {
+		options = &SnapshotOptions{}
+	}
+	format := options.Format
+	if format == nil {
+		format = NewCSVFormat()
+	}
+	compression := options.Compression
+	if compression == nil {
+		compression = NewGzipCompression()
+	}
+	shardSize := options.ShardSize
+	if shardSize == 0 {
+		shardSize = defaultShardSize
+	}
+	if err := os.MkdirAll(dirPath, 0700); err != nil {
+		return nil, err
+	}
+
+	manifest, err := LoadManifest(dirPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		manifest = &Manifest{
+			Bucket:      bucket.Name(),
+			Prefix:      options.Prefix,
+			Format:      format.Name(),
+			Compression: compression.Name(),
+			StartedAt:   time.Now(),
+		}
+	} else if err != nil {
+		return nil, err
+	} else if manifest.Bucket != bucket.Name() || manifest.Prefix != options.Prefix ||
+		manifest.Format != format.Name() || manifest.Compression != compression.Name() {
+		return nil, ErrManifestMismatch
+	} else if manifest.Completed() {
+		return manifest, nil
+	}
+
+	var (
+		shard      *shardWriter
+		object     objects.ObjectDetails
+		pageMarker = manifest.Marker
+	)
+	defer func() {
+		if shard != nil {
+			shard.abort()
+		}
+	}()
+	commit := func() error {
+		committed := shard
+		shard = nil
+		if err := committed.commit(); err != nil {
+			return err
+		}
+		manifest.Shards = append(manifest.Shards, &committed.shard)
+		manifest.ObjectsCount += committed.shard.ObjectsCount
+		manifest.TotalSize += committed.shard.TotalSize
+		manifest.Marker = pageMarker
+		manifest.LastKey = committed.shard.LastKey
+		if err := writeManifest(dirPath, manifest); err != nil {
+			return err
+		}
+		if options.OnShardCompleted != nil {
+			options.OnShardCompleted(manifest, &committed.shard)
+		}
+		return nil
+	}
+
+	lister := options.Lister
+	if lister == nil {
+		lister = bucket.List(ctx, &objects.ListObjectsOptions{Prefix: manifest.Prefix, Marker: manifest.Marker})
+		defer lister.Close()
+	}
+	for {
+		marker := lister.Marker()
+		if !lister.Next(&object) {
+			break
+		}
+		// 位置标记变化说明列举器请求了新的一页，记录这一页的位置标记，恢复时从这一页重新列举并跳过已经写入的对象
+		if lister.Marker() != marker {
+			pageMarker = marker
+		}
+		if manifest.LastKey != "" && object.Name <= manifest.LastKey {
+			continue
+		}
+		if shard == nil {
+			fileName := fmt.Sprintf("shard-%06d.%s.%s", len(manifest.Shards), format.Name(), compression.Name())
+			if shard, err = newShardWriter(filepath.Join(dirPath, fileName), format, compression); err != nil {
+				return manifest, err
+			}
+		}
+		if err = shard.write(&object); err != nil {
+			return manifest, err
+		}
+		if shard.shard.ObjectsCount >= shardSize {
+			if err = commit(); err != nil {
+				return manifest, err
+			}
+		}
+	}
+	if err = lister.Error(); err != nil {
+		return manifest, err
+	}
+	if shard != nil {
+		if err = commit(); err != nil {
+			return manifest, err
+		}
+	}
+	completedAt := time.Now()
+	manifest.CompletedAt = &completedAt
+	manifest.Marker = ""
+	return manifest, writeManifest(dirPath, manifest)
+}
+
+func newShardWriter(filePath string, format Format, compression Compression) (*shardWriter, error) {
+	file, err := os.OpenFile(filePath+".tmp", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	writer, err := compression.NewWriter(file)
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	return &shardWriter{
+		filePath: filePath,
+		file:     file,
+		writer:   writer,
+		encoder:  format.NewEncoder(writer),
+		shard:    Shard{FileName: filepath.Base(filePath)},
+	}, nil
+}
+
+func (w *shardWriter) write(object *objects.ObjectDetails) error {
+	if err := w.encoder.Encode(object); err != nil {
+		return err
+	}
+	if w.shard.ObjectsCount == 0 {
+		w.shard.FirstKey = object.Name
+	}
+	w.shard.LastKey = object.Name
+	w.shard.ObjectsCount += 1
+	w.shard.TotalSize += object.Size
+	return nil
+}
+
+func (w *shardWriter) commit() error {
+	if err := w.encoder.Flush(); err != nil {
+		w.abort()
+		return err
+	}
+	if err := w.writer.Close(); err != nil {
+		w.abort()
+		return err
+	}
+	if err := w.file.Sync(); err != nil {
+		w.abort()
+		return err
+	}
+	if err := w.file.Close(); err != nil {
+		os.Remove(w.file.Name())
+		return err
+	}
+	if err := os.Rename(w.file.Name(), w.filePath); err != nil {
+		os.Remove(w.file.Name())
+		return err
+	}
+	return nil
+}
+
+func (w *shardWriter) abort() {
+	w.file.Close()
+	os.Remove(w.file.Name())
+}
+
+func writeManifest(dirPath string, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	manifestPath := filepath.Join(dirPath, ManifestFileName)
+	tmpFile, err := os.CreateTemp(dirPath, "."+ManifestFileName+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = tmpFile.Write(data); err == nil {
+		err = tmpFile.Sync()
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), manifestPath)
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+	}
+	return err
+}
@@ -0,0 +1,217 @@
+//go:build unit
+// +build unit
+
+package inventory_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qiniu/go-sdk/v7/storagev2/apis/get_objects"
+	"github.com/qiniu/go-sdk/v7/storagev2/credentials"
+	"github.com/qiniu/go-sdk/v7/storagev2/http_client"
+	"github.com/qiniu/go-sdk/v7/storagev2/inventory"
+	"github.com/qiniu/go-sdk/v7/storagev2/objects"
+	"github.com/qiniu/go-sdk/v7/storagev2/region"
+)
+
+type mockObject struct {
+	hash string
+	size int64
+}
+
+func newMockServer(t *testing.T, mutex *sync.Mutex, remoteObjects map[string]*mockObject, failAtMarker *string) *httptest.Server {
+	const pageSize = 2
+	serveMux := http.NewServeMux()
+	serveMux.HandleFunc("/list", func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		marker := r.URL.Query().Get("marker")
+		if *failAtMarker != "" && marker == *failAtMarker {
+			*failAtMarker = ""
+			w.Header().Add("X-ReqId", "fakereqid")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"list failed"}`))
+			return
+		}
+		objectNames := make([]string, 0, len(remoteObjects))
+		for objectName := range remoteObjects {
+			objectNames = append(objectNames, objectName)
+		}
+		sort.Strings(objectNames)
+		offset := 0
+		if marker != "" {
+			var err error
+			if offset, err = strconv.Atoi(marker); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var response get_objects.Response
+		for _, objectName := range objectNames[offset:min(offset+pageSize, len(objectNames))] {
+			response.Items = append(response.Items, get_objects.ListedObjectEntry{
+				Key:      objectName,
+				PutTime:  time.Now().UnixNano() / 100,
+				Hash:     remoteObjects[objectName].hash,
+				Size:     remoteObjects[objectName].size,
+				MimeType: "text/plain",
+			})
+		}
+		if offset+pageSize < len(objectNames) {
+			response.Marker = strconv.Itoa(offset + pageSize)
+		}
+		jsonData, err := json.Marshal(&response)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Add("X-ReqId", "fakereqid")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jsonData)
+	})
+	return httptest.NewServer(serveMux)
+}
+
+func TestSnapshotAndDiff(t *testing.T) {
+	var (
+		mutex         sync.Mutex
+		failAtMarker  = "4"
+		remoteObjects = make(map[string]*mockObject)
+	)
+	for i, objectName := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		remoteObjects[objectName] = &mockObject{hash: "hash-" + objectName, size: int64(i + 1)}
+	}
+	server := newMockServer(t, &mutex, remoteObjects, &failAtMarker)
+	defer server.Close()
+
+	bucket := objects.NewObjectsManager(&objects.ObjectsManagerOptions{
+		Options: http_client.Options{
+			Credentials: credentials.NewCredentials("testak", "testsk"),
+			Regions:     &region.Region{Rsf: region.Endpoints{Preferred: []string{server.URL}}},
+		},
+	}).Bucket("bucket1")
+
+	tmpDir, err := os.MkdirTemp("", "test-inventory-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	fromDir, toDir := filepath.Join(tmpDir, "from"), filepath.Join(tmpDir, "to")
+
+	// 第一次生成在第三页列举失败，只有第一个分片写入完成
+	manifest, err := inventory.Snapshot(context.Background(), bucket, fromDir, &inventory.SnapshotOptions{ShardSize: 3})
+	if err == nil {
+		t.Fatalf("snapshot should fail")
+	}
+	if manifest.Completed() || len(manifest.Shards) != 1 || manifest.LastKey != "c" || manifest.Marker != "2" {
+		t.Fatalf("unexpected manifest: %+v", manifest)
+	}
+	if _, err = inventory.NewLister(fromDir, nil); err != inventory.ErrInventoryNotCompleted {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = inventory.Snapshot(context.Background(), bucket, fromDir, &inventory.SnapshotOptions{Format: inventory.NewJSONLinesFormat()}); err != inventory.ErrManifestMismatch {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 第二次生成从中断的位置继续
+	var completedShards []string
+	manifest, err = inventory.Snapshot(context.Background(), bucket, fromDir, &inventory.SnapshotOptions{
+		ShardSize: 3,
+		OnShardCompleted: func(_ *inventory.Manifest, shard *inventory.Shard) {
+			completedShards = append(completedShards, shard.FirstKey+"-"+shard.LastKey)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !manifest.Completed() || manifest.ObjectsCount != 8 || manifest.TotalSize != 36 || len(manifest.Shards) != 3 {
+		t.Fatalf("unexpected manifest: %+v", manifest)
+	}
+	if len(completedShards) != 2 || completedShards[0] != "d-f" || completedShards[1] != "g-h" {
+		t.Fatalf("unexpected completed shards: %v", completedShards)
+	}
+	if _, err = os.Stat(filepath.Join(fromDir, "shard-000002.csv.gz")); err != nil {
+		t.Fatal(err)
+	}
+
+	lister, err := inventory.NewLister(fromDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		object      objects.ObjectDetails
+		objectNames []string
+	)
+	for lister.Next(&object) {
+		if object.ETag != remoteObjects[object.Name].hash || object.Size != remoteObjects[object.Name].size || object.MimeType != "text/plain" {
+			t.Fatalf("unexpected object: %+v", object)
+		}
+		objectNames = append(objectNames, object.Name)
+	}
+	if err = lister.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(objectNames) != 8 || !sort.StringsAreSorted(objectNames) {
+		t.Fatalf("unexpected object names: %v", objectNames)
+	}
+
+	mutex.Lock()
+	delete(remoteObjects, "b")
+	remoteObjects["e"].hash = "hash-e2"
+	remoteObjects["i"] = &mockObject{hash: "hash-i", size: 9}
+	mutex.Unlock()
+	if _, err = inventory.Snapshot(context.Background(), bucket, toDir, &inventory.SnapshotOptions{Format: inventory.NewJSONLinesFormat()}); err != nil {
+		t.Fatal(err)
+	}
+
+	var added, removed, changed []string
+	report, err := inventory.DiffInventories(fromDir, toDir, &inventory.DiffOptions{
+		OnAdded:   func(object *objects.ObjectDetails) { added = append(added, object.Name) },
+		OnRemoved: func(object *objects.ObjectDetails) { removed = append(removed, object.Name) },
+		OnChanged: func(from, to *objects.ObjectDetails) {
+			if from.ETag != "hash-e" || to.ETag != "hash-e2" {
+				t.Fatalf("unexpected changed object: %+v, %+v", from, to)
+			}
+			changed = append(changed, to.Name)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Added != 1 || report.Removed != 1 || report.Changed != 1 || report.Unchanged != 6 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if len(added) != 1 || added[0] != "i" || len(removed) != 1 || removed[0] != "b" || len(changed) != 1 || changed[0] != "e" {
+		t.Fatalf("unexpected diff: %v, %v, %v", added, removed, changed)
+	}
+
+	// 通过有序并行列举器生成的清单与顺序列举的结果相同
+	parallelDir := filepath.Join(tmpDir, "parallel")
+	parallelLister := bucket.ListParallel(context.Background(), &objects.ParallelListObjectsOptions{
+		Ordered:   true,
+		KeyRanges: []objects.KeyRange{{End: "d"}, {Start: "d"}},
+	})
+	defer parallelLister.Close()
+	if manifest, err = inventory.Snapshot(context.Background(), bucket, parallelDir, &inventory.SnapshotOptions{
+		ShardSize: 3,
+		Lister:    parallelLister,
+	}); err != nil {
+		t.Fatal(err)
+	} else if !manifest.Completed() || manifest.ObjectsCount != 8 || len(manifest.Shards) != 3 {
+		t.Fatalf("unexpected manifest: %+v", manifest)
+	}
+	if report, err = inventory.DiffInventories(toDir, parallelDir, nil); err != nil {
+		t.Fatal(err)
+	} else if report.Added != 0 || report.Removed != 0 || report.Changed != 0 || report.Unchanged != 8 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+}
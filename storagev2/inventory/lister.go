@@ -0,0 +1,138 @@
+package inventory
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/qiniu/go-sdk/v7/storagev2/objects"
+)
+
+type (
+	// 清单列举器选项
+	ListerOptions struct {
+		// 额外支持的清单文件格式，内置支持 CSV 和 JSON Lines
+		Formats []Format
+
+		// 额外支持的清单文件压缩方式，内置支持 gzip
+		Compressions []Compression
+	}
+
+	inventoryLister struct {
+		dirPath     string
+		shards      []*Shard
+		format      Format
+		compression Compression
+		file        *os.File
+		reader      io.ReadCloser
+		decoder     Decoder
+		lastKey     string
+		err         error
+	}
+)
+
+// 创建清单列举器，按照分片文件的顺序读取 dirPath 目录中已经生成完毕的清单
+//
+// 清单列举器实现了 objects.Lister 接口，其位置标记为最后一个读取的对象名称
+func NewLister(dirPath string, options *ListerOptions) (objects.Lister, error) {
+	if options == nil {
+		options = &ListerOptions{}
+	}
+	manifest, err := LoadManifest(dirPath)
+	if err != nil {
+		return nil, err
+	} else if !manifest.Completed() {
+		return nil, ErrInventoryNotCompleted
+	}
+	lister := inventoryLister{dirPath: dirPath, shards: manifest.Shards}
+	for _, format := range append(options.Formats, NewCSVFormat(), NewJSONLinesFormat()) {
+		if format.Name() == manifest.Format {
+			lister.format = format
+			break
+		}
+	}
+	if lister.format == nil {
+		return nil, fmt.Errorf("unsupported inventory format: %s", manifest.Format)
+	}
+	for _, compression := range append(options.Compressions, NewGzipCompression()) {
+		if compression.Name() == manifest.Compression {
+			lister.compression = compression
+			break
+		}
+	}
+	if lister.compression == nil {
+		return nil, fmt.Errorf("unsupported inventory compression: %s", manifest.Compression)
+	}
+	return &lister, nil
+}
+
+func (l *inventoryLister) Next(object *objects.ObjectDetails) bool {
+	if l.err != nil {
+		return false
+	}
+	for {
+		if l.decoder == nil {
+			if len(l.shards) == 0 {
+				return false
+			}
+			if l.err = l.openShard(l.shards[0]); l.err != nil {
+				return false
+			}
+			l.shards = l.shards[1:]
+		}
+		err := l.decoder.Decode(object)
+		if err == nil {
+			l.lastKey = object.Name
+			return true
+		} else if err != io.EOF {
+			l.err = err
+			return false
+		}
+		if l.err = l.closeShard(); l.err != nil {
+			return false
+		}
+	}
+}
+
+func (l *inventoryLister) openShard(shard *Shard) error {
+	file, err := os.Open(filepath.Join(l.dirPath, shard.FileName))
+	if err != nil {
+		return err
+	}
+	reader, err := l.compression.NewReader(bufio.NewReader(file))
+	if err != nil {
+		file.Close()
+		return err
+	}
+	l.file, l.reader, l.decoder = file, reader, l.format.NewDecoder(reader)
+	return nil
+}
+
+func (l *inventoryLister) closeShard() error {
+	if l.decoder == nil {
+		return nil
+	}
+	err := l.reader.Close()
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	l.file, l.reader, l.decoder = nil, nil, nil
+	return err
+}
+
+func (l *inventoryLister) Error() error {
+	return l.err
+}
+
+func (l *inventoryLister) Marker() string {
+	return l.lastKey
+}
+
+func (l *inventoryLister) Close() error {
+	if err := l.closeShard(); err != nil && l.err == nil {
+		l.err = err
+	}
+	return l.err
+}
@@ -3,7 +3,6 @@ package objects
 import (
 	"context"
 	"encoding/hex"
-	"errors"
 	"fmt"
 	"net"
 	"net/url"
@@ -517,7 +516,7 @@ func doOperations(ctx internal_context.Context, operations []*operation, storage
 				}
 				operation.handleResponse(&object, nil)
 			} else {
-				operation.handleResponse(nil, errors.New(operationResponse.Data.Error))
+				operation.handleResponse(nil, &clientv1.ErrorInfo{Err: operationResponse.Data.Error, Code: int(operationResponse.Code)})
 				operation.tries += 1
 				if retrier.IsStatusCodeRetryable(int(operationResponse.Code)) && operation.tries < maxTries {
 					willDoNextLoop = append(willDoNextLoop, operation)
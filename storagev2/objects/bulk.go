@@ -0,0 +1,390 @@
+package objects
+
+import (
+	"context"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qiniu/go-sdk/v7/storagev2/errors"
+)
+
+type (
+	// 批量操作过滤条件，所有设置的条件都满足时才操作对象
+	BulkFilter struct {
+		// 对象名称前缀
+		Prefix string
+
+		// 对象名称通配符，语法与 path.Match 一致，* 不匹配 /
+		Glob string
+
+		// 最小对象大小（包含），单位为字节，为 0 表示不限制
+		MinSize int64
+
+		// 最大对象大小（包含），单位为字节，为 0 表示不限制
+		MaxSize int64
+
+		// 仅操作在此时间（包含）之后上传的对象
+		UploadedAfter time.Time
+
+		// 仅操作在此时间（不包含）之前上传的对象
+		UploadedBefore time.Time
+
+		// 仅操作指定存储类型的对象
+		StorageClasses []StorageClass
+
+		// 仅操作指定存储状态的对象
+		Statuses []Status
+
+		// 仅操作 MIME 类型匹配的对象，支持 path.Match 通配符，例如 image/*
+		MimeTypes []string
+
+		// 自定义过滤函数
+		Predicate func(*ObjectDetails) bool
+	}
+
+	// 批量操作动作，为对象生成操作，返回 nil 表示跳过该对象
+	BulkAction func(bucket *Bucket, object *ObjectDetails) (Operation, error)
+
+	// 对象重命名模板
+	//
+	// 支持以下占位符：
+	//   - {name}: 完整的对象名称
+	//   - {dir}: 对象名称中最后一个 / 及之前的部分
+	//   - {base}: 对象名称中最后一个 / 之后、扩展名之前的部分
+	//   - {ext}: 扩展名，包含 .
+	RenameTemplate string
+
+	// 对象生命周期，天数为 0 表示不设置
+	ObjectLifeCycle struct {
+		ToIAAfterDays          int64
+		ToArchiveIRAfterDays   int64
+		ToArchiveAfterDays     int64
+		ToDeepArchiveAfterDays int64
+		DeleteAfterDays        int64
+	}
+
+	// 批量操作选项
+	BulkOptions struct {
+		// 过滤条件，如果不填写，则操作列举到的所有对象
+		Filter *BulkFilter
+
+		// 对象列举器，如果不填写，则按照过滤条件的前缀列举存储空间
+		Lister Lister
+
+		// 批处理执行器，如果不填写，默认使用 ObjectsManager 的批处理执行器
+		BatchOpsExecutor BatchOpsExecutor
+
+		// 每次提交给批处理执行器的操作数量，默认为 1000
+		ChunkSize int
+
+		// 每批操作执行后回调，参数为当前的统计结果
+		OnProgress func(*BulkReport)
+	}
+
+	// 批量操作报告
+	BulkReport struct {
+		Listed    uint64       // 列举到的对象数量
+		Matched   uint64       // 满足过滤条件的对象数量
+		Succeeded uint64       // 操作成功的对象数量
+		Failed    uint64       // 操作失败的对象数量
+		Errors    []*BulkError // 操作失败的对象及错误
+	}
+
+	// 批量操作中单个对象的错误
+	BulkError struct {
+		ObjectName string
+		Err        error
+	}
+
+	bulkOperation struct {
+		Operation
+		objectName string
+		lock       sync.Mutex
+		err        error
+	}
+)
+
+// 判断对象是否满足过滤条件
+func (filter *BulkFilter) Match(object *ObjectDetails) bool {
+	if filter == nil {
+		return true
+	}
+	if !strings.HasPrefix(object.Name, filter.Prefix) {
+		return false
+	}
+	if filter.Glob != "" {
+		if matched, _ := path.Match(filter.Glob, object.Name); !matched {
+			return false
+		}
+	}
+	if (filter.MinSize > 0 && object.Size < filter.MinSize) || (filter.MaxSize > 0 && object.Size > filter.MaxSize) {
+		return false
+	}
+	if (!filter.UploadedAfter.IsZero() && object.UploadedAt.Before(filter.UploadedAfter)) ||
+		(!filter.UploadedBefore.IsZero() && !object.UploadedAt.Before(filter.UploadedBefore)) {
+		return false
+	}
+	if len(filter.StorageClasses) > 0 && !contains(filter.StorageClasses, object.StorageClass) {
+		return false
+	}
+	if len(filter.Statuses) > 0 && !contains(filter.Statuses, object.Status) {
+		return false
+	}
+	if len(filter.MimeTypes) > 0 {
+		matched := false
+		for _, pattern := range filter.MimeTypes {
+			if matched, _ = path.Match(pattern, object.MimeType); matched {
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return filter.Predicate == nil || filter.Predicate(object)
+}
+
+func contains[T comparable](values []T, value T) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// 根据模板生成新的对象名称
+func (template RenameTemplate) Render(objectName string) (string, error) {
+	dir, base := "", objectName
+	if i := strings.LastIndex(objectName, "/"); i >= 0 {
+		dir, base = objectName[:i+1], objectName[i+1:]
+	}
+	ext := path.Ext(base)
+	base = strings.TrimSuffix(base, ext)
+
+	var builder strings.Builder
+	rest := string(template)
+	for {
+		i := strings.IndexByte(rest, '{')
+		if i < 0 {
+			builder.WriteString(rest)
+			break
+		}
+		builder.WriteString(rest[:i])
+		j := strings.IndexByte(rest[i:], '}')
+		if j < 0 {
+			return "", errors.InvalidArgumentError{Name: "RenameTemplate", Reason: "unclosed placeholder"}
+		}
+		switch placeholder := rest[i+1 : i+j]; placeholder {
+		case "name":
+			builder.WriteString(objectName)
+		case "dir":
+			builder.WriteString(dir)
+		case "base":
+			builder.WriteString(base)
+		case "ext":
+			builder.WriteString(ext)
+		default:
+			return "", errors.InvalidArgumentError{Name: "RenameTemplate", Reason: "unknown placeholder {" + placeholder + "}"}
+		}
+		rest = rest[i+j+1:]
+	}
+	if builder.Len() == 0 {
+		return "", errors.InvalidArgumentError{Name: "RenameTemplate", Reason: "empty object name"}
+	}
+	return builder.String(), nil
+}
+
+// 删除对象，ObjectsManager 启用软删除时，跳过回收站中的对象，避免刚移动到回收站的对象被再次删除
+func BulkDelete() BulkAction {
+	return func(bucket *Bucket, object *ObjectDetails) (Operation, error) {
+		if bucket.objectsManager.softDelete != nil && bucket.Trash(nil).Contains(object.Name) {
+			return nil, nil
+		}
+		return bucket.Object(object.Name).Delete(), nil
+	}
+}
+
+// 移动对象到 toBucketName 空间，新的对象名称由 template 生成，新旧对象名称相同时跳过
+func BulkMove(toBucketName string, template RenameTemplate, force bool) BulkAction {
+	return func(bucket *Bucket, object *ObjectDetails) (Operation, error) {
+		toObjectName, err := template.Render(object.Name)
+		if err != nil {
+			return nil, err
+		} else if toBucketName == bucket.name && toObjectName == object.Name {
+			return nil, nil
+		}
+		return bucket.Object(object.Name).MoveTo(toBucketName, toObjectName).Force(force), nil
+	}
+}
+
+// 复制对象到 toBucketName 空间，新的对象名称由 template 生成，新旧对象名称相同时跳过
+func BulkCopy(toBucketName string, template RenameTemplate, force bool) BulkAction {
+	return func(bucket *Bucket, object *ObjectDetails) (Operation, error) {
+		toObjectName, err := template.Render(object.Name)
+		if err != nil {
+			return nil, err
+		} else if toBucketName == bucket.name && toObjectName == object.Name {
+			return nil, nil
+		}
+		return bucket.Object(object.Name).CopyTo(toBucketName, toObjectName).Force(force), nil
+	}
+}
+
+// 设置对象存储类型，存储类型已经一致的对象将被跳过
+func BulkSetStorageClass(storageClass StorageClass) BulkAction {
+	return func(bucket *Bucket, object *ObjectDetails) (Operation, error) {
+		if object.StorageClass == storageClass {
+			return nil, nil
+		}
+		return bucket.Object(object.Name).SetStorageClass(storageClass), nil
+	}
+}
+
+// 设置对象生命周期
+func BulkSetLifeCycle(lifeCycle ObjectLifeCycle) BulkAction {
+	return func(bucket *Bucket, object *ObjectDetails) (Operation, error) {
+		return bucket.Object(object.Name).SetLifeCycle().
+			ToIAAfterDays(lifeCycle.ToIAAfterDays).
+			ToArchiveIRAfterDays(lifeCycle.ToArchiveIRAfterDays).
+			ToArchiveAfterDays(lifeCycle.ToArchiveAfterDays).
+			ToDeepArchiveAfterDays(lifeCycle.ToDeepArchiveAfterDays).
+			DeleteAfterDays(lifeCycle.DeleteAfterDays), nil
+	}
+}
+
+// 设置对象 MIME 类型和自定义元数据，仅当对象的 Etag 与列举时一致时修改，mimeType 为空表示使用对象原有的 MIME 类型
+func BulkSetMetadata(mimeType string, metadata map[string]string) BulkAction {
+	return func(bucket *Bucket, object *ObjectDetails) (Operation, error) {
+		newMimeType := mimeType
+		if newMimeType == "" {
+			newMimeType = object.MimeType
+		}
+		return bucket.Object(object.Name).SetMetadata(newMimeType).Metadata(metadata).IfMatchETag(object.ETag), nil
+	}
+}
+
+// 批量操作对象
+//
+// 列举对象并按照过滤条件筛选后，为每个对象生成操作，每 ChunkSize 个操作提交给批处理执行器执行。
+// 单个对象的操作失败将记录在报告中，不会中断批量操作；列举失败、生成操作失败或批处理请求失败时中断并返回错误，已经执行的结果仍然记录在报告中。
+// 注意，移动到同一空间时，如果新的对象名称仍然满足过滤条件，可能会被再次列举到。
+func (bucket *Bucket) Bulk(ctx context.Context, action BulkAction, options *BulkOptions) (*BulkReport, error) {
+	if action == nil {
+		return nil, errors.MissingRequiredFieldError{Name: "Action"}
+	}
+	if options == nil {
+		options = &BulkOptions{}
+	}
+	chunkSize := options.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = 1000
+	}
+	lister := options.Lister
+	if lister == nil {
+		var prefix string
+		if options.Filter != nil {
+			prefix = options.Filter.Prefix
+		}
+		lister = bucket.List(ctx, &ListObjectsOptions{Prefix: prefix})
+		defer lister.Close()
+	}
+
+	var (
+		report     BulkReport
+		operations = make([]*bulkOperation, 0, chunkSize)
+	)
+	flush := func() error {
+		if len(operations) == 0 {
+			return nil
+		}
+		ops := make([]Operation, len(operations))
+		for i, operation := range operations {
+			ops[i] = operation
+		}
+		err := bucket.objectsManager.Batch(ctx, ops, &BatchOptions{BatchOpsExecutor: options.BatchOpsExecutor})
+		if err == nil {
+			for _, operation := range operations {
+				if operationErr := operation.result(); operationErr != nil {
+					report.Failed += 1
+					report.Errors = append(report.Errors, &BulkError{ObjectName: operation.objectName, Err: operationErr})
+				} else {
+					report.Succeeded += 1
+				}
+			}
+			if options.OnProgress != nil {
+				options.OnProgress(&report)
+			}
+		}
+		operations = operations[:0]
+		return err
+	}
+
+	var object ObjectDetails
+	for lister.Next(&object) {
+		report.Listed += 1
+		if !options.Filter.Match(&object) {
+			continue
+		}
+		report.Matched += 1
+		operation, err := action(bucket, &object)
+		if err != nil {
+			return &report, err
+		} else if operation == nil {
+			continue
+		}
+		operations = append(operations, newBulkOperation(operation, object.Name))
+		if len(operations) >= chunkSize {
+			if err = flush(); err != nil {
+				return &report, err
+			}
+		}
+	}
+	if err := lister.Error(); err != nil {
+		return &report, err
+	}
+	return &report, flush()
+}
+
+// 记录操作的最后一次结果，批处理执行器可能会重试失败的操作
+func newBulkOperation(operation Operation, objectName string) *bulkOperation {
+	bulkOp := bulkOperation{objectName: objectName}
+	// 软删除的结果在设置删除时间和生命周期之后才能确定，因此通过回调函数记录最终结果
+	if deleteOperation, ok := operation.(*DeleteObjectOperation); ok && deleteOperation.trash != nil {
+		onResponse, onError := deleteOperation.onResponse, deleteOperation.onError
+		operation = deleteOperation.OnResponse(func() {
+			bulkOp.setResult(nil)
+			if onResponse != nil {
+				onResponse()
+			}
+		}).OnError(func(err error) {
+			bulkOp.setResult(err)
+			if onError != nil {
+				onError(err)
+			} else if onResponse != nil {
+				onResponse()
+			}
+		})
+	}
+	bulkOp.Operation = operation
+	return &bulkOp
+}
+
+func (operation *bulkOperation) handleResponse(object *ObjectDetails, err error) {
+	operation.setResult(err)
+	operation.Operation.handleResponse(object, err)
+}
+
+func (operation *bulkOperation) setResult(err error) {
+	operation.lock.Lock()
+	defer operation.lock.Unlock()
+	operation.err = err
+}
+
+func (operation *bulkOperation) result() error {
+	operation.lock.Lock()
+	defer operation.lock.Unlock()
+	return operation.err
+}
@@ -0,0 +1,205 @@
+//go:build unit
+// +build unit
+
+package objects_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qiniu/go-sdk/v7/storagev2/apis/batch_ops"
+	"github.com/qiniu/go-sdk/v7/storagev2/apis/get_objects"
+	"github.com/qiniu/go-sdk/v7/storagev2/credentials"
+	"github.com/qiniu/go-sdk/v7/storagev2/errors"
+	"github.com/qiniu/go-sdk/v7/storagev2/http_client"
+	"github.com/qiniu/go-sdk/v7/storagev2/objects"
+	"github.com/qiniu/go-sdk/v7/storagev2/region"
+)
+
+func TestRenameTemplate(t *testing.T) {
+	testCases := []struct {
+		template, objectName, expected string
+	}{
+		{"archive/{name}", "logs/2024/a.log", "archive/logs/2024/a.log"},
+		{"{dir}{base}.bak{ext}", "logs/2024/a.log", "logs/2024/a.bak.log"},
+		{"{base}{ext}", "a", "a"},
+		{"flat/{base}", "dir/file.tar.gz", "flat/file.tar"},
+	}
+	for _, testCase := range testCases {
+		if actual, err := objects.RenameTemplate(testCase.template).Render(testCase.objectName); err != nil {
+			t.Fatal(err)
+		} else if actual != testCase.expected {
+			t.Fatalf("unexpected rendered name: %s, expected: %s", actual, testCase.expected)
+		}
+	}
+	for _, template := range []string{"{unknown}", "{name", ""} {
+		if _, err := objects.RenameTemplate(template).Render("a.txt"); err == nil {
+			t.Fatalf("template %q should be invalid", template)
+		} else if _, ok := err.(errors.InvalidArgumentError); !ok {
+			t.Fatalf("unexpected error type: %v", err)
+		}
+	}
+}
+
+func TestBulkFilter(t *testing.T) {
+	now := time.Now()
+	object := objects.ObjectDetails{
+		Name:         "images/2024/cat.jpg",
+		Size:         1024,
+		UploadedAt:   now,
+		MimeType:     "image/jpeg",
+		StorageClass: objects.IAStorageClass,
+		Status:       objects.EnabledStatus,
+	}
+	matchedFilters := []*objects.BulkFilter{
+		nil,
+		{Prefix: "images/", Glob: "images/*/*.jpg"},
+		{MinSize: 1024, MaxSize: 1024},
+		{UploadedAfter: now, UploadedBefore: now.Add(time.Second)},
+		{StorageClasses: []objects.StorageClass{objects.StandardStorageClass, objects.IAStorageClass}},
+		{Statuses: []objects.Status{objects.EnabledStatus}, MimeTypes: []string{"text/*", "image/*"}},
+		{Predicate: func(object *objects.ObjectDetails) bool { return strings.HasSuffix(object.Name, ".jpg") }},
+	}
+	for i, filter := range matchedFilters {
+		if !filter.Match(&object) {
+			t.Fatalf("filter %d should match", i)
+		}
+	}
+	unmatchedFilters := []*objects.BulkFilter{
+		{Prefix: "videos/"},
+		{Glob: "images/*.jpg"},
+		{MinSize: 1025},
+		{MaxSize: 1023},
+		{UploadedAfter: now.Add(time.Second)},
+		{UploadedBefore: now},
+		{StorageClasses: []objects.StorageClass{objects.ArchiveStorageClass}},
+		{Statuses: []objects.Status{objects.DisabledStatus}},
+		{MimeTypes: []string{"image/png"}},
+		{Predicate: func(*objects.ObjectDetails) bool { return false }},
+	}
+	for i, filter := range unmatchedFilters {
+		if filter.Match(&object) {
+			t.Fatalf("filter %d should not match", i)
+		}
+	}
+}
+
+func TestBucketBulk(t *testing.T) {
+	listedObjects := []get_objects.ListedObjectEntry{
+		{Key: "logs/a.log", Size: 100, MimeType: "text/plain"},
+		{Key: "logs/b.log", Size: 200, MimeType: "text/plain"},
+		{Key: "logs/c.txt", Size: 300, MimeType: "text/plain"},
+		{Key: "logs/d.log", Size: 0, MimeType: "text/plain"},
+		{Key: "logs/e.log", Size: 400, MimeType: "text/plain"},
+		{Key: "logs/sub/f.log", Size: 500, MimeType: "text/plain"},
+	}
+	var (
+		lock      sync.Mutex
+		batches   [][]string
+		triedOnce = make(map[string]bool)
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/list", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("prefix") != "logs/" {
+			t.Fatalf("unexpected prefix: %s", r.URL.Query().Get("prefix"))
+		}
+		for i := range listedObjects {
+			listedObjects[i].Hash = "testhash"
+			listedObjects[i].PutTime = time.Now().UnixNano() / 100
+		}
+		jsonData, err := json.Marshal(&get_objects.Response{Items: listedObjects})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("X-ReqId", "fakereqid")
+		w.Write(jsonData)
+	})
+	mux.HandleFunc("/batch", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		batches = append(batches, r.PostForm["op"])
+		responses := make([]batch_ops.OperationResponse, 0, len(r.PostForm["op"]))
+		for _, op := range r.PostForm["op"] {
+			parts := strings.Split(op, "/")
+			from, err := base64.URLEncoding.DecodeString(parts[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch string(from) {
+			case "bucket1:logs/b.log":
+				// 第一次失败，重试后成功
+				if !triedOnce[string(from)] {
+					triedOnce[string(from)] = true
+					responses = append(responses, batch_ops.OperationResponse{Code: 599, Data: batch_ops.OperationResponseData{Error: "server error"}})
+					continue
+				}
+			case "bucket1:logs/e.log":
+				responses = append(responses, batch_ops.OperationResponse{Code: 614, Data: batch_ops.OperationResponseData{Error: "file exists"}})
+				continue
+			}
+			responses = append(responses, batch_ops.OperationResponse{Code: 200})
+		}
+		jsonData, err := json.Marshal(&batch_ops.Response{OperationResponses: responses})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("X-ReqId", "fakereqid")
+		w.Write(jsonData)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	objectsManager := objects.NewObjectsManager(&objects.ObjectsManagerOptions{
+		Options: http_client.Options{
+			Credentials: credentials.NewCredentials("testak", "testsk"),
+			Regions: &region.Region{
+				Rs:  region.Endpoints{Preferred: []string{server.URL}},
+				Rsf: region.Endpoints{Preferred: []string{server.URL}},
+			},
+		},
+	})
+	var progresses []uint64
+	report, err := objectsManager.Bucket("bucket1").Bulk(context.Background(), objects.BulkMove("bucket2", "archive/{name}", false), &objects.BulkOptions{
+		Filter:           &objects.BulkFilter{Prefix: "logs/", Glob: "logs/*.log", MinSize: 1},
+		BatchOpsExecutor: objects.NewSerialBatchOpsExecutor(nil),
+		ChunkSize:        2,
+		OnProgress: func(report *objects.BulkReport) {
+			progresses = append(progresses, report.Succeeded+report.Failed)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Listed != 6 || report.Matched != 3 || report.Succeeded != 2 || report.Failed != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].ObjectName != "logs/e.log" {
+		t.Fatalf("unexpected errors: %+v", report.Errors)
+	}
+	if len(progresses) != 2 || progresses[0] != 2 || progresses[1] != 3 {
+		t.Fatalf("unexpected progresses: %v", progresses)
+	}
+	expectedOp := "move/" + base64.URLEncoding.EncodeToString([]byte("bucket1:logs/a.log")) + "/" +
+		base64.URLEncoding.EncodeToString([]byte("bucket2:archive/logs/a.log"))
+	if len(batches) != 3 || len(batches[0]) != 2 || batches[0][0] != expectedOp || len(batches[1]) != 1 || len(batches[2]) != 1 {
+		t.Fatalf("unexpected batches: %v", batches)
+	}
+
+	if _, err = objectsManager.Bucket("bucket1").Bulk(context.Background(), objects.BulkCopy("bucket2", "{unknown}", false), &objects.BulkOptions{
+		Filter: &objects.BulkFilter{Prefix: "logs/"},
+	}); err == nil {
+		t.Fatalf("invalid rename template should fail")
+	}
+}
@@ -2,11 +2,13 @@ package objects
 
 import (
 	"container/list"
+	"errors"
 	"path/filepath"
 	"strings"
 
 	"github.com/qiniu/go-sdk/v7/internal/context"
 	"github.com/qiniu/go-sdk/v7/storagev2/apis"
+	resumablerecorder "github.com/qiniu/go-sdk/v7/storagev2/objects/resumable_recorder"
 )
 
 type (
@@ -30,61 +32,269 @@ type (
 		NeedParts bool // 是否需要分片信息
 		Recursive bool // 是否递归列举
 	}
+
+	// 覆盖策略
+	OverwritePolicy uint8
+
+	// 目录操作选项
+	DirectoryOperationOptions struct {
+		// 覆盖策略，仅对移动和复制有效，默认为目标对象已经存在时操作失败
+		OverwritePolicy OverwritePolicy
+
+		// 每批操作的数量，默认为 1000
+		BatchSize int
+
+		// 批处理执行器，如果不填写，默认使用 ObjectsManager 的批处理执行器
+		BatchOpsExecutor BatchOpsExecutor
+
+		// 可恢复记录仪，如果填写，则每批操作完成后记录列举的位置，中断后再次执行相同的目录操作将从记录的位置继续
+		ResumableRecorder resumablerecorder.ResumableRecorder
+
+		// 每个对象操作完成后回调
+		OnObjectDone func(*DirectoryObjectResult)
+	}
+
+	// 目录操作中单个对象的结果
+	DirectoryObjectResult struct {
+		// 对象名称
+		ObjectName string
+
+		// 目标对象名称，删除时为空
+		ToObjectName string
+
+		// 目标对象已经存在而跳过，仅当覆盖策略为 OverwriteSkip 时有效
+		Skipped bool
+
+		// 操作错误，为 nil 表示操作成功或被跳过
+		Err error
+	}
 )
 
 var SkipDir = filepath.SkipDir
 
+const (
+	// 目标对象已经存在时操作失败
+	OverwriteNever OverwritePolicy = iota
+
+	// 目标对象已经存在时覆盖
+	OverwriteAlways
+
+	// 目标对象已经存在时跳过
+	OverwriteSkip
+)
+
 // 移动目录
 func (directory *Directory) MoveTo(ctx context.Context, toBucketName, toPrefix string) error {
+	return directory.MoveToWithOptions(ctx, toBucketName, toPrefix, nil)
+}
+
+// 移动目录，通过 options 指定覆盖策略、接收每个对象的操作结果并支持断点续传
+//
+// 目标目录位于当前目录之内时，目标目录下的对象将被跳过。
+func (directory *Directory) MoveToWithOptions(ctx context.Context, toBucketName, toPrefix string, options *DirectoryOperationOptions) error {
+	if options == nil {
+		options = &DirectoryOperationOptions{}
+	}
 	if !strings.HasSuffix(toPrefix, directory.pathSeparator) {
 		toPrefix += directory.pathSeparator
 	}
-	operations := make([]Operation, 0, 16)
-	if err := directory.forEachObject(ctx, func(objectDetails *ObjectDetails) {
+	return directory.doOperations(ctx, &resumablerecorder.ResumableRecorderOpenArgs{
+		Operation:    "move",
+		BucketName:   directory.bucket.name,
+		Prefix:       directory.prefix,
+		ToBucketName: toBucketName,
+		ToPrefix:     toPrefix,
+	}, options, func(objectDetails *ObjectDetails) (Operation, string) {
+		if directory.isUnderToPrefix(objectDetails.Name, toBucketName, toPrefix) {
+			return nil, ""
+		}
 		toObjectName := toPrefix + strings.TrimPrefix(objectDetails.Name, directory.prefix)
-		operations = append(operations, directory.bucket.Object(objectDetails.Name).MoveTo(toBucketName, toObjectName))
-	}); err != nil {
-		return err
-	}
-	return directory.bucket.objectsManager.Batch(ctx, operations, nil)
+		return directory.bucket.Object(objectDetails.Name).MoveTo(toBucketName, toObjectName).Force(options.OverwritePolicy == OverwriteAlways), toObjectName
+	})
 }
 
 // 复制目录
 func (directory *Directory) CopyTo(ctx context.Context, toBucketName, toPrefix string) error {
+	return directory.CopyToWithOptions(ctx, toBucketName, toPrefix, nil)
+}
+
+// 复制目录，通过 options 指定覆盖策略、接收每个对象的操作结果并支持断点续传
+//
+// 目标目录位于当前目录之内时，目标目录下的对象将被跳过。
+func (directory *Directory) CopyToWithOptions(ctx context.Context, toBucketName, toPrefix string, options *DirectoryOperationOptions) error {
+	if options == nil {
+		options = &DirectoryOperationOptions{}
+	}
 	if !strings.HasSuffix(toPrefix, directory.pathSeparator) {
 		toPrefix += directory.pathSeparator
 	}
-	operations := make([]Operation, 0, 16)
-	if err := directory.forEachObject(ctx, func(objectDetails *ObjectDetails) {
+	return directory.doOperations(ctx, &resumablerecorder.ResumableRecorderOpenArgs{
+		Operation:    "copy",
+		BucketName:   directory.bucket.name,
+		Prefix:       directory.prefix,
+		ToBucketName: toBucketName,
+		ToPrefix:     toPrefix,
+	}, options, func(objectDetails *ObjectDetails) (Operation, string) {
+		if directory.isUnderToPrefix(objectDetails.Name, toBucketName, toPrefix) {
+			return nil, ""
+		}
 		toObjectName := toPrefix + strings.TrimPrefix(objectDetails.Name, directory.prefix)
-		operations = append(operations, directory.bucket.Object(objectDetails.Name).CopyTo(toBucketName, toObjectName))
-	}); err != nil {
-		return err
-	}
-	return directory.bucket.objectsManager.Batch(ctx, operations, nil)
+		return directory.bucket.Object(objectDetails.Name).CopyTo(toBucketName, toObjectName).Force(options.OverwritePolicy == OverwriteAlways), toObjectName
+	})
 }
 
 // 删除目录
 func (directory *Directory) Delete(ctx context.Context) error {
-	operations := make([]Operation, 0, 16)
-	if err := directory.forEachObject(ctx, func(objectDetails *ObjectDetails) {
-		operations = append(operations, directory.bucket.Object(objectDetails.Name).Delete())
-	}); err != nil {
-		return err
+	return directory.DeleteWithOptions(ctx, nil)
+}
+
+// 删除目录，通过 options 接收每个对象的操作结果并支持断点续传
+//
+// ObjectsManager 启用软删除时，对象将被移动到回收站，如果目录本身不在回收站中，则跳过目录下回收站中的对象。
+func (directory *Directory) DeleteWithOptions(ctx context.Context, options *DirectoryOperationOptions) error {
+	if options == nil {
+		options = &DirectoryOperationOptions{}
 	}
-	return directory.bucket.objectsManager.Batch(ctx, operations, nil)
+	var trash *Trash
+	if directory.bucket.objectsManager.softDelete != nil {
+		if trash = directory.bucket.Trash(nil); trash.Contains(directory.prefix) {
+			trash = nil
+		}
+	}
+	return directory.doOperations(ctx, &resumablerecorder.ResumableRecorderOpenArgs{
+		Operation:  "delete",
+		BucketName: directory.bucket.name,
+		Prefix:     directory.prefix,
+	}, options, func(objectDetails *ObjectDetails) (Operation, string) {
+		if trash != nil && trash.Contains(objectDetails.Name) {
+			return nil, ""
+		}
+		return directory.bucket.Object(objectDetails.Name).Delete(), ""
+	})
 }
 
-func (directory *Directory) forEachObject(ctx context.Context, each func(*ObjectDetails)) error {
-	lister := directory.bucket.List(ctx, &ListObjectsOptions{Prefix: directory.prefix})
-	defer lister.Close()
+func newDirectoryObjectResult(operation *bulkOperation, toObjectName string, overwritePolicy OverwritePolicy) *DirectoryObjectResult {
+	result := DirectoryObjectResult{ObjectName: operation.objectName, ToObjectName: toObjectName, Err: operation.result()}
+	var httpCodeErr interface{ HttpCode() int }
+	if result.Err != nil && overwritePolicy == OverwriteSkip && toObjectName != "" &&
+		errors.As(result.Err, &httpCodeErr) && httpCodeErr.HttpCode() == statusCodeObjectExists {
+		result.Skipped, result.Err = true, nil
+	}
+	return &result
+}
 
-	var objectDetails ObjectDetails
-	for lister.Next(&objectDetails) {
-		each(&objectDetails)
+// 目标目录位于当前目录之内时，边列举边操作会再次列举到已经操作过的对象，因此跳过目标目录下的对象
+func (directory *Directory) isUnderToPrefix(objectName, toBucketName, toPrefix string) bool {
+	return toBucketName == directory.bucket.name && strings.HasPrefix(toPrefix, directory.prefix) && strings.HasPrefix(objectName, toPrefix)
+}
+
+// 边列举边分批执行操作，每批操作完成后回调每个对象的结果，并将列举的位置标记写入可恢复记录
+func (directory *Directory) doOperations(ctx context.Context, args *resumablerecorder.ResumableRecorderOpenArgs, options *DirectoryOperationOptions, newOperation func(*ObjectDetails) (Operation, string)) error {
+	batchSize := options.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	var (
+		record   resumablerecorder.ResumableRecord
+		recorder = options.ResumableRecorder
+		medium   resumablerecorder.WriteableResumableRecorderMedium
+	)
+	if recorder != nil {
+		if readableMedium := recorder.OpenForReading(args); readableMedium != nil {
+			for {
+				var r resumablerecorder.ResumableRecord
+				if err := readableMedium.Next(&r); err != nil {
+					break
+				}
+				record = r
+			}
+			readableMedium.Close()
+			medium = recorder.OpenForAppending(args)
+		}
+		if medium == nil {
+			record = resumablerecorder.ResumableRecord{}
+			medium = recorder.OpenForCreatingNew(args)
+		}
+		defer func() {
+			if medium != nil {
+				medium.Close()
+			}
+		}()
+	}
+
+	var (
+		operations    = make([]*bulkOperation, 0, batchSize)
+		toObjectNames = make([]string, 0, batchSize)
+		pageMarker    = record.Marker
+	)
+	flush := func() error {
+		if len(operations) == 0 {
+			return nil
+		}
+		ops := make([]Operation, len(operations))
+		for i, operation := range operations {
+			ops[i] = operation
+		}
+		if err := directory.bucket.objectsManager.Batch(ctx, ops, &BatchOptions{BatchOpsExecutor: options.BatchOpsExecutor}); err != nil {
+			return err
+		}
+		if options.OnObjectDone != nil {
+			for i, operation := range operations {
+				options.OnObjectDone(newDirectoryObjectResult(operation, toObjectNames[i], options.OverwritePolicy))
+			}
+		}
+		record.Marker = pageMarker
+		record.LastObjectName = operations[len(operations)-1].objectName
+		record.Completed += uint64(len(operations))
+		if medium != nil {
+			medium.Write(&record)
+		}
+		operations, toObjectNames = operations[:0], toObjectNames[:0]
+		return nil
 	}
 
-	return lister.Error()
+	lister := directory.bucket.List(ctx, &ListObjectsOptions{Prefix: directory.prefix, Marker: record.Marker})
+	defer lister.Close()
+	var objectDetails ObjectDetails
+	for {
+		marker := lister.Marker()
+		if !lister.Next(&objectDetails) {
+			break
+		}
+		// 位置标记变化说明列举器请求了新的一页，恢复时从这一页重新列举并跳过已经完成的对象
+		if lister.Marker() != marker {
+			pageMarker = marker
+		}
+		if record.LastObjectName != "" && objectDetails.Name <= record.LastObjectName {
+			continue
+		}
+		operation, toObjectName := newOperation(&objectDetails)
+		if operation == nil {
+			continue
+		}
+		operations = append(operations, newBulkOperation(operation, objectDetails.Name))
+		toObjectNames = append(toObjectNames, toObjectName)
+		if len(operations) >= batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := lister.Error(); err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	if recorder != nil {
+		if medium != nil {
+			medium.Close()
+			medium = nil
+		}
+		recorder.Delete(args)
+	}
+	return nil
 }
 
 // 列举目录条目
@@ -5,17 +5,23 @@ package objects_test
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/qiniu/go-sdk/v7/storagev2/apis/batch_ops"
 	"github.com/qiniu/go-sdk/v7/storagev2/apis/get_objects"
 	"github.com/qiniu/go-sdk/v7/storagev2/credentials"
 	"github.com/qiniu/go-sdk/v7/storagev2/http_client"
 	"github.com/qiniu/go-sdk/v7/storagev2/objects"
+	resumablerecorder "github.com/qiniu/go-sdk/v7/storagev2/objects/resumable_recorder"
 	"github.com/qiniu/go-sdk/v7/storagev2/region"
 )
 
@@ -436,3 +442,220 @@ func TestDirectoryListEntriesWithSkipDir(t *testing.T) {
 		t.Fatalf("unexpected object file1")
 	}
 }
+
+func TestDirectoryMoveToWithOptions(t *testing.T) {
+	objectNames := []string{"logs/a", "logs/b", "logs/c", "logs/d", "logs/e"}
+	var (
+		batchCalls int
+		moved      []string
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/list", func(w http.ResponseWriter, r *http.Request) {
+		const pageSize = 2
+		offset := 0
+		if marker := r.URL.Query().Get("marker"); marker != "" {
+			var err error
+			if offset, err = strconv.Atoi(marker); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var response get_objects.Response
+		for _, objectName := range objectNames[offset:min(offset+pageSize, len(objectNames))] {
+			response.Items = append(response.Items, get_objects.ListedObjectEntry{
+				Key:      objectName,
+				PutTime:  time.Now().UnixNano() / 100,
+				Hash:     "testhash",
+				Size:     1,
+				MimeType: "text/plain",
+			})
+		}
+		if offset+pageSize < len(objectNames) {
+			response.Marker = strconv.Itoa(offset + pageSize)
+		}
+		jsonData, err := json.Marshal(&response)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("X-ReqId", "fakereqid")
+		w.Write(jsonData)
+	})
+	mux.HandleFunc("/batch", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("X-ReqId", "fakereqid")
+		batchCalls += 1
+		if batchCalls == 2 {
+			// 模拟第二批操作时中断
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"interrupted"}`))
+			return
+		}
+		responses := make([]batch_ops.OperationResponse, 0, len(r.PostForm["op"]))
+		for _, op := range r.PostForm["op"] {
+			parts := strings.Split(op, "/")
+			if parts[0] != "move" || len(parts) != 3 {
+				t.Fatalf("unexpected op: %s", op)
+			}
+			from, err := base64.URLEncoding.DecodeString(parts[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			to, err := base64.URLEncoding.DecodeString(parts[2])
+			if err != nil {
+				t.Fatal(err)
+			}
+			moved = append(moved, string(from))
+			if string(to) == "bucket2:archive/d" {
+				responses = append(responses, batch_ops.OperationResponse{Code: 614, Data: batch_ops.OperationResponseData{Error: "file exists"}})
+			} else {
+				responses = append(responses, batch_ops.OperationResponse{Code: 200})
+			}
+		}
+		jsonData, err := json.Marshal(&batch_ops.Response{OperationResponses: responses})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(jsonData)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tmpDir, err := os.MkdirTemp("", "test-directory-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	objectsManager := objects.NewObjectsManager(&objects.ObjectsManagerOptions{
+		Options: http_client.Options{
+			Credentials: credentials.NewCredentials("testak", "testsk"),
+			Regions: &region.Region{
+				Rs:  region.Endpoints{Preferred: []string{server.URL}},
+				Rsf: region.Endpoints{Preferred: []string{server.URL}},
+			},
+		},
+	})
+	directory := objectsManager.Bucket("bucket1").Directory("logs/", "/")
+
+	var results []string
+	recorder := resumablerecorder.NewJsonFileSystemResumableRecorder(tmpDir)
+	options := objects.DirectoryOperationOptions{
+		OverwritePolicy:   objects.OverwriteSkip,
+		BatchSize:         2,
+		BatchOpsExecutor:  objects.NewSerialBatchOpsExecutor(nil),
+		ResumableRecorder: recorder,
+		OnObjectDone: func(result *objects.DirectoryObjectResult) {
+			if result.Err != nil {
+				t.Fatalf("unexpected error of %s: %v", result.ObjectName, result.Err)
+			}
+			s := result.ObjectName + "->" + result.ToObjectName
+			if result.Skipped {
+				s += "(skipped)"
+			}
+			results = append(results, s)
+		},
+	}
+	if err = directory.MoveToWithOptions(context.Background(), "bucket2", "archive", &options); err == nil {
+		t.Fatalf("move should be interrupted")
+	}
+	if strings.Join(results, ",") != "logs/a->archive/a,logs/b->archive/b" {
+		t.Fatalf("unexpected results: %v", results)
+	}
+
+	if err = directory.MoveToWithOptions(context.Background(), "bucket2", "archive", &options); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(results, ",") != "logs/a->archive/a,logs/b->archive/b,logs/c->archive/c,logs/d->archive/d(skipped),logs/e->archive/e" {
+		t.Fatalf("unexpected results: %v", results)
+	}
+	if strings.Join(moved, ",") != "bucket1:logs/a,bucket1:logs/b,bucket1:logs/c,bucket1:logs/d,bucket1:logs/e" {
+		t.Fatalf("unexpected moved objects: %v", moved)
+	}
+	if medium := recorder.OpenForReading(&resumablerecorder.ResumableRecorderOpenArgs{
+		Operation:    "move",
+		BucketName:   "bucket1",
+		Prefix:       "logs/",
+		ToBucketName: "bucket2",
+		ToPrefix:     "archive/",
+	}); medium != nil {
+		medium.Close()
+		t.Fatalf("resumable record should be deleted")
+	}
+}
+
+func TestDirectoryCopyToNestedPrefix(t *testing.T) {
+	var copied []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/list", func(w http.ResponseWriter, r *http.Request) {
+		var response get_objects.Response
+		// 目标目录下已经存在的对象也会被列举到
+		for _, objectName := range []string{"logs/a", "logs/sub/a", "logs/sub/b", "logs/subdir/c"} {
+			response.Items = append(response.Items, get_objects.ListedObjectEntry{
+				Key:      objectName,
+				PutTime:  time.Now().UnixNano() / 100,
+				Hash:     "testhash",
+				Size:     1,
+				MimeType: "text/plain",
+			})
+		}
+		jsonData, err := json.Marshal(&response)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("X-ReqId", "fakereqid")
+		w.Write(jsonData)
+	})
+	mux.HandleFunc("/batch", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		responses := make([]batch_ops.OperationResponse, 0, len(r.PostForm["op"]))
+		for _, op := range r.PostForm["op"] {
+			parts := strings.Split(op, "/")
+			if parts[0] != "copy" || len(parts) != 3 {
+				t.Fatalf("unexpected op: %s", op)
+			}
+			from, err := base64.URLEncoding.DecodeString(parts[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			to, err := base64.URLEncoding.DecodeString(parts[2])
+			if err != nil {
+				t.Fatal(err)
+			}
+			copied = append(copied, string(from)+"->"+string(to))
+			responses = append(responses, batch_ops.OperationResponse{Code: 200})
+		}
+		jsonData, err := json.Marshal(&batch_ops.Response{OperationResponses: responses})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("X-ReqId", "fakereqid")
+		w.Write(jsonData)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	objectsManager := objects.NewObjectsManager(&objects.ObjectsManagerOptions{
+		Options: http_client.Options{
+			Credentials: credentials.NewCredentials("testak", "testsk"),
+			Regions: &region.Region{
+				Rs:  region.Endpoints{Preferred: []string{server.URL}},
+				Rsf: region.Endpoints{Preferred: []string{server.URL}},
+			},
+		},
+	})
+	directory := objectsManager.Bucket("bucket1").Directory("logs/", "/")
+	if err := directory.CopyTo(context.Background(), "bucket1", "logs/sub"); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(copied)
+	if strings.Join(copied, ",") != "bucket1:logs/a->bucket1:logs/sub/a,bucket1:logs/subdir/c->bucket1:logs/sub/subdir/c" {
+		t.Fatalf("unexpected copied objects: %v", copied)
+	}
+}
@@ -34,6 +34,17 @@
 //	// 归档恢复
 //	err := obj.Restore(7).Call(ctx)
 //
+// # 前置条件
+//
+// 复制和移动可以通过 IfNotExists 要求目标对象不存在，修改元信息可以附加条件，条件不满足时返回 errors.PreconditionFailedError：
+//
+//	err := obj.CopyTo("target-bucket", "new-name.txt").IfNotExists().Call(ctx)
+//	err := obj.SetMetadata("application/json").IfMatchETag(etag).Call(ctx)
+//	var preconditionErr errors.PreconditionFailedError
+//	if errors.As(err, &preconditionErr) {
+//	    // 对象已被其他写入者修改
+//	}
+//
 // # 列举对象
 //
 //	lister := bucket.List(ctx, &objects.ListObjectsOptions{Prefix: "images/"})
@@ -46,6 +57,12 @@
 //	    // 处理错误
 //	}
 //
+// 对于包含大量对象的空间，可以通过 [Bucket.ListParallel] 按照公共前缀或显式指定的 [KeyRange] 划分键空间并行列举，
+// 设置 Ordered 后仍按照对象名称升序输出：
+//
+//	lister := bucket.ListParallel(ctx, &objects.ParallelListObjectsOptions{Depth: 2, Concurrency: 16, Ordered: true})
+//	defer lister.Close()
+//
 // # 批量操作
 //
 // 将多个 [Operation] 收集后批量执行：
@@ -55,6 +72,17 @@
 //	ops = append(ops, bucket.Object("b.txt").Delete())
 //	err := objectsManager.Batch(ctx, ops, &objects.BatchOptions{})
 //
+// # 批量筛选操作
+//
+// 通过 [Bucket.Bulk] 列举对象，按照 [BulkFilter] 筛选后批量执行操作，并返回统计报告：
+//
+//	report, err := bucket.Bulk(ctx, objects.BulkMove("archive-bucket", "2024/{name}", false), &objects.BulkOptions{
+//	    Filter: &objects.BulkFilter{Prefix: "logs/", Glob: "logs/*.log", UploadedBefore: deadline},
+//	})
+//	for _, bulkErr := range report.Errors {
+//	    fmt.Println(bulkErr.ObjectName, bulkErr.Err)
+//	}
+//
 // # 目录操作
 //
 // 通过 [Directory] 批量操作同前缀的对象：
@@ -63,6 +91,38 @@
 //	err := dir.Delete(ctx)
 //	err := dir.CopyTo(ctx, "backup-bucket", "logs-backup/")
 //
+// 目录操作边列举边分批执行，通过 [DirectoryOperationOptions] 可以指定覆盖策略、接收每个对象的操作结果，
+// 并通过可恢复记录仪记录列举的位置，中断后再次执行相同的目录操作将从记录的位置继续：
+//
+//	err := dir.MoveToWithOptions(ctx, "archive-bucket", "logs/", &objects.DirectoryOperationOptions{
+//	    OverwritePolicy:   objects.OverwriteSkip,
+//	    ResumableRecorder: resumablerecorder.NewJsonFileSystemResumableRecorder("/tmp/recorder"),
+//	    OnObjectDone: func(result *objects.DirectoryObjectResult) {
+//	        if result.Err != nil {
+//	            fmt.Println(result.ObjectName, result.Err)
+//	        }
+//	    },
+//	})
+//
+// # 软删除
+//
+// 通过 [ObjectsManagerOptions] 的 SoftDelete 启用软删除后，删除操作将对象移动到回收站前缀下以删除时间为版本的对象，
+// 设置删除时间元数据，并在保留天数到期后被自动删除。同一对象多次删除时保留每个版本。
+// 通过 [Trash] 可以列举、恢复或者彻底删除回收站中的对象：
+//
+//	objectsManager := objects.NewObjectsManager(&objects.ObjectsManagerOptions{
+//	    Options:    http_client.Options{Credentials: cred},
+//	    SoftDelete: &objects.SoftDeleteOptions{TrashPrefix: ".trash/", RetentionDays: 30},
+//	})
+//	bucket := objectsManager.Bucket("my-bucket")
+//	err := bucket.Object("a.txt").Delete().Call(ctx)
+//	err = bucket.Trash(nil).List(ctx, nil, func(object *objects.TrashedObject) error {
+//	    fmt.Println(object.Name, object.Version, object.DeletedAt)
+//	    return nil
+//	})
+//	// 版本为空时恢复最新删除的版本
+//	err = bucket.Trash(nil).Restore(ctx, "a.txt", "", false)
+//
 // # 存储类型
 //
 //   - [StandardStorageClass]: 标准存储
@@ -29,8 +29,11 @@ func (object *Object) CopyTo(toBucketName, toObjectName string) *CopyObjectOpera
 	}
 }
 
-// 删除对象
+// 删除对象，ObjectsManager 启用软删除时，对象将被移动到回收站
 func (object *Object) Delete() *DeleteObjectOperation {
+	if object.bucket.objectsManager.softDelete != nil {
+		return object.bucket.Trash(nil).SoftDelete(object.name)
+	}
 	return &DeleteObjectOperation{
 		object: *object,
 	}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	goerrors "errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -15,6 +16,7 @@ import (
 
 	"github.com/qiniu/go-sdk/v7/storagev2/apis/stat_object"
 	"github.com/qiniu/go-sdk/v7/storagev2/credentials"
+	"github.com/qiniu/go-sdk/v7/storagev2/errors"
 	"github.com/qiniu/go-sdk/v7/storagev2/http_client"
 	"github.com/qiniu/go-sdk/v7/storagev2/objects"
 	"github.com/qiniu/go-sdk/v7/storagev2/region"
@@ -350,3 +352,56 @@ func TestObjectSetLifeCycle(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestObjectPreconditions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Add("X-ReqId", "fakereqid")
+		rw.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/copy/"):
+			rw.WriteHeader(614)
+			rw.Write([]byte(`{"error":"file exists"}`))
+		case strings.HasPrefix(r.URL.Path, "/chgm/"):
+			if !strings.HasSuffix(r.URL.Path, "/cond/"+base64.URLEncoding.EncodeToString([]byte("hash=staleetag"))) {
+				t.Fatalf("unexpected path: %s", r.URL.Path)
+			}
+			rw.WriteHeader(412)
+			rw.Write([]byte(`{"error":"precondition failed"}`))
+		case r.URL.Path == "/batch":
+			rw.Write([]byte(`[{"code":614,"data":{"error":"file exists"}}]`))
+		default:
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	objectsManager := objects.NewObjectsManager(&objects.ObjectsManagerOptions{
+		Options: http_client.Options{
+			Credentials: credentials.NewCredentials("testak", "testsk"),
+			Regions:     &region.Region{Rs: region.Endpoints{Preferred: []string{server.URL}}},
+		},
+	})
+	object := objectsManager.Bucket("bucket1").Object("testobject")
+
+	var preconditionErr errors.PreconditionFailedError
+	// 没有设置前置条件时原样返回服务器的错误
+	if err := object.CopyTo("bucket2", "testobject").Call(context.Background()); err == nil || goerrors.As(err, &preconditionErr) {
+		t.Fatalf("server error is expected, got %v", err)
+	}
+	if err := object.CopyTo("bucket2", "testobject").IfNotExists().Call(context.Background()); !goerrors.As(err, &preconditionErr) || preconditionErr.Condition != "IfNotExists" {
+		t.Fatalf("PreconditionFailedError is expected, got %v", err)
+	}
+	if err := object.SetMetadata("application/json").IfMatchETag("staleetag").Call(context.Background()); !goerrors.As(err, &preconditionErr) || preconditionErr.Condition != "Conditions" {
+		t.Fatalf("PreconditionFailedError is expected, got %v", err)
+	}
+
+	var batchErr error
+	if err := objectsManager.Batch(context.Background(), []objects.Operation{
+		object.CopyTo("bucket2", "testobject").IfNotExists().OnError(func(err error) { batchErr = err }),
+	}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !goerrors.As(batchErr, &preconditionErr) || batchErr.Error() != "precondition `IfNotExists` failed: file exists" {
+		t.Fatalf("PreconditionFailedError is expected, got %v", batchErr)
+	}
+}
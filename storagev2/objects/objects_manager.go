@@ -14,6 +14,7 @@ type (
 		options          httpclient.Options
 		listerVersion    ListerVersion
 		batchOpsExecutor BatchOpsExecutor
+		softDelete       *SoftDeleteOptions
 	}
 
 	// 对象管理器选项
@@ -26,6 +27,9 @@ type (
 
 		// 批处理执行器，如果不填写，默认为串型批处理执行器
 		BatchOpsExecutor BatchOpsExecutor
+
+		// 软删除选项，如果填写，则对象的删除操作将对象移动到回收站，而不是直接删除
+		SoftDelete *SoftDeleteOptions
 	}
 
 	// 批处理选项
@@ -49,6 +53,7 @@ func NewObjectsManager(options *ObjectsManagerOptions) *ObjectsManager {
 		options:          options.Options,
 		listerVersion:    options.ListerVersion,
 		batchOpsExecutor: batchOpsExecutor,
+		softDelete:       options.SoftDelete,
 	}
 }
 
@@ -69,5 +74,11 @@ func (objectsManager *ObjectsManager) Batch(ctx context.Context, operations []Op
 	if batchOpsExecutor == nil {
 		batchOpsExecutor = objectsManager.batchOpsExecutor
 	}
-	return batchOpsExecutor.ExecuteBatchOps(ctx, operations, objectsManager.storage)
+	err := batchOpsExecutor.ExecuteBatchOps(ctx, operations, objectsManager.storage)
+	if softDeletes := softDeleteOperationsOf(operations); len(softDeletes) > 0 {
+		if finishErr := finishSoftDeletes(ctx, objectsManager, softDeletes, err); err == nil {
+			err = finishErr
+		}
+	}
+	return err
 }
@@ -39,27 +39,33 @@ type (
 
 	// 移动对象操作
 	MoveObjectOperation struct {
-		fromObject Object
-		toObject   entry
-		force      bool
-		onResponse func()
-		onError    func(error)
+		fromObject  Object
+		toObject    entry
+		force       bool
+		ifNotExists bool
+		onResponse  func()
+		onError     func(error)
 	}
 
 	// 复制对象操作
 	CopyObjectOperation struct {
-		fromObject Object
-		toObject   entry
-		force      bool
-		onResponse func()
-		onError    func(error)
+		fromObject  Object
+		toObject    entry
+		force       bool
+		ifNotExists bool
+		onResponse  func()
+		onError     func(error)
 	}
 
 	// 删除对象操作
 	DeleteObjectOperation struct {
-		object     Object
-		onResponse func()
-		onError    func(error)
+		object           Object
+		onResponse       func()
+		onError          func(error)
+		trash            *Trash
+		trashName        string
+		deletedAt        time.Time
+		softDeleteResult *softDeleteResult
 	}
 
 	// 解冻对象操作
@@ -227,6 +233,14 @@ func (operation *MoveObjectOperation) Force(force bool) *MoveObjectOperation {
 	return &copy
 }
 
+// 仅当目标对象不存在时移动，目标对象已存在时返回 errors.PreconditionFailedError，会覆盖 Force 的设置
+func (operation *MoveObjectOperation) IfNotExists() *MoveObjectOperation {
+	copy := *operation
+	copy.force = false
+	copy.ifNotExists = true
+	return &copy
+}
+
 func (operation *MoveObjectOperation) OnResponse(fn func()) *MoveObjectOperation {
 	copy := *operation
 	copy.onResponse = fn
@@ -244,6 +258,7 @@ func (operation *MoveObjectOperation) relatedEntries() []entry {
 }
 
 func (operation *MoveObjectOperation) handleResponse(_ *ObjectDetails, err error) {
+	err = operation.wrapError(err)
 	if err != nil && operation.onError != nil {
 		operation.onError(err)
 	} else if operation.onResponse != nil {
@@ -268,7 +283,15 @@ func (operation *MoveObjectOperation) Call(ctx context.Context) error {
 		OverwrittenBucketName: operation.fromObject.bucket.name,
 	})
 	operation.handleResponse(nil, err)
-	return err
+	return operation.wrapError(err)
+}
+
+// 设置了 IfNotExists 时，目标对象已存在将返回 errors.PreconditionFailedError，否则原样返回服务器的错误
+func (operation *MoveObjectOperation) wrapError(err error) error {
+	if operation.force || !operation.ifNotExists {
+		return err
+	}
+	return wrapPreconditionError(err, "IfNotExists", statusCodeObjectExists)
 }
 
 var _ Operation = (*MoveObjectOperation)(nil)
@@ -279,6 +302,14 @@ func (operation *CopyObjectOperation) Force(force bool) *CopyObjectOperation {
 	return &copy
 }
 
+// 仅当目标对象不存在时复制，目标对象已存在时返回 errors.PreconditionFailedError，会覆盖 Force 的设置
+func (operation *CopyObjectOperation) IfNotExists() *CopyObjectOperation {
+	copy := *operation
+	copy.force = false
+	copy.ifNotExists = true
+	return &copy
+}
+
 func (operation *CopyObjectOperation) OnResponse(fn func()) *CopyObjectOperation {
 	copy := *operation
 	copy.onResponse = fn
@@ -296,6 +327,7 @@ func (operation *CopyObjectOperation) relatedEntries() []entry {
 }
 
 func (operation *CopyObjectOperation) handleResponse(_ *ObjectDetails, err error) {
+	err = operation.wrapError(err)
 	if err != nil && operation.onError != nil {
 		operation.onError(err)
 	} else if operation.onResponse != nil {
@@ -320,7 +352,15 @@ func (operation *CopyObjectOperation) Call(ctx context.Context) error {
 		OverwrittenBucketName: operation.fromObject.bucket.name,
 	})
 	operation.handleResponse(nil, err)
-	return err
+	return operation.wrapError(err)
+}
+
+// 设置了 IfNotExists 时，目标对象已存在将返回 errors.PreconditionFailedError，否则原样返回服务器的错误
+func (operation *CopyObjectOperation) wrapError(err error) error {
+	if operation.force || !operation.ifNotExists {
+		return err
+	}
+	return wrapPreconditionError(err, "IfNotExists", statusCodeObjectExists)
 }
 
 var _ Operation = (*CopyObjectOperation)(nil)
@@ -328,16 +368,31 @@ var _ Operation = (*CopyObjectOperation)(nil)
 func (operation *DeleteObjectOperation) OnResponse(fn func()) *DeleteObjectOperation {
 	copy := *operation
 	copy.onResponse = fn
+	if copy.trash != nil {
+		copy.softDeleteResult = new(softDeleteResult)
+	}
 	return &copy
 }
 
 func (operation *DeleteObjectOperation) OnError(fn func(error)) *DeleteObjectOperation {
 	copy := *operation
 	copy.onError = fn
+	if copy.trash != nil {
+		copy.softDeleteResult = new(softDeleteResult)
+	}
 	return &copy
 }
 
 func (operation *DeleteObjectOperation) handleResponse(_ *ObjectDetails, err error) {
+	// 软删除仅在移动到回收站后记录结果，待设置删除时间和生命周期后再回调
+	if operation.trash != nil {
+		operation.softDeleteResult.set(err)
+		return
+	}
+	operation.finishSoftDelete(err)
+}
+
+func (operation *DeleteObjectOperation) finishSoftDelete(err error) {
 	if err != nil && operation.onError != nil {
 		operation.onError(err)
 	} else if operation.onResponse != nil {
@@ -345,15 +400,40 @@ func (operation *DeleteObjectOperation) handleResponse(_ *ObjectDetails, err err
 	}
 }
 
+func (operation *DeleteObjectOperation) trashObject() *Object {
+	return operation.trash.bucket.Object(operation.trashName)
+}
+
 func (operation *DeleteObjectOperation) relatedEntries() []entry {
-	return []entry{{operation.object.bucket.name, operation.object.name}}
+	entries := []entry{{operation.object.bucket.name, operation.object.name}}
+	if operation.trash != nil {
+		entries = append(entries, entry{operation.trash.bucket.name, operation.trashName})
+	}
+	return entries
 }
 
 func (operation *DeleteObjectOperation) String() string {
+	if operation.trash != nil {
+		return "move/" + operation.object.encode() + "/" + operation.trashObject().encode()
+	}
 	return "delete/" + operation.object.encode()
 }
 
 func (operation *DeleteObjectOperation) Call(ctx context.Context) error {
+	if operation.trash != nil {
+		_, err := operation.object.bucket.objectsManager.storage.MoveObject(ctx, &apis.MoveObjectRequest{
+			SrcEntry:  operation.object.String(),
+			DestEntry: operation.trashObject().String(),
+		}, &apis.Options{
+			OverwrittenBucketName: operation.object.bucket.name,
+		})
+		operation.handleResponse(nil, err)
+		if err = finishSoftDeletes(ctx, operation.object.bucket.objectsManager, []*DeleteObjectOperation{operation}, nil); err != nil {
+			return err
+		}
+		_, err = operation.softDeleteResult.get()
+		return err
+	}
 	_, err := operation.object.bucket.objectsManager.storage.DeleteObject(ctx, &apis.DeleteObjectRequest{
 		Entry: operation.object.String(),
 	}, &apis.Options{
@@ -500,6 +580,17 @@ func (operation *SetObjectMetadataOperation) Conditions(conds map[string]string)
 	return &copy
 }
 
+// 仅当对象当前的 Etag 与之相同时修改，条件不匹配时返回 errors.PreconditionFailedError
+func (operation *SetObjectMetadataOperation) IfMatchETag(etag string) *SetObjectMetadataOperation {
+	copy := *operation
+	copy.conditions = make(map[string]string, len(operation.conditions)+1)
+	for k, v := range operation.conditions {
+		copy.conditions[k] = v
+	}
+	copy.conditions["hash"] = etag
+	return &copy
+}
+
 func (operation *SetObjectMetadataOperation) OnResponse(fn func()) *SetObjectMetadataOperation {
 	copy := *operation
 	copy.onResponse = fn
@@ -517,6 +608,7 @@ func (operation *SetObjectMetadataOperation) relatedEntries() []entry {
 }
 
 func (operation *SetObjectMetadataOperation) handleResponse(_ *ObjectDetails, err error) {
+	err = operation.wrapError(err)
 	if err != nil && operation.onError != nil {
 		operation.onError(err)
 	} else if operation.onResponse != nil {
@@ -557,7 +649,14 @@ func (operation *SetObjectMetadataOperation) Call(ctx context.Context) error {
 		OverwrittenBucketName: operation.object.bucket.name,
 	})
 	operation.handleResponse(nil, err)
-	return err
+	return operation.wrapError(err)
+}
+
+func (operation *SetObjectMetadataOperation) wrapError(err error) error {
+	if len(operation.conditions) == 0 {
+		return err
+	}
+	return wrapPreconditionError(err, "Conditions", statusCodePreconditionFailed)
 }
 
 var _ Operation = (*SetObjectMetadataOperation)(nil)
@@ -0,0 +1,338 @@
+package objects
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/qiniu/go-sdk/v7/storagev2/apis"
+)
+
+type (
+	// 并行列举选项
+	ParallelListObjectsOptions struct {
+		// 前缀，仅列举该前缀下的对象
+		Prefix string
+
+		// 显式指定的键范围，如果填写，则直接按照键范围并行列举，不再通过公共前缀划分键空间
+		KeyRanges []KeyRange
+
+		// 划分键空间时使用的目录分隔符，默认为 /
+		Delimiter string
+
+		// 划分键空间的目录层数，默认为 1，即仅按照前缀下第一层公共前缀划分
+		Depth int
+
+		// 并行列举的数量，默认为 8
+		Concurrency int
+
+		// 每个列举流缓存的对象数量，默认为 1000
+		BufferSize int
+
+		// 是否按照对象名称升序输出，默认为按照列举完成的顺序输出
+		Ordered bool
+
+		// 是否需要分片信息
+		NeedParts bool
+	}
+
+	// 键范围
+	KeyRange struct {
+		// 前缀，仅列举该前缀下的对象
+		Prefix string
+
+		// 起始位置标记，从该位置开始列举，可以使用之前列举时通过 Lister.Marker 获取的位置标记
+		Marker string
+
+		// 起始对象名称（包含），为空表示不限制，与 Marker 同时填写时以 Marker 作为列举的起始位置
+		Start string
+
+		// 结束对象名称（不包含），为空表示不限制
+		End string
+	}
+
+	parallelLister struct {
+		ctx      context.Context
+		cancel   context.CancelFunc
+		segments chan chan ObjectDetails
+		current  chan ObjectDetails
+		wg       sync.WaitGroup
+		errLock  sync.Mutex
+		err      error
+		closed   bool
+	}
+
+	listSegment struct {
+		keyRange KeyRange
+		objects  []ObjectDetails
+		static   bool
+	}
+
+	listJob struct {
+		segment *listSegment
+		output  chan ObjectDetails
+	}
+)
+
+// 并行列举对象
+//
+// 通过目录分隔符列举公共前缀划分键空间，或者直接使用显式指定的键范围，对每个前缀或键范围分别使用 Bucket.List 列举，再合并为一个列举器。
+// 有序输出时，各个前缀或键范围按照对象名称的顺序依次输出，显式指定的键范围必须按照升序排列且互不重叠；无序输出时，先列举到的对象先输出。
+// 直接位于前缀下（不属于任何公共前缀）的对象在划分键空间时列举，对于没有目录结构的空间，建议显式指定键范围。
+// 并行列举器不支持断点续传，Marker 始终返回空字符串。
+func (bucket *Bucket) ListParallel(ctx context.Context, options *ParallelListObjectsOptions) Lister {
+	if options == nil {
+		options = &ParallelListObjectsOptions{}
+	}
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = 8
+	}
+	bufferSize := options.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = 1000
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	lister := &parallelLister{ctx: ctx, cancel: cancel, segments: make(chan chan ObjectDetails, concurrency)}
+	lister.wg.Add(1)
+	go func() {
+		defer lister.wg.Done()
+		lister.dispatch(bucket, options, concurrency, bufferSize)
+	}()
+	return lister
+}
+
+func (l *parallelLister) dispatch(bucket *Bucket, options *ParallelListObjectsOptions, concurrency, bufferSize int) {
+	defer close(l.segments)
+
+	var (
+		workers sync.WaitGroup
+		jobs    = make(chan listJob)
+		shared  chan ObjectDetails
+	)
+	if !options.Ordered {
+		shared = make(chan ObjectDetails, bufferSize)
+		l.segments <- shared
+	}
+	for i := 0; i < concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for job := range jobs {
+				l.listSegment(bucket, job.segment, job.output, options.NeedParts)
+				if options.Ordered {
+					close(job.output)
+				}
+			}
+		}()
+	}
+	defer func() {
+		close(jobs)
+		workers.Wait()
+		if shared != nil {
+			close(shared)
+		}
+	}()
+
+	emit := func(segment *listSegment) bool {
+		output := shared
+		if output == nil {
+			output = make(chan ObjectDetails, bufferSize)
+			select {
+			case l.segments <- output:
+			case <-l.ctx.Done():
+				l.setError(l.ctx.Err())
+				return false
+			}
+		}
+		select {
+		case jobs <- listJob{segment, output}:
+			return true
+		case <-l.ctx.Done():
+			if options.Ordered {
+				close(output)
+			}
+			l.setError(l.ctx.Err())
+			return false
+		}
+	}
+
+	if len(options.KeyRanges) > 0 {
+		for _, keyRange := range options.KeyRanges {
+			if !emit(&listSegment{keyRange: keyRange}) {
+				return
+			}
+		}
+		return
+	}
+	delimiter := options.Delimiter
+	if delimiter == "" {
+		delimiter = "/"
+	}
+	depth := options.Depth
+	if depth <= 0 {
+		depth = 1
+	}
+	if err := l.discover(bucket, options.Prefix, delimiter, depth, options.NeedParts, emit); err != nil {
+		l.setError(err)
+	}
+}
+
+// 通过目录分隔符列举 prefix 下的公共前缀，按照对象名称的顺序生成列举段
+func (l *parallelLister) discover(bucket *Bucket, prefix, delimiter string, depth int, needParts bool, emit func(*listSegment) bool) error {
+	request := apis.GetObjectsRequest{
+		Bucket:    bucket.name,
+		Prefix:    prefix,
+		Delimiter: delimiter,
+		NeedParts: needParts,
+	}
+	for {
+		response, err := bucket.objectsManager.storage.GetObjects(l.ctx, &request, nil)
+		if err != nil {
+			return err
+		}
+		commonPrefixes := append([]string(nil), response.CommonPrefixes...)
+		sort.Strings(commonPrefixes)
+		items := response.Items
+		for len(items) > 0 || len(commonPrefixes) > 0 {
+			if len(commonPrefixes) == 0 || (len(items) > 0 && items[0].Key < commonPrefixes[0]) {
+				segment := listSegment{static: true}
+				for len(items) > 0 && (len(commonPrefixes) == 0 || items[0].Key < commonPrefixes[0]) {
+					var object ObjectDetails
+					if err = object.fromListedObjectEntry(&items[0]); err != nil {
+						return err
+					}
+					segment.objects = append(segment.objects, object)
+					items = items[1:]
+				}
+				if !emit(&segment) {
+					return nil
+				}
+				continue
+			}
+			commonPrefix := commonPrefixes[0]
+			commonPrefixes = commonPrefixes[1:]
+			if depth > 1 {
+				if err = l.discover(bucket, commonPrefix, delimiter, depth-1, needParts, emit); err != nil {
+					return err
+				}
+			} else if !emit(&listSegment{keyRange: KeyRange{Prefix: commonPrefix}}) {
+				return nil
+			}
+		}
+		if response.Marker == "" {
+			return nil
+		}
+		request.Marker = response.Marker
+	}
+}
+
+func (l *parallelLister) listSegment(bucket *Bucket, segment *listSegment, output chan<- ObjectDetails, needParts bool) {
+	send := func(object ObjectDetails) bool {
+		select {
+		case output <- object:
+			return true
+		case <-l.ctx.Done():
+			l.setError(l.ctx.Err())
+			return false
+		}
+	}
+	if segment.static {
+		for _, object := range segment.objects {
+			if !send(object) {
+				return
+			}
+		}
+		return
+	}
+
+	marker := segment.keyRange.Marker
+	if marker == "" {
+		marker = markerBefore(segment.keyRange.Start)
+	}
+	lister := bucket.List(l.ctx, &ListObjectsOptions{Prefix: segment.keyRange.Prefix, Marker: marker, NeedParts: needParts})
+	defer lister.Close()
+	var object ObjectDetails
+	for lister.Next(&object) {
+		if object.Name < segment.keyRange.Start {
+			object = ObjectDetails{}
+			continue
+		}
+		if segment.keyRange.End != "" && object.Name >= segment.keyRange.End {
+			return
+		}
+		if !send(object) {
+			return
+		}
+		object = ObjectDetails{}
+	}
+	if err := lister.Error(); err != nil {
+		l.setError(err)
+	}
+}
+
+// 构造从 start 之前开始列举的 v1 位置标记，服务端从标记中的对象名称之后开始列举
+//
+// 标记中的对象名称取 start 去掉最后一个字节，该名称一定小于 start，二者之间的对象由调用方跳过
+func markerBefore(start string) string {
+	if len(start) <= 1 {
+		return ""
+	}
+	markerJSON, err := json.Marshal(&struct {
+		C int    `json:"c"`
+		K string `json:"k"`
+	}{K: start[:len(start)-1]})
+	if err != nil {
+		return ""
+	}
+	return base64.URLEncoding.EncodeToString(markerJSON)
+}
+
+func (l *parallelLister) setError(err error) {
+	l.errLock.Lock()
+	defer l.errLock.Unlock()
+	if l.err == nil && !l.closed {
+		l.err = err
+	}
+	l.cancel()
+}
+
+func (l *parallelLister) Next(object *ObjectDetails) bool {
+	for l.Error() == nil {
+		if l.current == nil {
+			segment, ok := <-l.segments
+			if !ok {
+				return false
+			}
+			l.current = segment
+		}
+		if o, ok := <-l.current; ok {
+			*object = o
+			return l.Error() == nil
+		}
+		l.current = nil
+	}
+	return false
+}
+
+func (l *parallelLister) Error() error {
+	l.errLock.Lock()
+	defer l.errLock.Unlock()
+	return l.err
+}
+
+// 并行列举器不支持断点续传，始终返回空字符串
+func (l *parallelLister) Marker() string {
+	return ""
+}
+
+func (l *parallelLister) Close() error {
+	l.errLock.Lock()
+	l.closed = true
+	l.cancel()
+	l.errLock.Unlock()
+	l.wg.Wait()
+	return l.Error()
+}
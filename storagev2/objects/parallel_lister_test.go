@@ -0,0 +1,220 @@
+//go:build unit
+// +build unit
+
+package objects_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qiniu/go-sdk/v7/storagev2/apis/get_objects"
+	"github.com/qiniu/go-sdk/v7/storagev2/credentials"
+	"github.com/qiniu/go-sdk/v7/storagev2/http_client"
+	"github.com/qiniu/go-sdk/v7/storagev2/objects"
+	"github.com/qiniu/go-sdk/v7/storagev2/region"
+)
+
+func newParallelListServer(t *testing.T, objectNames []string, failedPrefix string) *httptest.Server {
+	const pageSize = 2
+	sort.Strings(objectNames)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/list" {
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+		query := r.URL.Query()
+		if query.Get("bucket") != "bucket1" {
+			t.Fatalf("unexpected bucket")
+		}
+		prefix, delimiter, marker := query.Get("prefix"), query.Get("delimiter"), query.Get("marker")
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("X-ReqId", "fakereqid")
+		if failedPrefix != "" && prefix == failedPrefix {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"list failed"}`))
+			return
+		}
+
+		// 条目为对象名称或公共前缀，公共前缀以 delimiter 结尾
+		var entries []string
+		for _, objectName := range objectNames {
+			if !strings.HasPrefix(objectName, prefix) {
+				continue
+			}
+			if delimiter != "" {
+				if idx := strings.Index(objectName[len(prefix):], delimiter); idx >= 0 {
+					commonPrefix := objectName[:len(prefix)+idx+len(delimiter)]
+					if len(entries) == 0 || entries[len(entries)-1] != commonPrefix {
+						entries = append(entries, commonPrefix)
+					}
+					continue
+				}
+			}
+			entries = append(entries, objectName)
+		}
+		offset := 0
+		if marker != "" {
+			var err error
+			if offset, err = strconv.Atoi(marker); err != nil {
+				// 由起始对象名称构造的 v1 位置标记，从标记中的对象名称之后开始列举
+				var v1Marker struct {
+					K string `json:"k"`
+				}
+				markerJSON, err := base64.URLEncoding.DecodeString(marker)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err = json.Unmarshal(markerJSON, &v1Marker); err != nil {
+					t.Fatal(err)
+				}
+				for offset < len(entries) && entries[offset] <= v1Marker.K {
+					offset += 1
+				}
+			}
+		}
+		// 分页可能只包含公共前缀，get_objects.Response 不允许 Items 为空，因此直接构造响应
+		response := struct {
+			Marker         string                          `json:"marker,omitempty"`
+			CommonPrefixes []string                        `json:"commonPrefixes,omitempty"`
+			Items          []get_objects.ListedObjectEntry `json:"items"`
+		}{Items: []get_objects.ListedObjectEntry{}}
+		for _, entry := range entries[offset:min(offset+pageSize, len(entries))] {
+			if delimiter != "" && strings.HasSuffix(entry, delimiter) {
+				response.CommonPrefixes = append(response.CommonPrefixes, entry)
+			} else {
+				response.Items = append(response.Items, get_objects.ListedObjectEntry{
+					Key:      entry,
+					PutTime:  time.Now().UnixNano() / 100,
+					Hash:     "testhash",
+					Size:     int64(len(entry)),
+					MimeType: "text/plain",
+				})
+			}
+		}
+		if offset+pageSize < len(entries) {
+			response.Marker = strconv.Itoa(offset + pageSize)
+		}
+		jsonData, err := json.Marshal(&response)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(jsonData)
+	}))
+}
+
+func TestParallelLister(t *testing.T) {
+	objectNames := []string{
+		"a.txt", "dir1/x", "dir1/y", "dir1/z", "dir2/c", "dir2/sub/a", "dir2/sub/b",
+		"e.txt", "f/g", "f/h/i", "z.txt",
+	}
+	server := newParallelListServer(t, append([]string(nil), objectNames...), "")
+	defer server.Close()
+
+	bucket := objects.NewObjectsManager(&objects.ObjectsManagerOptions{
+		Options: http_client.Options{
+			Credentials: credentials.NewCredentials("testak", "testsk"),
+			Regions:     &region.Region{Rsf: region.Endpoints{Preferred: []string{server.URL}}},
+		},
+	}).Bucket("bucket1")
+
+	listAll := func(options *objects.ParallelListObjectsOptions) []string {
+		lister := bucket.ListParallel(context.Background(), options)
+		defer lister.Close()
+		var (
+			object objects.ObjectDetails
+			names  []string
+		)
+		for lister.Next(&object) {
+			if object.Size != int64(len(object.Name)) {
+				t.Fatalf("unexpected object: %+v", object)
+			}
+			names = append(names, object.Name)
+		}
+		if err := lister.Error(); err != nil {
+			t.Fatal(err)
+		}
+		return names
+	}
+
+	for _, depth := range []int{1, 2} {
+		if names := listAll(&objects.ParallelListObjectsOptions{Ordered: true, Depth: depth, Concurrency: 2, BufferSize: 1}); strings.Join(names, ",") != strings.Join(objectNames, ",") {
+			t.Fatalf("unexpected ordered names of depth %d: %v", depth, names)
+		}
+	}
+
+	names := listAll(&objects.ParallelListObjectsOptions{Concurrency: 3})
+	sort.Strings(names)
+	if strings.Join(names, ",") != strings.Join(objectNames, ",") {
+		t.Fatalf("unexpected unordered names: %v", names)
+	}
+
+	names = listAll(&objects.ParallelListObjectsOptions{Prefix: "dir2/", Ordered: true})
+	if strings.Join(names, ",") != "dir2/c,dir2/sub/a,dir2/sub/b" {
+		t.Fatalf("unexpected names with prefix: %v", names)
+	}
+
+	names = listAll(&objects.ParallelListObjectsOptions{
+		Ordered: true,
+		KeyRanges: []objects.KeyRange{
+			{Prefix: "dir1/", End: "dir1/z"},
+			{Prefix: "dir2/", Marker: "2"},
+			{Prefix: "e"},
+		},
+	})
+	if strings.Join(names, ",") != "dir1/x,dir1/y,dir2/sub/b,e.txt" {
+		t.Fatalf("unexpected names with key ranges: %v", names)
+	}
+
+	names = listAll(&objects.ParallelListObjectsOptions{
+		Ordered: true,
+		KeyRanges: []objects.KeyRange{
+			{End: "dir1/y"},
+			{Start: "dir1/y", End: "dir2/sub/b"},
+			{Start: "dir2/sub/b"},
+		},
+	})
+	if strings.Join(names, ",") != strings.Join(objectNames, ",") {
+		t.Fatalf("unexpected names with start of key ranges: %v", names)
+	}
+	names = listAll(&objects.ParallelListObjectsOptions{
+		KeyRanges: []objects.KeyRange{{Prefix: "dir2/", Start: "dir2/d", End: "dir2/sub/b"}},
+	})
+	if strings.Join(names, ",") != "dir2/sub/a" {
+		t.Fatalf("unexpected names with start of key ranges: %v", names)
+	}
+}
+
+func TestParallelListerError(t *testing.T) {
+	server := newParallelListServer(t, []string{"a.txt", "dir1/x", "dir2/y", "dir3/z"}, "dir2/")
+	defer server.Close()
+
+	bucket := objects.NewObjectsManager(&objects.ObjectsManagerOptions{
+		Options: http_client.Options{
+			Credentials: credentials.NewCredentials("testak", "testsk"),
+			Regions:     &region.Region{Rsf: region.Endpoints{Preferred: []string{server.URL}}},
+		},
+	}).Bucket("bucket1")
+
+	for _, ordered := range []bool{true, false} {
+		lister := bucket.ListParallel(context.Background(), &objects.ParallelListObjectsOptions{Ordered: ordered})
+		var object objects.ObjectDetails
+		for lister.Next(&object) {
+			if object.Name == "dir2/y" {
+				t.Fatalf("unexpected object: %s", object.Name)
+			}
+		}
+		if err := lister.Error(); err == nil {
+			t.Fatalf("list error is expected")
+		}
+		if err := lister.Close(); err == nil {
+			t.Fatalf("list error is expected")
+		}
+	}
+}
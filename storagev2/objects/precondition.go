@@ -0,0 +1,23 @@
+package objects
+
+import (
+	stderrors "errors"
+
+	"github.com/qiniu/go-sdk/v7/storagev2/errors"
+)
+
+const (
+	// 目标对象已存在
+	statusCodeObjectExists = 614
+	// 条件不匹配
+	statusCodePreconditionFailed = 412
+)
+
+// 将服务器返回的前置条件错误转换为 errors.PreconditionFailedError，其他错误原样返回
+func wrapPreconditionError(err error, condition string, statusCode int) error {
+	var httpCodeErr interface{ HttpCode() int }
+	if err != nil && stderrors.As(err, &httpCodeErr) && httpCodeErr.HttpCode() == statusCode {
+		return errors.PreconditionFailedError{Condition: condition, Err: err}
+	}
+	return err
+}
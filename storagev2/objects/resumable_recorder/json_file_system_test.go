@@ -0,0 +1,85 @@
+//go:build unit
+// +build unit
+
+package resumablerecorder_test
+
+import (
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	resumablerecorder "github.com/qiniu/go-sdk/v7/storagev2/objects/resumable_recorder"
+)
+
+func TestJsonFileSystemResumableRecorder(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	options := resumablerecorder.ResumableRecorderOpenArgs{
+		Operation:    "move",
+		BucketName:   "bucket1",
+		Prefix:       "logs/",
+		ToBucketName: "bucket2",
+		ToPrefix:     "archive/",
+	}
+	fs := resumablerecorder.NewJsonFileSystemResumableRecorder(tmpDir)
+	if medium := fs.OpenForReading(&options); medium != nil {
+		t.Fatalf("record should not exist")
+	}
+	writableMedium := fs.OpenForCreatingNew(&options)
+	if err = writableMedium.Write(&resumablerecorder.ResumableRecord{Marker: "marker1", LastObjectName: "logs/a", Completed: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	if err = writableMedium.Close(); err != nil {
+		t.Fatal(err)
+	}
+	writableMedium = fs.OpenForAppending(&options)
+	if err = writableMedium.Write(&resumablerecorder.ResumableRecord{Marker: "marker2", LastObjectName: "logs/b", Completed: 2000}); err != nil {
+		t.Fatal(err)
+	}
+	if err = writableMedium.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	options2 := options
+	options2.Operation = "copy"
+	if medium := fs.OpenForReading(&options2); medium != nil {
+		t.Fatalf("record of another operation should not exist")
+	}
+
+	readableMedium := fs.OpenForReading(&options)
+	var records []resumablerecorder.ResumableRecord
+	for {
+		var record resumablerecorder.ResumableRecord
+		if err = readableMedium.Next(&record); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	if err = readableMedium.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[1] != (resumablerecorder.ResumableRecord{Marker: "marker2", LastObjectName: "logs/b", Completed: 2000}) {
+		t.Fatalf("unexpected records: %+v", records)
+	}
+
+	if err = fs.ClearOutdated(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if medium := fs.OpenForReading(&options); medium == nil {
+		t.Fatalf("record should not be cleared")
+	} else {
+		medium.Close()
+	}
+	if err = fs.Delete(&options); err != nil {
+		t.Fatal(err)
+	}
+	if medium := fs.OpenForReading(&options); medium != nil {
+		t.Fatalf("record should be deleted")
+	}
+}
@@ -0,0 +1,72 @@
+package resumablerecorder
+
+import (
+	"io"
+	"time"
+)
+
+type (
+	// 可恢复记录仪选项
+	ResumableRecorderOpenArgs struct {
+		// 操作类型，例如 move、copy、delete
+		Operation string
+
+		// 空间名称
+		BucketName string
+
+		// 目录前缀
+		Prefix string
+
+		// 目标空间名称，删除时为空
+		ToBucketName string
+
+		// 目标目录前缀，删除时为空
+		ToPrefix string
+	}
+
+	// 可恢复记录仪接口
+	ResumableRecorder interface {
+		// 打开记录仪介质以读取记录
+		OpenForReading(*ResumableRecorderOpenArgs) ReadableResumableRecorderMedium
+
+		// 打开记录仪介质以追加记录
+		OpenForAppending(*ResumableRecorderOpenArgs) WriteableResumableRecorderMedium
+
+		// 新建记录仪介质以追加记录
+		OpenForCreatingNew(*ResumableRecorderOpenArgs) WriteableResumableRecorderMedium
+
+		// 删除记录仪介质
+		Delete(*ResumableRecorderOpenArgs) error
+
+		// 清理过期的记录仪介质
+		ClearOutdated(createdBefore time.Duration) error
+	}
+
+	// 只读的可恢复记录仪介质接口
+	ReadableResumableRecorderMedium interface {
+		io.Closer
+
+		// 读取下一条记录
+		Next(*ResumableRecord) error
+	}
+
+	// 只追加的可恢复记录仪介质接口
+	WriteableResumableRecorderMedium interface {
+		io.Closer
+
+		// 写入下一条记录
+		Write(*ResumableRecord) error
+	}
+
+	// 可恢复记录，每批操作完成后写入一条
+	ResumableRecord struct {
+		// 恢复列举的位置标记
+		Marker string
+
+		// 已经完成的最后一个对象名称
+		LastObjectName string
+
+		// 已经完成的对象数量
+		Completed uint64
+	}
+)
@@ -0,0 +1,300 @@
+package objects
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qiniu/go-sdk/v7/storagev2/apis"
+	"github.com/qiniu/go-sdk/v7/storagev2/errors"
+)
+
+type (
+	// 软删除选项
+	SoftDeleteOptions struct {
+		// 回收站前缀，默认为 .trash/
+		TrashPrefix string
+
+		// 对象在回收站中保留的天数，到期后被自动删除，默认为 30
+		RetentionDays int64
+	}
+
+	// 回收站，软删除的对象被移动到回收站前缀下，并在到期后被自动删除
+	//
+	// 回收站中的对象名称为回收站前缀 + 原对象名称 + "~" + 版本，版本为删除时间（UTC，格式为 20060102T150405.000000000Z），
+	// 因此同一对象多次删除后，回收站中保留每次删除的版本，按照版本升序排列。
+	Trash struct {
+		bucket        *Bucket
+		prefix        string
+		retentionDays int64
+	}
+
+	// 回收站中的对象
+	TrashedObject struct {
+		// 对象详情，其中对象名称为删除前的对象名称
+		ObjectDetails
+
+		// 对象在回收站中的名称
+		TrashedName string
+
+		// 版本，即删除时间，恢复或彻底删除指定版本时使用；对象名称不符合回收站命名格式时为空
+		Version string
+
+		// 删除时间，如果无法从版本或删除时间元数据中获取，则为零值
+		DeletedAt time.Time
+	}
+
+	softDeleteResult struct {
+		lock sync.Mutex
+		done bool
+		err  error
+	}
+)
+
+const (
+	defaultTrashPrefix        = ".trash/"
+	defaultTrashRetentionDays = 30
+	trashDeletedAtMetadataKey = "x-qn-meta-deleted-at"
+	trashVersionSeparator     = "~"
+	trashVersionLayout        = "20060102T150405.000000000Z"
+)
+
+// 获取存储空间的回收站，options 为空时使用 ObjectsManager 的软删除选项
+func (bucket *Bucket) Trash(options *SoftDeleteOptions) *Trash {
+	if options == nil {
+		options = bucket.objectsManager.softDelete
+	}
+	if options == nil {
+		options = &SoftDeleteOptions{}
+	}
+	trash := Trash{bucket: bucket, prefix: options.TrashPrefix, retentionDays: options.RetentionDays}
+	if trash.prefix == "" {
+		trash.prefix = defaultTrashPrefix
+	}
+	if trash.retentionDays <= 0 {
+		trash.retentionDays = defaultTrashRetentionDays
+	}
+	return &trash
+}
+
+// 回收站前缀
+func (trash *Trash) Prefix() string {
+	return trash.prefix
+}
+
+// 判断对象是否位于回收站中
+func (trash *Trash) Contains(objectName string) bool {
+	return strings.HasPrefix(objectName, trash.prefix)
+}
+
+// 软删除对象，将对象移动到回收站中以删除时间为版本的对象，已经位于回收站中的对象将被直接删除
+//
+// 移动完成后，为回收站中的对象设置删除时间元数据 x-qn-meta-deleted-at（UNIX 时间戳，单位为秒），并设置过期删除的生命周期。
+// 移动时不强制覆盖，回收站中已经存在同一版本时返回错误，不会覆盖之前删除的版本。
+func (trash *Trash) SoftDelete(objectName string) *DeleteObjectOperation {
+	operation := DeleteObjectOperation{object: Object{trash.bucket, objectName}}
+	if !trash.Contains(objectName) {
+		operation.trash = trash
+		operation.deletedAt = time.Now()
+		operation.trashName = trash.trashedNameOf(objectName, operation.deletedAt.UTC().Format(trashVersionLayout))
+		operation.softDeleteResult = new(softDeleteResult)
+	}
+	return &operation
+}
+
+// 彻底删除回收站中对象的指定版本，objectName 为删除前的对象名称
+func (trash *Trash) Purge(objectName, version string) *DeleteObjectOperation {
+	return &DeleteObjectOperation{object: Object{trash.bucket, trash.trashedNameOf(objectName, version)}}
+}
+
+// 列举回收站中的对象，options 中的前缀为删除前的对象名称的前缀，f 返回错误时停止列举
+func (trash *Trash) List(ctx context.Context, options *ListObjectsOptions, f func(*TrashedObject) error) error {
+	var listOptions ListObjectsOptions
+	if options != nil {
+		listOptions = *options
+	}
+	listOptions.Prefix = trash.prefix + listOptions.Prefix
+	lister := trash.bucket.List(ctx, &listOptions)
+	defer lister.Close()
+
+	var objectDetails ObjectDetails
+	for lister.Next(&objectDetails) {
+		trashedObject := TrashedObject{ObjectDetails: objectDetails, TrashedName: objectDetails.Name}
+		trashedObject.Name, trashedObject.Version = trash.parseTrashedName(objectDetails.Name)
+		if deletedAt, err := time.Parse(trashVersionLayout, trashedObject.Version); err == nil {
+			trashedObject.DeletedAt = deletedAt
+		} else {
+			deletedAt, ok := objectDetails.Metadata[trashDeletedAtMetadataKey]
+			if !ok {
+				deletedAt = objectDetails.Metadata[strings.TrimPrefix(trashDeletedAtMetadataKey, "x-qn-meta-")]
+			}
+			if seconds, err := strconv.ParseInt(deletedAt, 10, 64); err == nil {
+				trashedObject.DeletedAt = time.Unix(seconds, 0)
+			}
+		}
+		if err := f(&trashedObject); err != nil {
+			return err
+		}
+		objectDetails = ObjectDetails{}
+	}
+	return lister.Error()
+}
+
+// 将回收站中对象的指定版本恢复到删除前的对象名称，并取消过期删除的生命周期，version 为空时恢复最新的版本
+//
+// 不强制覆盖时，如果原对象名称已经存在，则返回错误。
+// 恢复后的对象仍然保留删除时间元数据，对象原有的过期删除时间不会被恢复。
+func (trash *Trash) Restore(ctx context.Context, objectName, version string, force bool) error {
+	if version == "" {
+		var err error
+		if version, err = trash.latestVersionOf(ctx, objectName); err != nil {
+			return err
+		}
+	}
+	if err := trash.bucket.Object(trash.trashedNameOf(objectName, version)).MoveTo(trash.bucket.name, objectName).Force(force).Call(ctx); err != nil {
+		return err
+	}
+	_, err := trash.bucket.objectsManager.storage.DeleteObjectAfterDays(ctx, &apis.DeleteObjectAfterDaysRequest{
+		Entry:           trash.bucket.name + ":" + objectName,
+		DeleteAfterDays: 0,
+	}, &apis.Options{
+		OverwrittenBucketName: trash.bucket.name,
+	})
+	return err
+}
+
+func (trash *Trash) latestVersionOf(ctx context.Context, objectName string) (string, error) {
+	var latestVersion string
+	err := trash.List(ctx, &ListObjectsOptions{Prefix: objectName + trashVersionSeparator}, func(trashedObject *TrashedObject) error {
+		if trashedObject.Name == objectName && trashedObject.Version > latestVersion {
+			latestVersion = trashedObject.Version
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	} else if latestVersion == "" {
+		return "", errors.InvalidArgumentError{Name: "objectName", Reason: "object is not found in the trash"}
+	}
+	return latestVersion, nil
+}
+
+func (trash *Trash) trashedNameOf(objectName, version string) string {
+	return trash.prefix + objectName + trashVersionSeparator + version
+}
+
+// 版本位于最后一个分隔符之后，并且不含分隔符，因此原对象名称中包含分隔符时也能正确解析
+func (trash *Trash) parseTrashedName(trashedName string) (string, string) {
+	name := strings.TrimPrefix(trashedName, trash.prefix)
+	if i := strings.LastIndex(name, trashVersionSeparator); i >= 0 {
+		if _, err := time.Parse(trashVersionLayout, name[i+len(trashVersionSeparator):]); err == nil {
+			return name[:i], name[i+len(trashVersionSeparator):]
+		}
+	}
+	return name, ""
+}
+
+// 为移动到回收站的对象设置删除时间元数据和过期删除的生命周期，然后回调每个软删除操作的结果
+func finishSoftDeletes(ctx context.Context, objectsManager *ObjectsManager, operations []*DeleteObjectOperation, batchErr error) error {
+	moved := make([]*DeleteObjectOperation, 0, len(operations))
+	for _, operation := range operations {
+		if done, err := operation.softDeleteResult.get(); !done && batchErr != nil {
+			operation.finishSoftDelete(batchErr)
+		} else if err != nil {
+			operation.finishSoftDelete(err)
+		} else {
+			moved = append(moved, operation)
+		}
+	}
+	if len(moved) == 0 {
+		return nil
+	}
+
+	details := make([]*ObjectDetails, len(moved))
+	statErrs := make([]error, len(moved))
+	statOps := make([]Operation, len(moved))
+	for i, operation := range moved {
+		i := i
+		statOps[i] = operation.trashObject().Stat().
+			OnResponse(func(objectDetails *ObjectDetails) { details[i], statErrs[i] = objectDetails, nil }).
+			OnError(func(err error) { statErrs[i] = err })
+	}
+	if err := objectsManager.Batch(ctx, statOps, nil); err != nil {
+		for _, operation := range moved {
+			operation.finishSoftDelete(err)
+		}
+		return err
+	}
+
+	metadataErrs := make([]error, len(moved))
+	lifeCycleErrs := make([]error, len(moved))
+	updateOps := make([]Operation, 0, 2*len(moved))
+	for i, operation := range moved {
+		if statErrs[i] != nil || details[i] == nil {
+			continue
+		}
+		i := i
+		mimeType := details[i].MimeType
+		if mimeType == "" {
+			mimeType = "application/octet-stream"
+		}
+		metadata := make(map[string]string, len(details[i].Metadata)+1)
+		for k, v := range details[i].Metadata {
+			metadata[k] = v
+		}
+		metadata[trashDeletedAtMetadataKey] = strconv.FormatInt(operation.deletedAt.Unix(), 10)
+		// 过期删除的天数从对象上传时开始计算
+		deleteAfterDays := int64(math.Ceil(time.Since(details[i].UploadedAt).Hours()/24)) + operation.trash.retentionDays
+		trashObject := operation.trashObject()
+		updateOps = append(updateOps,
+			trashObject.SetMetadata(mimeType).Metadata(metadata).
+				OnResponse(func() { metadataErrs[i] = nil }).
+				OnError(func(err error) { metadataErrs[i] = err }),
+			trashObject.SetLifeCycle().DeleteAfterDays(deleteAfterDays).
+				OnResponse(func() { lifeCycleErrs[i] = nil }).
+				OnError(func(err error) { lifeCycleErrs[i] = err }),
+		)
+	}
+	err := objectsManager.Batch(ctx, updateOps, nil)
+	for i, operation := range moved {
+		switch {
+		case statErrs[i] != nil:
+			operation.finishSoftDelete(statErrs[i])
+		case err != nil:
+			operation.finishSoftDelete(err)
+		case metadataErrs[i] != nil:
+			operation.finishSoftDelete(metadataErrs[i])
+		default:
+			operation.finishSoftDelete(lifeCycleErrs[i])
+		}
+	}
+	return err
+}
+
+func softDeleteOperationsOf(operations []Operation) []*DeleteObjectOperation {
+	var softDeletes []*DeleteObjectOperation
+	for _, operation := range operations {
+		if bulkOp, ok := operation.(*bulkOperation); ok {
+			operation = bulkOp.Operation
+		}
+		if deleteOperation, ok := operation.(*DeleteObjectOperation); ok && deleteOperation.trash != nil {
+			softDeletes = append(softDeletes, deleteOperation)
+		}
+	}
+	return softDeletes
+}
+
+func (result *softDeleteResult) set(err error) {
+	result.lock.Lock()
+	defer result.lock.Unlock()
+	result.done, result.err = true, err
+}
+
+func (result *softDeleteResult) get() (bool, error) {
+	result.lock.Lock()
+	defer result.lock.Unlock()
+	return result.done, result.err
+}
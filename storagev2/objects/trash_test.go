@@ -0,0 +1,227 @@
+//go:build unit
+// +build unit
+
+package objects_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qiniu/go-sdk/v7/storagev2/apis/batch_ops"
+	"github.com/qiniu/go-sdk/v7/storagev2/apis/get_objects"
+	"github.com/qiniu/go-sdk/v7/storagev2/credentials"
+	"github.com/qiniu/go-sdk/v7/storagev2/http_client"
+	"github.com/qiniu/go-sdk/v7/storagev2/objects"
+	"github.com/qiniu/go-sdk/v7/storagev2/region"
+)
+
+func TestTrash(t *testing.T) {
+	var (
+		lock         sync.Mutex
+		requests     []string
+		trashedNames []string
+		putTime      = time.Now().Add(-60*time.Hour).UnixNano() / 100
+		versionRegex = regexp.MustCompile(`~\d{8}T\d{6}\.\d{9}Z$`)
+	)
+	encode := func(s string) string {
+		return base64.URLEncoding.EncodeToString([]byte(s))
+	}
+	decode := func(s string) string {
+		b, err := base64.URLEncoding.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 删除时间各不相同，记录请求时替换为固定的版本
+		return versionRegex.ReplaceAllString(string(b), "~v")
+	}
+	writeJSON := func(w http.ResponseWriter, v interface{}) {
+		jsonData, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("X-ReqId", "fakereqid")
+		w.Write(jsonData)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/batch", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		responses := make([]batch_ops.OperationResponse, 0, len(r.PostForm["op"]))
+		for _, op := range r.PostForm["op"] {
+			parts := strings.Split(op, "/")
+			requests = append(requests, parts[0]+":"+decode(parts[1]))
+			switch {
+			case parts[0] == "move" && decode(parts[1]) == "bucket1:c.txt":
+				responses = append(responses, batch_ops.OperationResponse{Code: 612, Data: batch_ops.OperationResponseData{Error: "no such file or directory"}})
+			case parts[0] == "move":
+				if len(parts) != 3 || decode(parts[2]) != "bucket1:.trash/"+strings.TrimPrefix(decode(parts[1]), "bucket1:")+"~v" {
+					t.Fatalf("unexpected move operation: %s", op)
+				}
+				responses = append(responses, batch_ops.OperationResponse{Code: 200})
+			case parts[0] == "stat":
+				responses = append(responses, batch_ops.OperationResponse{Code: 200, Data: batch_ops.OperationResponseData{
+					Size: 10, Hash: "testhash", MimeType: "text/plain", PutTime: putTime,
+					Metadata: map[string]string{"author": "qiniu"},
+				}})
+			case parts[0] == "chgm":
+				if len(parts) != 8 || parts[3] != encode("text/plain") ||
+					!strings.Contains(op, "/x-qn-meta-author/"+encode("qiniu")) || !strings.Contains(op, "/x-qn-meta-deleted-at/") {
+					t.Fatalf("unexpected chgm operation: %s", op)
+				}
+				responses = append(responses, batch_ops.OperationResponse{Code: 200})
+			case parts[0] == "lifecycle":
+				// 上传于 2.5 天之前，保留 7 天
+				if strings.Join(parts[2:], "/") != "deleteAfterDays/10" {
+					t.Fatalf("unexpected lifecycle operation: %s", op)
+				}
+				responses = append(responses, batch_ops.OperationResponse{Code: 200})
+			default:
+				responses = append(responses, batch_ops.OperationResponse{Code: 200})
+			}
+		}
+		writeJSON(w, &batch_ops.Response{OperationResponses: responses})
+	})
+	mux.HandleFunc("/list", func(w http.ResponseWriter, r *http.Request) {
+		prefix := r.URL.Query().Get("prefix")
+		if !strings.HasPrefix(prefix, ".trash/") {
+			t.Fatalf("unexpected prefix: %s", prefix)
+		}
+		// 列举结果可能为空，get_objects.Response 不允许 Items 为空，因此直接构造响应
+		response := struct {
+			Items []get_objects.ListedObjectEntry `json:"items"`
+		}{Items: []get_objects.ListedObjectEntry{}}
+		for _, entry := range []get_objects.ListedObjectEntry{
+			{Key: ".trash/a.txt~20231114T221320.000000000Z"},
+			{Key: ".trash/a.txt~20240101T000000.000000000Z"},
+			{Key: ".trash/a.txt~bak~20240102T000000.000000000Z"},
+			{Key: ".trash/legacy.txt", Metadata: map[string]string{"deleted-at": "1700000000"}},
+		} {
+			if strings.HasPrefix(entry.Key, prefix) {
+				entry.PutTime, entry.Hash, entry.Size, entry.MimeType = putTime, "testhash", 10, "text/plain"
+				response.Items = append(response.Items, entry)
+			}
+		}
+		writeJSON(w, &response)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		lock.Lock()
+		requests = append(requests, parts[0]+":"+decode(parts[1]))
+		lock.Unlock()
+		switch parts[0] {
+		case "move":
+			from, _ := base64.URLEncoding.DecodeString(parts[1])
+			to, _ := base64.URLEncoding.DecodeString(parts[2])
+			if string(from) == "bucket1:a.txt" {
+				if !versionRegex.MatchString(string(to)) || !strings.HasPrefix(string(to), "bucket1:.trash/a.txt~") || len(parts) != 3 {
+					t.Fatalf("unexpected soft delete request: %s", r.URL.Path)
+				}
+				lock.Lock()
+				trashedNames = append(trashedNames, string(to))
+				lock.Unlock()
+			} else if !strings.HasPrefix(string(from), "bucket1:.trash/a.txt~2024") || string(to) != "bucket1:a.txt" || len(parts) != 3 {
+				t.Fatalf("unexpected restore request: %s", r.URL.Path)
+			}
+		case "deleteAfterDays":
+			if decode(parts[1]) != "bucket1:a.txt" || parts[2] != "0" {
+				t.Fatalf("unexpected deleteAfterDays request: %s", r.URL.Path)
+			}
+		default:
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+		w.Header().Add("X-ReqId", "fakereqid")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	objectsManager := objects.NewObjectsManager(&objects.ObjectsManagerOptions{
+		Options: http_client.Options{
+			Credentials: credentials.NewCredentials("testak", "testsk"),
+			Regions: &region.Region{
+				Rs:  region.Endpoints{Preferred: []string{server.URL}},
+				Rsf: region.Endpoints{Preferred: []string{server.URL}},
+			},
+		},
+		SoftDelete: &objects.SoftDeleteOptions{RetentionDays: 7},
+	})
+	bucket := objectsManager.Bucket("bucket1")
+	trash := bucket.Trash(nil)
+	if trash.Prefix() != ".trash/" || !trash.Contains(".trash/a.txt") || trash.Contains("a.txt") {
+		t.Fatalf("unexpected trash prefix: %s", trash.Prefix())
+	}
+
+	// 同一对象删除两次，回收站中保留两个版本
+	for i := 0; i < 2; i++ {
+		if err := bucket.Object("a.txt").Delete().Call(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if expected := strings.TrimSuffix(strings.Repeat("move:bucket1:a.txt,stat:bucket1:.trash/a.txt~v,chgm:bucket1:.trash/a.txt~v,lifecycle:bucket1:.trash/a.txt~v,", 2), ","); strings.Join(requests, ",") != expected {
+		t.Fatalf("unexpected requests: %v", requests)
+	}
+	if len(trashedNames) != 2 || trashedNames[0] == trashedNames[1] {
+		t.Fatalf("each deletion should be moved to a distinct version: %v", trashedNames)
+	}
+
+	requests = nil
+	var succeeded, failed []string
+	newDeleteOperation := func(objectName string) objects.Operation {
+		return bucket.Object(objectName).Delete().
+			OnResponse(func() { succeeded = append(succeeded, objectName) }).
+			OnError(func(error) { failed = append(failed, objectName) })
+	}
+	if err := objectsManager.Batch(context.Background(), []objects.Operation{
+		newDeleteOperation("b.txt"),
+		newDeleteOperation("c.txt"),
+		newDeleteOperation(".trash/old.txt"),
+	}, &objects.BatchOptions{BatchOpsExecutor: objects.NewSerialBatchOpsExecutor(nil)}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(succeeded, ",") != ".trash/old.txt,b.txt" || strings.Join(failed, ",") != "c.txt" {
+		t.Fatalf("unexpected results: %v, %v", succeeded, failed)
+	}
+	if strings.Join(requests, ",") != "move:bucket1:b.txt,move:bucket1:c.txt,delete:bucket1:.trash/old.txt,stat:bucket1:.trash/b.txt~v,chgm:bucket1:.trash/b.txt~v,lifecycle:bucket1:.trash/b.txt~v" {
+		t.Fatalf("unexpected requests: %v", requests)
+	}
+
+	var trashedObjects []string
+	if err := trash.List(context.Background(), nil, func(trashedObject *objects.TrashedObject) error {
+		trashedObjects = append(trashedObjects, trashedObject.Name+"@"+trashedObject.Version+"@"+strconv.FormatInt(trashedObject.DeletedAt.Unix(), 10))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(trashedObjects, ",") != "a.txt@20231114T221320.000000000Z@1700000000,a.txt@20240101T000000.000000000Z@1704067200,"+
+		"a.txt~bak@20240102T000000.000000000Z@1704153600,legacy.txt@@1700000000" {
+		t.Fatalf("unexpected trashed objects: %v", trashedObjects)
+	}
+
+	// 不指定版本时恢复最新的版本
+	requests = nil
+	if err := trash.Restore(context.Background(), "a.txt", "", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := trash.Restore(context.Background(), "a.txt", "20240101T000000.000000000Z", false); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(requests, ",") != "move:bucket1:.trash/a.txt~v,deleteAfterDays:bucket1:a.txt,move:bucket1:.trash/a.txt~v,deleteAfterDays:bucket1:a.txt" {
+		t.Fatalf("unexpected requests: %v", requests)
+	}
+	if err := trash.Restore(context.Background(), "b.txt", "", false); err == nil {
+		t.Fatalf("restoring an object not in the trash should fail")
+	}
+}
@@ -0,0 +1,39 @@
+// Package syncer 提供本地目录与七牛云存储空间目录之间的增量同步。
+//
+// 与 uploader.UploadManager.UploadDirectory 和 downloader.DownloadManager.DownloadDirectory 每次都全量传输不同，
+// [Syncer] 会先比较本地目录与空间目录（通过 objects.Directory.ListEntries 列举）的差异，生成同步计划，
+// 再仅上传、下载或删除存在差异的文件。
+//
+// # 比较方式
+//
+// 首先比较文件大小，大小不同即视为存在差异。大小相同时：
+//
+//   - [CompareSizeAndModTime]（默认）：比较修改时间，对象的修改时间保存在自定义元数据 x-qn-meta-mtime 中，
+//     如果对象没有该元数据，则使用对象的上传时间
+//   - [CompareHash]：计算本地文件的七牛 Etag，并与对象的哈希值比较
+//   - [CompareSizeOnly]：仅比较文件大小
+//
+// 通过 Syncer 上传的对象会写入修改时间元数据，下载的文件也会将修改时间设置为对象的修改时间，因此同步完成后再次同步不会产生差异。
+//
+// # 同步本地目录到空间
+//
+//	s := syncer.NewSyncer(&syncer.SyncerOptions{
+//	    Options: http_client.Options{Credentials: cred},
+//	})
+//	report, err := s.Sync(ctx, "/path/to/dir", &syncer.SyncOptions{
+//	    BucketName:   "my-bucket",
+//	    ObjectPrefix: "backup/",
+//	    Direction:    syncer.DirectionUpload,
+//	    DeletePolicy: syncer.DeletePolicyDelete,
+//	    Exclude:      []string{"*.tmp", ".git"},
+//	})
+//
+// # 仅生成同步计划
+//
+// 设置 SyncOptions.DryRun 后，Sync 只生成同步计划而不执行，也可以直接调用 [Syncer.Plan]：
+//
+//	plan, err := s.Plan(ctx, "/path/to/dir", &syncer.SyncOptions{...})
+//	for _, action := range plan.Actions {
+//	    fmt.Println(action)
+//	}
+package syncer
@@ -0,0 +1,271 @@
+package syncer
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qiniu/go-sdk/v7/internal/etag"
+	"github.com/qiniu/go-sdk/v7/storagev2/objects"
+)
+
+type (
+	localFile struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+
+	remoteObject struct {
+		name    string
+		size    int64
+		etag    string
+		modTime time.Time
+	}
+)
+
+// 生成同步计划
+//
+// 遍历本地目录并列举空间目录，比较两端差异后生成需要执行的变更，不会修改本地文件或空间中的对象。
+// 与 objects.Bucket.Directory 相同，不以分隔符结尾的对象前缀会被补上分隔符。
+// 对象名称对应的本地路径位于本地目录之外时（例如对象名称包含 ..），该对象将被忽略。
+func (syncer *Syncer) Plan(ctx context.Context, localPath string, options *SyncOptions) (*Plan, error) {
+	if options == nil {
+		options = &SyncOptions{}
+	}
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+	pathSeparator := options.PathSeparator
+	if pathSeparator == "" {
+		pathSeparator = "/"
+	}
+	if options.ObjectPrefix != "" && !strings.HasSuffix(options.ObjectPrefix, pathSeparator) {
+		normalizedOptions := *options
+		normalizedOptions.ObjectPrefix += pathSeparator
+		options = &normalizedOptions
+	}
+
+	localFiles, err := options.walkLocal(localPath)
+	if err != nil {
+		return nil, err
+	}
+	remoteObjects, err := syncer.listRemote(ctx, options, pathSeparator)
+	if err != nil {
+		return nil, err
+	}
+
+	relativePaths := make([]string, 0, len(localFiles)+len(remoteObjects))
+	for relativePath := range localFiles {
+		relativePaths = append(relativePaths, relativePath)
+	}
+	for relativePath := range remoteObjects {
+		if _, ok := localFiles[relativePath]; !ok {
+			relativePaths = append(relativePaths, relativePath)
+		}
+	}
+	sort.Strings(relativePaths)
+
+	var (
+		plan      Plan
+		deletions int
+	)
+	for _, relativePath := range relativePaths {
+		local, remote := localFiles[relativePath], remoteObjects[relativePath]
+		localFilePath, ok := localPathOf(localPath, relativePath)
+		if !ok {
+			continue
+		}
+		action := Action{
+			RelativePath: relativePath,
+			LocalPath:    localFilePath,
+			ObjectName:   options.ObjectPrefix + strings.Replace(relativePath, "/", pathSeparator, -1),
+		}
+		if remote != nil {
+			action.ObjectName = remote.name
+		}
+		switch {
+		case local != nil && remote != nil:
+			changed, reason, err := options.compare(local, remote)
+			if err != nil {
+				return nil, err
+			} else if !changed {
+				plan.Unchanged = append(plan.Unchanged, relativePath)
+				continue
+			}
+			action.Reason = reason
+			if options.Direction == DirectionUpload {
+				action.Type, action.Size = ActionUpload, local.size
+			} else {
+				action.Type, action.Size, action.modTime = ActionDownload, remote.size, remote.modTime
+			}
+		case local != nil:
+			if options.Direction == DirectionUpload {
+				action.Type, action.Size, action.Reason = ActionUpload, local.size, "object not found"
+			} else if options.DeletePolicy == DeletePolicyDelete {
+				action.Type, action.Size, action.Reason = ActionDeleteLocalFile, local.size, "object not found"
+				deletions += 1
+			} else {
+				continue
+			}
+		default:
+			if options.Direction == DirectionDownload {
+				action.Type, action.Size, action.Reason, action.modTime = ActionDownload, remote.size, "local file not found", remote.modTime
+			} else if options.DeletePolicy == DeletePolicyDelete {
+				action.Type, action.Size, action.Reason = ActionDeleteObject, remote.size, "local file not found"
+				deletions += 1
+			} else {
+				continue
+			}
+		}
+		plan.Actions = append(plan.Actions, &action)
+	}
+	if options.MaxDeletions > 0 && deletions > options.MaxDeletions {
+		return &plan, ErrTooManyDeletions
+	}
+	return &plan, nil
+}
+
+// 将相对路径映射为本地路径，相对路径指向本地目录之外时返回 false
+func localPathOf(localPath, relativePath string) (string, bool) {
+	fullPath := filepath.Join(localPath, filepath.FromSlash(relativePath))
+	if rel, err := filepath.Rel(localPath, fullPath); err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return fullPath, true
+}
+
+func (options *SyncOptions) walkLocal(localPath string) (map[string]*localFile, error) {
+	localFiles := make(map[string]*localFile)
+	err := filepath.WalkDir(localPath, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && filePath == localPath {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		relativePath, err := filepath.Rel(localPath, filePath)
+		if err != nil {
+			return err
+		}
+		relativePath = filepath.ToSlash(relativePath)
+		if d.IsDir() {
+			if relativePath != "." && options.isExcluded(relativePath) {
+				return filepath.SkipDir
+			}
+			return nil
+		} else if !d.Type().IsRegular() || !options.isIncluded(relativePath) {
+			return nil
+		} else if strings.HasPrefix(d.Name(), ".") && strings.HasSuffix(d.Name(), ".tmp") {
+			return nil // 跳过下载中的临时文件
+		}
+		fileInfo, err := d.Info()
+		if err != nil {
+			return err
+		}
+		localFiles[relativePath] = &localFile{path: filePath, size: fileInfo.Size(), modTime: fileInfo.ModTime()}
+		return nil
+	})
+	return localFiles, err
+}
+
+func (syncer *Syncer) listRemote(ctx context.Context, options *SyncOptions, pathSeparator string) (map[string]*remoteObject, error) {
+	remoteObjects := make(map[string]*remoteObject)
+	directory := syncer.objectsManager.Bucket(options.BucketName).Directory(options.ObjectPrefix, pathSeparator)
+	err := directory.ListEntries(ctx, &objects.ListEntriesOptions{Recursive: true}, func(entry *objects.Entry) error {
+		if entry.Object == nil {
+			if options.isExcluded(options.relativePathOf(entry.DirectoryName, pathSeparator)) {
+				return objects.SkipDir
+			}
+			return nil
+		}
+		object := entry.Object
+		relativePath := options.relativePathOf(object.Name, pathSeparator)
+		if relativePath == "" || strings.HasSuffix(object.Name, pathSeparator) || !options.isIncluded(relativePath) {
+			return nil
+		}
+		modTime := object.UploadedAt
+		if t := parseModTime(object.Metadata); !t.IsZero() {
+			modTime = t
+		}
+		remoteObjects[relativePath] = &remoteObject{name: object.Name, size: object.Size, etag: object.ETag, modTime: modTime}
+		return nil
+	})
+	return remoteObjects, err
+}
+
+func (options *SyncOptions) relativePathOf(objectName, pathSeparator string) string {
+	relativePath := strings.TrimPrefix(objectName, options.ObjectPrefix)
+	if pathSeparator != "/" {
+		relativePath = strings.Replace(relativePath, pathSeparator, "/", -1)
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(relativePath, "/"), "/")
+}
+
+func (options *SyncOptions) compare(local *localFile, remote *remoteObject) (bool, string, error) {
+	if local.size != remote.size {
+		return true, "size differs", nil
+	}
+	switch options.CompareMode {
+	case CompareHash:
+		file, err := os.Open(local.path)
+		if err != nil {
+			return false, "", err
+		}
+		defer file.Close()
+		localEtag, err := etag.FromReader(file)
+		if err != nil {
+			return false, "", err
+		}
+		if localEtag != remote.etag {
+			return true, "hash differs", nil
+		}
+	case CompareSizeOnly:
+	default:
+		if local.modTime.Unix() != remote.modTime.Unix() {
+			return true, "modification time differs", nil
+		}
+	}
+	return false, "", nil
+}
+
+func (options *SyncOptions) isIncluded(relativePath string) bool {
+	if options.isExcluded(relativePath) {
+		return false
+	}
+	return len(options.Include) == 0 || matchAny(options.Include, relativePath)
+}
+
+func (options *SyncOptions) isExcluded(relativePath string) bool {
+	return len(options.Exclude) > 0 && matchAny(options.Exclude, relativePath)
+}
+
+func matchAny(patterns []string, relativePath string) bool {
+	for _, pattern := range patterns {
+		name := relativePath
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(relativePath)
+		}
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func parseModTime(metadata map[string]string) time.Time {
+	for k, v := range metadata {
+		if strings.EqualFold(k, MetadataKeyModTime) {
+			if seconds, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return time.Unix(seconds, 0)
+			}
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,387 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/qiniu/go-sdk/v7/storagev2/downloader"
+	storagev2errors "github.com/qiniu/go-sdk/v7/storagev2/errors"
+	httpclient "github.com/qiniu/go-sdk/v7/storagev2/http_client"
+	"github.com/qiniu/go-sdk/v7/storagev2/objects"
+	"github.com/qiniu/go-sdk/v7/storagev2/uploader"
+	"github.com/qiniu/go-sdk/v7/storagev2/uptoken"
+	"golang.org/x/sync/errgroup"
+)
+
+type (
+	// 同步器
+	Syncer struct {
+		objectsManager  *objects.ObjectsManager
+		uploadManager   *uploader.UploadManager
+		downloadManager *downloader.DownloadManager
+	}
+
+	// 同步器选项
+	SyncerOptions struct {
+		// HTTP 客户端选项
+		httpclient.Options
+
+		// 上传管理器，如果不填写，则使用 HTTP 客户端选项创建
+		UploadManager *uploader.UploadManager
+
+		// 下载管理器，如果不填写，则使用 HTTP 客户端选项创建
+		DownloadManager *downloader.DownloadManager
+	}
+
+	// 同步方向
+	Direction uint8
+
+	// 删除策略
+	DeletePolicy uint8
+
+	// 比较方式
+	CompareMode uint8
+
+	// 同步选项
+	SyncOptions struct {
+		// 空间名称
+		BucketName string
+
+		// 对象前缀
+		ObjectPrefix string
+
+		// 同步方向，默认为从本地同步到空间
+		Direction Direction
+
+		// 删除策略，默认为不删除
+		DeletePolicy DeletePolicy
+
+		// 最多允许删除的文件数量，如果计划删除的文件数量超过该值，则返回 ErrTooManyDeletions，不填写则表示不限制
+		MaxDeletions int
+
+		// 比较方式，默认为比较文件大小和修改时间
+		CompareMode CompareMode
+
+		// 包含的文件，如果不填写，则包含全部文件
+		// 不含路径分隔符的模式匹配文件名，否则匹配相对路径，语法与 path.Match 相同
+		Include []string
+
+		// 排除的文件，优先级高于 Include，语法与 Include 相同，被排除的文件在源端和目标端都会被忽略
+		Exclude []string
+
+		// 是否仅生成同步计划而不执行
+		DryRun bool
+
+		// 分隔符，默认为 /
+		PathSeparator string
+
+		// 同步并发度，默认为 4
+		ObjectConcurrency int
+
+		// 上传凭证，仅从本地同步到空间时有效，如果不填写，则使用凭证生成
+		UpToken uptoken.Provider
+
+		// 是否使用 HTTP 协议下载，默认为不使用，仅从空间同步到本地时有效
+		UseInsecureProtocol bool
+
+		// 下载 URL 生成器，仅从空间同步到本地时有效
+		DownloadURLsProvider downloader.DownloadURLsProvider
+
+		// 每个变更执行完毕后的回调，err 为 nil 表示执行成功
+		OnActionDone func(action *Action, err error)
+	}
+
+	// 变更类型
+	ActionType uint8
+
+	// 变更
+	Action struct {
+		// 变更类型
+		Type ActionType
+
+		// 相对路径，以 / 分隔
+		RelativePath string
+
+		// 本地文件路径
+		LocalPath string
+
+		// 对象名称
+		ObjectName string
+
+		// 需要传输的数据量，删除时为被删除文件的大小
+		Size int64
+
+		// 变更原因
+		Reason string
+
+		modTime time.Time
+	}
+
+	// 同步计划
+	Plan struct {
+		// 需要执行的变更
+		Actions []*Action
+
+		// 没有差异的文件的相对路径
+		Unchanged []string
+	}
+
+	// 同步报告
+	Report struct {
+		// 同步计划
+		Plan *Plan
+
+		// 是否仅生成同步计划
+		DryRun bool
+
+		// 执行成功的变更
+		Succeeded []*Action
+
+		// 执行失败的变更
+		Failed []*ActionError
+	}
+
+	// 变更执行错误
+	ActionError struct {
+		Action *Action
+		Err    error
+	}
+)
+
+const (
+	// 从本地同步到空间
+	DirectionUpload Direction = iota
+
+	// 从空间同步到本地
+	DirectionDownload
+)
+
+const (
+	// 不删除目标端多余的文件
+	DeletePolicyKeep DeletePolicy = iota
+
+	// 删除目标端中源端不存在的文件
+	DeletePolicyDelete
+)
+
+const (
+	// 比较文件大小和修改时间
+	CompareSizeAndModTime CompareMode = iota
+
+	// 比较文件大小和七牛 Etag
+	CompareHash
+
+	// 仅比较文件大小
+	CompareSizeOnly
+)
+
+const (
+	// 上传本地文件
+	ActionUpload ActionType = iota + 1
+
+	// 下载对象
+	ActionDownload
+
+	// 删除对象
+	ActionDeleteObject
+
+	// 删除本地文件
+	ActionDeleteLocalFile
+)
+
+// 保存文件修改时间的元数据键，值为 UNIX 时间戳，单位为秒
+const MetadataKeyModTime = "x-qn-meta-mtime"
+
+// 计划删除的文件数量超过限制
+var ErrTooManyDeletions = errors.New("too many deletions")
+
+// 创建同步器
+func NewSyncer(options *SyncerOptions) *Syncer {
+	if options == nil {
+		options = &SyncerOptions{}
+	}
+	uploadManager := options.UploadManager
+	if uploadManager == nil {
+		uploadManager = uploader.NewUploadManager(&uploader.UploadManagerOptions{Options: options.Options})
+	}
+	downloadManager := options.DownloadManager
+	if downloadManager == nil {
+		downloadManager = downloader.NewDownloadManager(&downloader.DownloadManagerOptions{Options: options.Options})
+	}
+	return &Syncer{
+		objectsManager:  objects.NewObjectsManager(&objects.ObjectsManagerOptions{Options: options.Options}),
+		uploadManager:   uploadManager,
+		downloadManager: downloadManager,
+	}
+}
+
+// 同步本地目录与空间目录
+//
+// 先生成同步计划，如果不是 DryRun，再并发执行计划中的全部变更。单个变更失败不会中断其他变更，
+// 失败的变更记录在报告中，同时返回第一个失败的变更错误。
+func (syncer *Syncer) Sync(ctx context.Context, localPath string, options *SyncOptions) (*Report, error) {
+	if options == nil {
+		options = &SyncOptions{}
+	}
+	plan, err := syncer.Plan(ctx, localPath, options)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{Plan: plan, DryRun: options.DryRun}
+	if options.DryRun {
+		return report, nil
+	}
+
+	var (
+		reportMutex sync.Mutex
+		deletions   []*Action
+	)
+	done := func(action *Action, err error) {
+		reportMutex.Lock()
+		if err == nil {
+			report.Succeeded = append(report.Succeeded, action)
+		} else {
+			report.Failed = append(report.Failed, &ActionError{Action: action, Err: err})
+		}
+		reportMutex.Unlock()
+		if options.OnActionDone != nil {
+			options.OnActionDone(action, err)
+		}
+	}
+
+	objectConcurrency := options.ObjectConcurrency
+	if objectConcurrency <= 0 {
+		objectConcurrency = 4
+	}
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(objectConcurrency)
+	for _, action := range plan.Actions {
+		action := action
+		switch action.Type {
+		case ActionUpload:
+			g.Go(func() error {
+				done(action, syncer.upload(gctx, action, options))
+				return nil
+			})
+		case ActionDownload:
+			g.Go(func() error {
+				done(action, syncer.download(gctx, action, options))
+				return nil
+			})
+		case ActionDeleteLocalFile:
+			g.Go(func() error {
+				done(action, os.Remove(action.LocalPath))
+				return nil
+			})
+		case ActionDeleteObject:
+			deletions = append(deletions, action)
+		}
+	}
+	g.Wait()
+
+	if len(deletions) > 0 {
+		bucket := syncer.objectsManager.Bucket(options.BucketName)
+		operations := make([]objects.Operation, 0, len(deletions))
+		for _, action := range deletions {
+			action := action
+			operations = append(operations, bucket.Object(action.ObjectName).Delete().
+				OnResponse(func() { done(action, nil) }).
+				OnError(func(err error) { done(action, err) }))
+		}
+		if err = syncer.objectsManager.Batch(ctx, operations, nil); err != nil {
+			return report, err
+		}
+	}
+
+	if len(report.Failed) > 0 {
+		return report, report.Failed[0]
+	}
+	return report, nil
+}
+
+func (syncer *Syncer) upload(ctx context.Context, action *Action, options *SyncOptions) error {
+	fileInfo, err := os.Stat(action.LocalPath)
+	if err != nil {
+		return err
+	}
+	objectName := action.ObjectName
+	return syncer.uploadManager.UploadFile(ctx, action.LocalPath, &uploader.ObjectOptions{
+		BucketName: options.BucketName,
+		ObjectName: &objectName,
+		FileName:   filepath.Base(action.LocalPath),
+		UpToken:    options.UpToken,
+		Metadata:   map[string]string{MetadataKeyModTime: strconv.FormatInt(fileInfo.ModTime().Unix(), 10)},
+	}, nil)
+}
+
+// 先下载到同一目录下的临时文件，成功后再替换目标文件，避免留下不完整的文件
+func (syncer *Syncer) download(ctx context.Context, action *Action, options *SyncOptions) error {
+	dir := filepath.Dir(action.LocalPath)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	tmpFile, err := os.CreateTemp(dir, "."+filepath.Base(action.LocalPath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpFilePath := tmpFile.Name()
+	tmpFile.Close()
+	defer os.Remove(tmpFilePath)
+
+	_, err = syncer.downloadManager.DownloadToFile(ctx, action.ObjectName, tmpFilePath, &downloader.ObjectOptions{
+		GenerateOptions: downloader.GenerateOptions{
+			BucketName:          options.BucketName,
+			UseInsecureProtocol: options.UseInsecureProtocol,
+		},
+		DownloadURLsProvider: options.DownloadURLsProvider,
+	})
+	if err != nil {
+		return err
+	}
+	if !action.modTime.IsZero() {
+		if err = os.Chtimes(tmpFilePath, action.modTime, action.modTime); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmpFilePath, action.LocalPath)
+}
+
+func (options *SyncOptions) validate() error {
+	if options.BucketName == "" {
+		return storagev2errors.MissingRequiredFieldError{Name: "BucketName"}
+	}
+	return nil
+}
+
+func (actionType ActionType) String() string {
+	switch actionType {
+	case ActionUpload:
+		return "upload"
+	case ActionDownload:
+		return "download"
+	case ActionDeleteObject:
+		return "delete object"
+	case ActionDeleteLocalFile:
+		return "delete local file"
+	default:
+		return "unknown"
+	}
+}
+
+func (action *Action) String() string {
+	return fmt.Sprintf("%s %s (%s)", action.Type, action.RelativePath, action.Reason)
+}
+
+func (err *ActionError) Error() string {
+	return fmt.Sprintf("failed to %s: %s", err.Action, err.Err)
+}
+
+func (err *ActionError) Unwrap() error {
+	return err.Err
+}
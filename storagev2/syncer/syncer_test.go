@@ -0,0 +1,345 @@
+//go:build unit
+// +build unit
+
+package syncer_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qiniu/go-sdk/v7/storagev2/apis/batch_ops"
+	"github.com/qiniu/go-sdk/v7/storagev2/apis/get_objects"
+	"github.com/qiniu/go-sdk/v7/storagev2/credentials"
+	"github.com/qiniu/go-sdk/v7/storagev2/downloader"
+	"github.com/qiniu/go-sdk/v7/storagev2/http_client"
+	"github.com/qiniu/go-sdk/v7/storagev2/region"
+	"github.com/qiniu/go-sdk/v7/storagev2/syncer"
+)
+
+type mockObject struct {
+	content string
+	modTime time.Time
+}
+
+func newMockServer(t *testing.T, remoteObjects map[string]*mockObject) (*httptest.Server, *sync.Mutex, map[string]string, *[]string) {
+	var (
+		mutex    sync.Mutex
+		uploaded = make(map[string]string)
+		deleted  []string
+	)
+	serveMux := http.NewServeMux()
+	serveMux.HandleFunc("/list", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("bucket") != "bucket1" {
+			t.Fatalf("unexpected bucket")
+		}
+		prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+		var (
+			response     get_objects.Response
+			prefixes     = make(map[string]struct{})
+			objectNames  = make([]string, 0, len(remoteObjects))
+			commonPrefix []string
+		)
+		for objectName := range remoteObjects {
+			objectNames = append(objectNames, objectName)
+		}
+		sort.Strings(objectNames)
+		for _, objectName := range objectNames {
+			if !strings.HasPrefix(objectName, prefix) {
+				continue
+			}
+			if delimiter != "" {
+				if idx := strings.Index(objectName[len(prefix):], delimiter); idx >= 0 {
+					p := objectName[:len(prefix)+idx+len(delimiter)]
+					if _, ok := prefixes[p]; !ok {
+						prefixes[p] = struct{}{}
+						commonPrefix = append(commonPrefix, p)
+					}
+					continue
+				}
+			}
+			object := remoteObjects[objectName]
+			entry := get_objects.ListedObjectEntry{
+				Key:      objectName,
+				PutTime:  time.Now().UnixNano() / 100,
+				Hash:     "testhash",
+				Size:     int64(len(object.content)),
+				MimeType: "text/plain",
+			}
+			if !object.modTime.IsZero() {
+				entry.Metadata = map[string]string{"x-qn-meta-mtime": strconv.FormatInt(object.modTime.Unix(), 10)}
+			}
+			response.Items = append(response.Items, entry)
+		}
+		response.CommonPrefixes = commonPrefix
+		jsonData, err := json.Marshal(&response)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Add("X-ReqId", "fakereqid")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jsonData)
+	})
+	serveMux.HandleFunc("/batch", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		responses := make([]batch_ops.OperationResponse, 0, len(r.PostForm["op"]))
+		for _, op := range r.PostForm["op"] {
+			if !strings.HasPrefix(op, "delete/") {
+				t.Fatalf("unexpected op: %s", op)
+			}
+			entryBytes, err := base64.URLEncoding.DecodeString(strings.TrimPrefix(op, "delete/"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			mutex.Lock()
+			deleted = append(deleted, strings.TrimPrefix(string(entryBytes), "bucket1:"))
+			mutex.Unlock()
+			responses = append(responses, batch_ops.OperationResponse{Code: 200})
+		}
+		jsonData, err := json.Marshal(&batch_ops.Response{OperationResponses: responses})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Add("X-ReqId", "fakereqid")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jsonData)
+	})
+	serveMux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			if err := r.ParseMultipartForm(2 * 1024 * 1024); err != nil {
+				t.Fatal(err)
+			}
+			key := r.MultipartForm.Value["key"][0]
+			if values := r.MultipartForm.Value["x-qn-meta-mtime"]; len(values) != 1 {
+				t.Fatalf("mtime metadata is expected")
+			}
+			file, err := r.MultipartForm.File["file"][0].Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			var buf bytes.Buffer
+			if _, err = buf.ReadFrom(file); err != nil {
+				t.Fatal(err)
+			}
+			mutex.Lock()
+			uploaded[key] = buf.String()
+			mutex.Unlock()
+			w.Header().Add("X-ReqId", "fakereqid")
+			w.Write([]byte(`{"ok":true}`))
+		case http.MethodGet, http.MethodHead:
+			object, ok := remoteObjects[strings.TrimPrefix(r.URL.Path, "/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Add("X-ReqId", "fakereqid")
+			http.ServeContent(w, r, "", time.Time{}, strings.NewReader(object.content))
+		default:
+			t.Fatalf("unexpected method: %s", r.Method)
+		}
+	})
+	server := httptest.NewServer(serveMux)
+	return server, &mutex, uploaded, &deleted
+}
+
+func newSyncer(server *httptest.Server) *syncer.Syncer {
+	return syncer.NewSyncer(&syncer.SyncerOptions{
+		Options: http_client.Options{
+			Credentials: credentials.NewCredentials("testak", "testsk"),
+			Regions: &region.Region{
+				Up:  region.Endpoints{Preferred: []string{server.URL}},
+				Rs:  region.Endpoints{Preferred: []string{server.URL}},
+				Rsf: region.Endpoints{Preferred: []string{server.URL}},
+			},
+		},
+	})
+}
+
+func writeLocalFile(t *testing.T, filePath, content string, modTime time.Time) {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filePath, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filePath, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSyncerUpload(t *testing.T) {
+	modTime := time.Unix(1700000000, 0)
+	localDir := t.TempDir()
+	writeLocalFile(t, filepath.Join(localDir, "same.txt"), "same", modTime)
+	writeLocalFile(t, filepath.Join(localDir, "changed.txt"), "new content", modTime)
+	writeLocalFile(t, filepath.Join(localDir, "new.txt"), "new", modTime)
+	writeLocalFile(t, filepath.Join(localDir, "skip.log"), "log", modTime)
+	writeLocalFile(t, filepath.Join(localDir, "sub", "deep.txt"), "deep", modTime)
+
+	server, mutex, uploaded, deleted := newMockServer(t, map[string]*mockObject{
+		"backup/same.txt":    {content: "same", modTime: modTime},
+		"backup/changed.txt": {content: "old", modTime: modTime},
+		"backup/extra.txt":   {content: "extra", modTime: modTime},
+		"backup/server.log":  {content: "log"},
+	})
+	defer server.Close()
+
+	s := newSyncer(server)
+	options := syncer.SyncOptions{
+		BucketName:   "bucket1",
+		ObjectPrefix: "backup/",
+		DeletePolicy: syncer.DeletePolicyDelete,
+		Exclude:      []string{"*.log"},
+		DryRun:       true,
+	}
+	report, err := s.Sync(context.Background(), localDir, &options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"upload changed.txt",
+		"delete object extra.txt",
+		"upload new.txt",
+		"upload sub/deep.txt",
+	}
+	if len(report.Plan.Actions) != len(expected) {
+		t.Fatalf("unexpected actions: %v", report.Plan.Actions)
+	}
+	for i, action := range report.Plan.Actions {
+		if got := action.Type.String() + " " + action.RelativePath; got != expected[i] {
+			t.Fatalf("unexpected action: %s", got)
+		}
+	}
+	if len(report.Plan.Unchanged) != 1 || report.Plan.Unchanged[0] != "same.txt" {
+		t.Fatalf("unexpected unchanged: %v", report.Plan.Unchanged)
+	}
+	if len(uploaded) != 0 || len(*deleted) != 0 {
+		t.Fatalf("dry run should not change anything")
+	}
+
+	options.DryRun = false
+	options.MaxDeletions = 1
+	if report, err = s.Sync(context.Background(), localDir, &options); err != nil {
+		t.Fatal(err)
+	} else if len(report.Succeeded) != 4 || len(report.Failed) != 0 {
+		t.Fatalf("unexpected report")
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(uploaded) != 3 || uploaded["backup/changed.txt"] != "new content" || uploaded["backup/new.txt"] != "new" || uploaded["backup/sub/deep.txt"] != "deep" {
+		t.Fatalf("unexpected uploaded: %v", uploaded)
+	}
+	if len(*deleted) != 1 || (*deleted)[0] != "backup/extra.txt" {
+		t.Fatalf("unexpected deleted: %v", *deleted)
+	}
+}
+
+func TestSyncerDownload(t *testing.T) {
+	modTime := time.Unix(1700000000, 0)
+	localDir := t.TempDir()
+	writeLocalFile(t, filepath.Join(localDir, "a.txt"), "stale", modTime.Add(-time.Hour))
+	writeLocalFile(t, filepath.Join(localDir, "stale.txt"), "stale", modTime)
+
+	server, _, _, _ := newMockServer(t, map[string]*mockObject{
+		"a.txt":         {content: "hello", modTime: modTime},
+		"dir/b.txt":     {content: "world", modTime: modTime},
+		"dir/c.txt":     {content: "c"},
+		"extra/d.txt":   {content: "d", modTime: modTime},
+		"../escape.txt": {content: "escape", modTime: modTime},
+	})
+	defer server.Close()
+
+	s := newSyncer(server)
+	options := syncer.SyncOptions{
+		BucketName:           "bucket1",
+		Direction:            syncer.DirectionDownload,
+		DeletePolicy:         syncer.DeletePolicyDelete,
+		MaxDeletions:         1,
+		Exclude:              []string{"extra"},
+		DownloadURLsProvider: downloader.NewStaticDomainBasedURLsProvider([]string{server.URL}),
+	}
+	report, err := s.Sync(context.Background(), localDir, &options)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(report.Succeeded) != 4 {
+		t.Fatalf("unexpected report: %v", report.Plan.Actions)
+	}
+	for relativePath, content := range map[string]string{"a.txt": "hello", "dir/b.txt": "world", "dir/c.txt": "c"} {
+		filePath := filepath.Join(localDir, filepath.FromSlash(relativePath))
+		if data, err := os.ReadFile(filePath); err != nil {
+			t.Fatal(err)
+		} else if string(data) != content {
+			t.Fatalf("unexpected content of %s", relativePath)
+		}
+		if relativePath != "dir/c.txt" {
+			if fileInfo, err := os.Stat(filePath); err != nil {
+				t.Fatal(err)
+			} else if !fileInfo.ModTime().Equal(modTime) {
+				t.Fatalf("unexpected modification time of %s", relativePath)
+			}
+		}
+	}
+	if _, err = os.Stat(filepath.Join(localDir, "stale.txt")); !os.IsNotExist(err) {
+		t.Fatalf("stale.txt should be deleted")
+	}
+	if _, err = os.Stat(filepath.Join(localDir, "extra")); !os.IsNotExist(err) {
+		t.Fatalf("excluded directory should not be downloaded")
+	}
+	if _, err = os.Stat(filepath.Join(localDir, "..", "escape.txt")); !os.IsNotExist(err) {
+		t.Fatalf("object outside of the local directory should not be downloaded")
+	}
+
+	writeLocalFile(t, filepath.Join(localDir, "stale1.txt"), "stale", modTime)
+	writeLocalFile(t, filepath.Join(localDir, "stale2.txt"), "stale", modTime)
+	if _, err = s.Plan(context.Background(), localDir, &options); err != syncer.ErrTooManyDeletions {
+		t.Fatalf("ErrTooManyDeletions is expected, got %v", err)
+	}
+}
+
+func TestSyncerUploadWithPrefixWithoutSeparator(t *testing.T) {
+	modTime := time.Unix(1700000000, 0)
+	localDir := t.TempDir()
+	writeLocalFile(t, filepath.Join(localDir, "same.txt"), "same", modTime)
+	writeLocalFile(t, filepath.Join(localDir, "new.txt"), "new", modTime)
+
+	server, mutex, uploaded, _ := newMockServer(t, map[string]*mockObject{
+		"backup/same.txt": {content: "same", modTime: modTime},
+		"backupsame.txt":  {content: "other", modTime: modTime},
+	})
+	defer server.Close()
+
+	report, err := newSyncer(server).Sync(context.Background(), localDir, &syncer.SyncOptions{
+		BucketName:   "bucket1",
+		ObjectPrefix: "backup",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Plan.Actions) != 1 || report.Plan.Actions[0].ObjectName != "backup/new.txt" {
+		t.Fatalf("unexpected actions: %v", report.Plan.Actions)
+	}
+	if len(report.Plan.Unchanged) != 1 || report.Plan.Unchanged[0] != "same.txt" {
+		t.Fatalf("unexpected unchanged: %v", report.Plan.Unchanged)
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(uploaded) != 1 || uploaded["backup/new.txt"] != "new" {
+		t.Fatalf("unexpected uploaded: %v", uploaded)
+	}
+}
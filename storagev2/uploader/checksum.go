@@ -0,0 +1,135 @@
+package uploader
+
+import (
+	"context"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/qiniu/go-sdk/v7/internal/checksum"
+	"github.com/qiniu/go-sdk/v7/internal/etag"
+	"github.com/qiniu/go-sdk/v7/storagev2/errors"
+	httpclient "github.com/qiniu/go-sdk/v7/storagev2/http_client"
+	"github.com/qiniu/go-sdk/v7/storagev2/uploader/source"
+)
+
+type (
+	// 上传校验算法，可以组合使用
+	ChecksumAlgorithms uint8
+
+	// 本地计算的整个对象的校验和，上传完毕后与服务器计算的值比较
+	localChecksums struct {
+		hasher *checksum.Hasher
+		etag   hash.Hash
+	}
+
+	// 在切片的同时按顺序计算整个对象的校验和，切片完毕后将校验和写入对象元数据
+	checksumSource struct {
+		source.Source
+		checksums *localChecksums
+		metadata  map[string]string
+	}
+)
+
+const (
+	// CRC64-ECMA 校验
+	ChecksumCRC64 ChecksumAlgorithms = 1 << iota
+	// MD5 校验
+	ChecksumMD5
+)
+
+func (algorithms ChecksumAlgorithms) newHasher() *checksum.Hasher {
+	return checksum.NewHasher(algorithms&ChecksumCRC64 != 0, algorithms&ChecksumMD5 != 0)
+}
+
+func newLocalChecksums(algorithms ChecksumAlgorithms) *localChecksums {
+	return &localChecksums{hasher: algorithms.newHasher(), etag: etag.New()}
+}
+
+func (checksums *localChecksums) Write(p []byte) (int, error) {
+	checksums.hasher.Write(p)
+	return checksums.etag.Write(p)
+}
+
+// 复制元数据，使得写入校验和时不会修改调用方的元数据
+func prepareChecksums(objectOptions *ObjectOptions) {
+	if objectOptions.Checksums == 0 {
+		return
+	}
+	metadata := make(map[string]string, len(objectOptions.Metadata)+2)
+	for k, v := range objectOptions.Metadata {
+		metadata[k] = v
+	}
+	objectOptions.Metadata = metadata
+}
+
+func setChecksumsToMetadata(metadata map[string]string, hasher *checksum.Hasher) {
+	if crc64 := hasher.CRC64(); crc64 != "" {
+		metadata[checksum.MetadataKeyCRC64] = crc64
+	}
+	if md5 := hasher.MD5(); md5 != "" {
+		metadata[checksum.MetadataKeyMD5] = md5
+	}
+}
+
+// 计算 io.ReadSeeker 从当前位置开始的数据的校验和，计算完毕后 Seek 回原来的位置
+func checksumsFromReadSeeker(r io.ReadSeeker, algorithms ChecksumAlgorithms) (*localChecksums, error) {
+	offset, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	checksums := newLocalChecksums(algorithms)
+	if _, err = io.Copy(checksums, r); err != nil {
+		return nil, err
+	}
+	if _, err = r.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return checksums, nil
+}
+
+// 调度器按顺序切片，因此可以在切片时依次计算各个分片，得到整个对象的校验和
+func (src *checksumSource) Slice(n uint64) (source.Part, error) {
+	part, err := src.Source.Slice(n)
+	if err != nil {
+		return nil, err
+	} else if part == nil {
+		setChecksumsToMetadata(src.metadata, src.checksums.hasher)
+		return nil, nil
+	}
+	if _, err = io.Copy(src.checksums, part); err != nil {
+		return nil, err
+	}
+	if _, err = part.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return part, nil
+}
+
+// 上传完毕后获取对象信息，与服务器计算的 Etag 及 MD5 比较
+//
+// 写入对象元数据的校验和由客户端提供，不能证明服务器保存的数据正确，因此不参与比较。
+// 仅在给出对象名称且能够获取到凭证时校验；分片大小不为 4 MB 时，服务器计算的 Etag 与标准七牛 Etag 不同，compareEtag 应为 false；
+// 服务器仅对部分上传方式返回对象 MD5，没有返回时不比较。
+func verifyUploadedObject(ctx context.Context, options *httpclient.Options, objectOptions *ObjectOptions, checksums *localChecksums, compareEtag bool) error {
+	if checksums == nil || objectOptions.ObjectName == nil {
+		return nil
+	}
+	credentials := getCredentials(options)
+	if credentials == nil {
+		return nil
+	}
+	stat, err := statObject(ctx, options, credentials, objectOptions)
+	if err != nil {
+		return err
+	}
+	if compareEtag {
+		if expected := etag.Encode(checksums.etag.Sum(nil)); stat.Hash != expected {
+			return errors.ChecksumMismatchError{Algorithm: "Etag", Expected: expected, Actual: stat.Hash}
+		}
+	}
+	if expected := checksums.hasher.MD5(); expected != "" && stat.Md5 != "" && !strings.EqualFold(stat.Md5, expected) {
+		return errors.ChecksumMismatchError{Algorithm: "MD5", Expected: expected, Actual: stat.Md5}
+	}
+	return nil
+}
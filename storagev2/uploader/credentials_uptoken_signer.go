@@ -12,6 +12,7 @@ import (
 type credentialsUpTokenSigner struct {
 	credentials credentials.CredentialsProvider
 	bucketName  string
+	insertOnly  bool
 	tokenTtl    time.Duration
 	cacheTtl    time.Duration
 
@@ -34,6 +35,9 @@ func (signer *credentialsUpTokenSigner) GetPutPolicy(ctx context.Context) (uptok
 		if err != nil {
 			return nil, err
 		}
+		if signer.insertOnly {
+			signer.cachedPolicy = signer.cachedPolicy.SetInsertOnly(1)
+		}
 		signer.policyCachedAt = now
 	}
 
@@ -78,6 +82,6 @@ func (signer *credentialsUpTokenSigner) getCredentials(ctx context.Context) (*cr
 	return signer.cachedCredentials, nil
 }
 
-func newCredentialsUpTokenSigner(credentials credentials.CredentialsProvider, bucketName string, tokenTtl, cacheTtl time.Duration) uptoken.Provider {
-	return &credentialsUpTokenSigner{credentials: credentials, bucketName: bucketName, tokenTtl: tokenTtl, cacheTtl: cacheTtl}
+func newCredentialsUpTokenSigner(credentials credentials.CredentialsProvider, bucketName string, insertOnly bool, tokenTtl, cacheTtl time.Duration) uptoken.Provider {
+	return &credentialsUpTokenSigner{credentials: credentials, bucketName: bucketName, insertOnly: insertOnly, tokenTtl: tokenTtl, cacheTtl: cacheTtl}
 }
@@ -26,6 +26,25 @@
 //	    UpToken:    uptoken.NewSigner(putPolicy, cred),
 //	}, nil)
 //
+// 对于无法 Seek 且大小未知的数据流（例如管道），并行分片上传时 SDK 会将数据预读到有限数量的分片缓冲区中，
+// 使得多个分片可以在数据流仍在产生时并行上传，缓冲区数量由 UploadManagerOptions.StreamingBuffers 控制，
+// 占用的内存不超过缓冲区数量与分片大小的乘积。
+//
+// # 上传远程数据源
+//
+// 支持范围请求的 HTTP URL 或任意 io.ReaderAt 可以封装为数据源，分片按需读取并可并行上传、断点续传：
+//
+//	src, err := source.NewHTTPRangeSource(ctx, "https://example.com/large-file", nil)
+//	if err != nil {
+//	    return err
+//	}
+//	defer src.Close()
+//	err = uploadManager.UploadSource(ctx, src, &uploader.ObjectOptions{
+//	    BucketName: "my-bucket",
+//	    ObjectName: &objectName,
+//	    UpToken:    uptoken.NewSigner(putPolicy, cred),
+//	}, nil)
+//
 // # 上传目录
 //
 //	err := uploadManager.UploadDirectory(ctx, "/path/to/dir", &uploader.DirectoryOptions{
@@ -43,6 +62,23 @@
 //	    },
 //	}
 //
+// # 完整性校验
+//
+// 设置 [ObjectOptions.Checksums] 后，SDK 会计算整个对象的 CRC64 和（或）MD5 并写入对象元数据。
+// 分片上传 v2 会比较服务器返回的分片 MD5，上传完毕后还会获取对象信息，与服务器计算的 Etag 和 MD5 比较，
+// 不一致时返回 errors.ChecksumMismatchError：
+//
+//	opts := &uploader.ObjectOptions{
+//	    Checksums: uploader.ChecksumCRC64 | uploader.ChecksumMD5,
+//	}
+//
+// 下载时设置 downloader.ObjectOptions.VerifyChecksums 即可按照这些元数据校验下载的文件。
+//
+// # 前置条件
+//
+// 多个写入者写入同一对象时，可以设置 [ObjectOptions.IfNotExists]（对应上传策略中的 insertOnly，由服务器保证）或
+// [ObjectOptions.IfMatchETag]（上传前比较，仅为尽力而为的检查，不能防止并发覆盖），前置条件不满足时返回 errors.PreconditionFailedError。
+//
 // # 底层接口
 //
 // 如需精细控制，可直接使用：
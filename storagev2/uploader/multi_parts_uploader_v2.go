@@ -6,11 +6,13 @@ import (
 	"encoding/hex"
 	"errors"
 	"io"
+	"strings"
 	"time"
 
 	internal_io "github.com/qiniu/go-sdk/v7/internal/io"
 	"github.com/qiniu/go-sdk/v7/storagev2/apis"
 	"github.com/qiniu/go-sdk/v7/storagev2/apis/resumable_upload_v2_complete_multipart_upload"
+	storagev2errors "github.com/qiniu/go-sdk/v7/storagev2/errors"
 	"github.com/qiniu/go-sdk/v7/storagev2/retrier"
 	resumablerecorder "github.com/qiniu/go-sdk/v7/storagev2/uploader/resumable_recorder"
 	"github.com/qiniu/go-sdk/v7/storagev2/uploader/source"
@@ -217,6 +219,11 @@ func (uploader *multiPartsUploaderV2) uploadPart(ctx context.Context, initialize
 	if err != nil {
 		return nil, err
 	}
+	if initialized.multiPartsObjectOptions.Checksums&ChecksumMD5 != 0 {
+		if expected := hex.EncodeToString(md5[:]); !strings.EqualFold(response.Md5, expected) {
+			return nil, storagev2errors.ChecksumMismatchError{Algorithm: "MD5", PartNumber: part.PartNumber(), Expected: expected, Actual: response.Md5}
+		}
+	}
 
 	if medium := initialized.medium; medium != nil {
 		medium.Write(&resumablerecorder.ResumableRecord{
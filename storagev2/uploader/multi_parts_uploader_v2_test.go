@@ -24,6 +24,7 @@ import (
 	internal_io "github.com/qiniu/go-sdk/v7/internal/io"
 	"github.com/qiniu/go-sdk/v7/storagev2/apis"
 	"github.com/qiniu/go-sdk/v7/storagev2/credentials"
+	"github.com/qiniu/go-sdk/v7/storagev2/errors"
 	"github.com/qiniu/go-sdk/v7/storagev2/http_client"
 	"github.com/qiniu/go-sdk/v7/storagev2/region"
 	"github.com/qiniu/go-sdk/v7/storagev2/uploader"
@@ -468,3 +469,64 @@ func TestMultiPartsUploaderV2Resuming(t *testing.T) {
 		t.Fatalf("unexpected response body")
 	}
 }
+
+func TestMultiPartsUploaderV2PartChecksumMismatch(t *testing.T) {
+	serveMux := mux.NewRouter()
+	serveMux.HandleFunc("/buckets/{bucketName}/objects/{encodedObjectName}/uploads", func(w http.ResponseWriter, r *http.Request) {
+		jsonBytes, err := json.Marshal(&apis.ResumableUploadV2InitiateMultipartUploadResponse{
+			UploadId:  "testuploadID",
+			ExpiredAt: time.Now().Add(1 * time.Hour).Unix(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Add("X-ReqId", "fakereqid")
+		w.Write(jsonBytes)
+	}).Methods(http.MethodPost)
+	serveMux.HandleFunc("/buckets/{bucketName}/objects/{encodedObjectName}/uploads/{uploadID}/{partNumber}", func(w http.ResponseWriter, r *http.Request) {
+		jsonBody, err := json.Marshal(&apis.ResumableUploadV2UploadPartResponse{
+			Etag: "testetag1",
+			Md5:  "00000000000000000000000000000000",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Add("X-ReqId", "fakereqid")
+		w.Write(jsonBody)
+	}).Methods(http.MethodPut)
+	server := httptest.NewServer(serveMux)
+	defer server.Close()
+
+	multiPartsUploaderV2 := uploader.NewMultiPartsUploaderV2(&uploader.MultiPartsUploaderOptions{
+		Options: http_client.Options{
+			Regions:     &region.Region{Up: region.Endpoints{Preferred: []string{server.URL}}},
+			Credentials: credentials.NewCredentials("testak", "testsk"),
+		},
+	})
+
+	src := source.NewReaderAtSource(strings.NewReader("hello world"), 11, "")
+	key := "testkey"
+	initializedPart, err := multiPartsUploaderV2.InitializeParts(context.Background(), src, &uploader.MultiPartsObjectOptions{
+		uploader.ObjectOptions{
+			BucketName: "testbucket",
+			ObjectName: &key,
+			Checksums:  uploader.ChecksumMD5,
+		},
+		4 * 1024 * 1024,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer initializedPart.Close()
+
+	part, err := src.Slice(4 * 1024 * 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = multiPartsUploaderV2.UploadPart(context.Background(), initializedPart, part, nil)
+	if mismatchErr, ok := err.(errors.ChecksumMismatchError); !ok {
+		t.Fatalf("ChecksumMismatchError is expected, got %v", err)
+	} else if mismatchErr.Algorithm != "MD5" || mismatchErr.PartNumber != 1 {
+		t.Fatalf("unexpected error: %v", mismatchErr)
+	}
+}
@@ -1,6 +1,7 @@
 package uploader
 
 import (
+	"github.com/qiniu/go-sdk/v7/storagev2/bandwidth"
 	"github.com/qiniu/go-sdk/v7/storagev2/region"
 	"github.com/qiniu/go-sdk/v7/storagev2/uptoken"
 )
@@ -35,6 +36,31 @@ type (
 
 		// 对象上传进度
 		OnUploadingProgress func(*UploadingProgress)
+
+		// 带宽限制器，如果设置，则覆盖 HTTP 客户端选项中的带宽限制器
+		BandwidthLimiter *bandwidth.Limiter
+
+		// 上传校验算法，默认为不校验
+		//
+		// 设置后，会计算整个对象的校验和并写入对象元数据（x-qn-meta-crc64ecma 与 x-qn-meta-md5），供下载时校验。
+		// 分片上传 v2 时会将服务器返回的分片 MD5 与本地计算结果比较；上传完毕后获取对象信息，
+		// 将服务器计算的 Etag（表单上传或分片大小为 4 MB 时）和 MD5（服务器返回时）与本地计算结果比较。
+		// 校验失败时返回 errors.ChecksumMismatchError。
+		Checksums ChecksumAlgorithms
+
+		// 仅当对象不存在时上传，对应上传策略中的 insertOnly，由服务器保证
+		//
+		// 如果没有提供上传凭证，SDK 生成的上传凭证会自动设置 insertOnly，
+		// 如果提供了上传凭证，则其上传策略必须已经设置 insertOnly，否则返回 errors.InvalidArgumentError，不能与 IfMatchETag 同时设置。
+		// 对象已经存在时返回 errors.PreconditionFailedError。
+		IfNotExists bool
+
+		// 上传前检查对象当前的 Etag 是否与之相同，仅为尽力而为的检查，并不保证条件覆盖
+		//
+		// 上传接口本身不支持条件覆盖，服务器不会校验该条件，SDK 只是在上传前获取对象信息进行比较，
+		// 比较与上传完成之间对象仍可能被其他写入者修改，而本次上传会将其覆盖。对象不存在或 Etag 不同时返回 errors.PreconditionFailedError。
+		// 需要防止并发写入相互覆盖时，请使用 IfNotExists 或在业务层加锁。
+		IfMatchETag string
 	}
 
 	// 分片上传对象上传选项
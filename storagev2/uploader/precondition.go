@@ -0,0 +1,91 @@
+package uploader
+
+import (
+	"context"
+	stderrors "errors"
+
+	"github.com/qiniu/go-sdk/v7/storagev2/apis"
+	creds "github.com/qiniu/go-sdk/v7/storagev2/credentials"
+	"github.com/qiniu/go-sdk/v7/storagev2/errors"
+	httpclient "github.com/qiniu/go-sdk/v7/storagev2/http_client"
+)
+
+const (
+	preconditionIfNotExists = "IfNotExists"
+	preconditionIfMatchETag = "IfMatchETag"
+)
+
+// 上传前检查前置条件
+//
+// IfNotExists 由服务器按照上传策略中的 insertOnly 保证，这里只检查上传凭证是否设置了 insertOnly；
+// IfMatchETag 则在这里获取对象信息进行比较，比较之后对象仍可能被修改，因此只是尽力而为的检查。
+func checkPreconditions(ctx context.Context, options *httpclient.Options, objectOptions *ObjectOptions) error {
+	if objectOptions.IfNotExists && objectOptions.IfMatchETag != "" {
+		return errors.InvalidArgumentError{Name: preconditionIfMatchETag, Reason: "cannot be used together with IfNotExists"}
+	}
+	if objectOptions.IfNotExists {
+		putPolicy, err := objectOptions.UpToken.GetPutPolicy(ctx)
+		if err != nil {
+			return err
+		}
+		if insertOnly, ok := putPolicy.GetInsertOnly(); !ok || insertOnly == 0 {
+			return errors.InvalidArgumentError{Name: preconditionIfNotExists, Reason: "insertOnly must be set in put policy of up token"}
+		}
+	}
+	if objectOptions.IfMatchETag != "" {
+		if objectOptions.ObjectName == nil {
+			return errors.MissingRequiredFieldError{Name: "ObjectName"}
+		}
+		credentials := getCredentials(options)
+		if credentials == nil {
+			return errors.MissingRequiredFieldError{Name: "Credentials"}
+		}
+		stat, err := statObject(ctx, options, credentials, objectOptions)
+		if err != nil {
+			if httpCodeOf(err) == 612 {
+				return errors.PreconditionFailedError{Condition: preconditionIfMatchETag, Err: err}
+			}
+			return err
+		}
+		if stat.Hash != objectOptions.IfMatchETag {
+			return errors.PreconditionFailedError{Condition: preconditionIfMatchETag}
+		}
+	}
+	return nil
+}
+
+// 将服务器返回的对象已存在错误转换为 errors.PreconditionFailedError
+func wrapPreconditionError(err error, objectOptions *ObjectOptions) error {
+	if err != nil && objectOptions.IfNotExists && httpCodeOf(err) == 614 {
+		return errors.PreconditionFailedError{Condition: preconditionIfNotExists, Err: err}
+	}
+	return err
+}
+
+func httpCodeOf(err error) int {
+	var httpCodeErr interface{ HttpCode() int }
+	if stderrors.As(err, &httpCodeErr) {
+		return httpCodeErr.HttpCode()
+	}
+	return 0
+}
+
+func getCredentials(options *httpclient.Options) creds.CredentialsProvider {
+	if options.Credentials != nil {
+		return options.Credentials
+	} else if defaultCreds := creds.Default(); defaultCreds != nil {
+		return defaultCreds
+	}
+	return nil
+}
+
+func statObject(ctx context.Context, options *httpclient.Options, credentials creds.CredentialsProvider, objectOptions *ObjectOptions) (*apis.StatObjectResponse, error) {
+	bucketName, err := guessBucketName(ctx, objectOptions.BucketName, objectOptions.UpToken)
+	if err != nil {
+		return nil, err
+	}
+	return apis.NewStorage(options).StatObject(ctx, &apis.StatObjectRequest{
+		Entry:       bucketName + ":" + *objectOptions.ObjectName,
+		Credentials: credentials,
+	}, &apis.Options{OverwrittenRegion: objectOptions.RegionsProvider})
+}
@@ -139,12 +139,16 @@ func (frr jsonFileSystemResumableRecorder) tryToClearPath(filePath string) error
 	defer file.Close()
 
 	_ = fileutil.Fadvise(file, 0, 0, fileutil.POSIX_FADV_SEQUENTIAL)
-	decoder := json.NewDecoder(file)
+	return jsonBasedResumableRecorderCheckExpired(json.NewDecoder(file))
+}
+
+// 检查记录是否全部过期，全部过期或没有任何记录时返回错误，无法识别的记录不做处理
+func jsonBasedResumableRecorderCheckExpired(decoder *json.Decoder) error {
 	var (
 		lineOptions jsonBasedResumableRecorderOpenArgs
 		jrr         jsonBasedResumableRecord
 	)
-	if err = decoder.Decode(&lineOptions); err != nil {
+	if err := decoder.Decode(&lineOptions); err != nil {
 		return nil
 	}
 	if lineOptions.Version != fileSystemResumableRecorderVersion {
@@ -255,9 +259,13 @@ func jsonFileSystemResumableRecorderVerifyHeaderLine(decoder *json.Decoder, opti
 }
 
 func (medium jsonFileSystemResumableRecorderReadableMedium) Next(rr *ResumableRecord) error {
+	return jsonBasedResumableRecorderDecodeNext(medium.decoder, rr)
+}
+
+func jsonBasedResumableRecorderDecodeNext(decoder *json.Decoder, rr *ResumableRecord) error {
 	var jrr jsonBasedResumableRecord
 	for {
-		if err := medium.decoder.Decode(&jrr); err != nil {
+		if err := decoder.Decode(&jrr); err != nil {
 			return err
 		} else if time.Now().Before(time.Unix(jrr.ExpiredAt, 0)) {
 			break
@@ -288,7 +296,11 @@ func (medium jsonFileSystemResumableRecorderReadableMedium) Close() error {
 }
 
 func (medium jsonFileSystemResumableRecorderWritableMedium) Write(rr *ResumableRecord) error {
-	jrr := jsonBasedResumableRecord{
+	return medium.encoder.Encode(newJsonBasedResumableRecord(rr))
+}
+
+func newJsonBasedResumableRecord(rr *ResumableRecord) *jsonBasedResumableRecord {
+	return &jsonBasedResumableRecord{
 		UploadID:   rr.UploadID,
 		PartID:     rr.PartID,
 		Offset:     rr.Offset,
@@ -298,7 +310,6 @@ func (medium jsonFileSystemResumableRecorderWritableMedium) Write(rr *ResumableR
 		CRC32:      rr.CRC32,
 		MD5:        hex.EncodeToString(rr.MD5[:]),
 	}
-	return medium.encoder.Encode(&jrr)
 }
 
 func (medium jsonFileSystemResumableRecorderWritableMedium) Close() error {
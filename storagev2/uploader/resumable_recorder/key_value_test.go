@@ -0,0 +1,111 @@
+//go:build unit
+// +build unit
+
+package resumablerecorder_test
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	resumablerecorder "github.com/qiniu/go-sdk/v7/storagev2/uploader/resumable_recorder"
+)
+
+func TestKeyValueResumableRecorder(t *testing.T) {
+	singleFileRecorder := resumablerecorder.NewSingleFileResumableRecorder(filepath.Join(t.TempDir(), "records", "recorder.db"))
+	defer func() {
+		if err := singleFileRecorder.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	for name, recorder := range map[string]resumablerecorder.ResumableRecorder{
+		"memory":      resumablerecorder.NewMemoryResumableRecorder(),
+		"single-file": singleFileRecorder,
+	} {
+		t.Run(name, func(t *testing.T) {
+			options := resumablerecorder.ResumableRecorderOpenArgs{
+				AccessKey:  "testak",
+				BucketName: "test-bucket",
+				ObjectName: "test-object",
+				SourceID:   "/tmp/fakeFile",
+				PartSize:   4 * 1024 * 1024,
+				TotalSize:  100 * 1024 * 1024,
+			}
+			if recorder.OpenForAppending(&options) != nil {
+				t.Fatalf("medium should not be appendable before created")
+			}
+			writableMedium := recorder.OpenForCreatingNew(&options)
+			for i := uint64(0); i < 2; i++ {
+				if err := writableMedium.Write(&resumablerecorder.ResumableRecord{
+					UploadID:   "test-upload-id",
+					PartID:     fmt.Sprintf("test-part-%d", i+1),
+					Offset:     i * 4 * 1024 * 1024,
+					PartNumber: i + 1,
+					ExpiredAt:  time.Now().Add(time.Hour),
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			writableMedium.Close()
+			writableMedium = recorder.OpenForAppending(&options)
+			if err := writableMedium.Write(&resumablerecorder.ResumableRecord{
+				UploadID:   "test-upload-id",
+				PartID:     "test-part-3",
+				Offset:     2 * 4 * 1024 * 1024,
+				PartNumber: 3,
+				ExpiredAt:  time.Now().Add(time.Hour),
+			}); err != nil {
+				t.Fatal(err)
+			}
+			writableMedium.Close()
+
+			expiredOptions := options
+			expiredOptions.ObjectName = "test-object-2"
+			writableMedium = recorder.OpenForCreatingNew(&expiredOptions)
+			if err := writableMedium.Write(&resumablerecorder.ResumableRecord{
+				UploadID:   "test-upload-id-2",
+				PartID:     "test-part-1",
+				PartNumber: 1,
+				ExpiredAt:  time.Now().Add(-time.Hour),
+			}); err != nil {
+				t.Fatal(err)
+			}
+			writableMedium.Close()
+
+			readableMedium := recorder.OpenForReading(&options)
+			for i := uint64(0); i < 3; i++ {
+				var rr resumablerecorder.ResumableRecord
+				if err := readableMedium.Next(&rr); err != nil {
+					t.Fatal(err)
+				} else if rr.PartID != fmt.Sprintf("test-part-%d", i+1) || rr.PartNumber != i+1 {
+					t.Fatalf("unexpected record: %#v", rr)
+				}
+			}
+			var rr resumablerecorder.ResumableRecord
+			if err := readableMedium.Next(&rr); err != io.EOF {
+				t.Fatalf("io.EOF is expected, got %v", err)
+			}
+			readableMedium.Close()
+
+			if err := recorder.ClearExpired(); err != nil {
+				t.Fatal(err)
+			}
+			if recorder.OpenForReading(&expiredOptions) != nil {
+				t.Fatalf("expired records should be cleared")
+			}
+			if readableMedium = recorder.OpenForReading(&options); readableMedium == nil {
+				t.Fatalf("valid records should be kept")
+			}
+			readableMedium.Close()
+
+			if err := recorder.Delete(&options); err != nil {
+				t.Fatal(err)
+			}
+			if recorder.OpenForReading(&options) != nil {
+				t.Fatalf("records should be deleted")
+			}
+		})
+	}
+}
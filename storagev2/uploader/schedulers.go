@@ -2,6 +2,7 @@ package uploader
 
 import (
 	"context"
+	"io"
 	"sort"
 	"sync"
 
@@ -21,15 +22,17 @@ type (
 	}
 
 	concurrentMultiPartsUploaderScheduler struct {
-		uploader    MultiPartsUploader
-		partSize    uint64
-		concurrency int
+		uploader         MultiPartsUploader
+		partSize         uint64
+		concurrency      int
+		streamingBuffers int
 	}
 
 	// 并行分片上传调度器选项
 	concurrentMultiPartsUploaderSchedulerOptions struct {
-		PartSize    uint64 // 分片大小
-		Concurrency int    // 并发度
+		PartSize         uint64 // 分片大小
+		Concurrency      int    // 并发度
+		StreamingBuffers int    // 流式上传分片缓冲区数量
 	}
 )
 
@@ -66,8 +69,12 @@ func newConcurrentMultiPartsUploaderScheduler(uploader MultiPartsUploader, optio
 	if concurrency <= 0 {
 		concurrency = 4
 	}
+	streamingBuffers := options.StreamingBuffers
+	if streamingBuffers <= 0 {
+		streamingBuffers = concurrency + 1
+	}
 
-	return concurrentMultiPartsUploaderScheduler{uploader, partSize, concurrency}
+	return concurrentMultiPartsUploaderScheduler{uploader, partSize, concurrency, streamingBuffers}
 }
 
 func (scheduler serialMultiPartsUploaderScheduler) UploadParts(ctx context.Context, initialized InitializedParts, src source.Source, options *UploadPartsOptions) ([]UploadedPart, error) {
@@ -87,6 +94,7 @@ func (scheduler serialMultiPartsUploaderScheduler) UploadParts(ctx context.Conte
 			}
 		}
 		uploadedPart, err := scheduler.uploader.UploadPart(ctx, initialized, part, &uploadPartParam)
+		closePart(part)
 		if err != nil {
 			return nil, err
 		}
@@ -121,18 +129,21 @@ func (scheduler concurrentMultiPartsUploaderScheduler) UploadParts(ctx context.C
 		partsCount := (totalSize + scheduler.partSize - 1) / scheduler.partSize
 		parts = make([]UploadedPart, 0, partsCount)
 	}
-	g, ctx := errgroup.WithContext(ctx)
+	g, gctx := errgroup.WithContext(ctx)
 	g.SetLimit(scheduler.concurrency)
 	var onUploadingProgressMutex sync.Mutex
-	for {
+	for gctx.Err() == nil {
 		part, err := src.Slice(scheduler.partSize)
 		if err != nil {
+			g.Wait()
 			return nil, err
 		}
 		if part == nil {
 			break
 		}
 		g.Go(func() error {
+			defer closePart(part)
+
 			var uploadPartParam UploadPartOptions
 			if options != nil && options.OnUploadingProgress != nil {
 				uploadPartParam.OnUploadingProgress = func(progress *UploadingPartProgress) {
@@ -141,7 +152,7 @@ func (scheduler concurrentMultiPartsUploaderScheduler) UploadParts(ctx context.C
 					options.OnUploadingProgress(part.PartNumber(), progress)
 				}
 			}
-			uploadedPart, err := scheduler.uploader.UploadPart(ctx, initialized, part, &uploadPartParam)
+			uploadedPart, err := scheduler.uploader.UploadPart(gctx, initialized, part, &uploadPartParam)
 			if err != nil {
 				return err
 			}
@@ -159,6 +170,8 @@ func (scheduler concurrentMultiPartsUploaderScheduler) UploadParts(ctx context.C
 	}
 	if err := g.Wait(); err != nil {
 		return nil, err
+	} else if err = ctx.Err(); err != nil {
+		return nil, err
 	}
 	sort.Slice(parts, func(i, j int) bool {
 		return parts[i].Offset() < parts[j].Offset()
@@ -173,3 +186,10 @@ func (scheduler concurrentMultiPartsUploaderScheduler) MultiPartsUploader() Mult
 func (scheduler concurrentMultiPartsUploaderScheduler) PartSize() uint64 {
 	return scheduler.partSize
 }
+
+// 分片上传结束后，如果分片持有缓冲区，则归还缓冲区
+func closePart(part source.Part) {
+	if closer, ok := part.(io.Closer); ok {
+		closer.Close()
+	}
+}
@@ -0,0 +1,215 @@
+package source
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+)
+
+type (
+	// 缓冲数据源选项
+	BufferedReaderSourceOptions struct {
+		// 分片缓冲区数量，即最多预读的分片数量，如果不填写，默认为 4
+		// 数据源占用的内存不超过分片缓冲区数量与分片大小的乘积
+		Buffers int
+
+		// 数据源 ID
+		SourceID string
+	}
+
+	bufferedReaderSource struct {
+		r          io.ReadCloser
+		sourceID   string
+		buffers    chan []byte
+		parts      chan bufferedReaderResult
+		done       chan struct{}
+		startOnce  sync.Once
+		closeOnce  sync.Once
+		readAhead  sync.WaitGroup
+		err        error
+		errorMutex sync.Mutex
+	}
+
+	// 每次读取前检查数据源是否已经关闭，使得关闭后不再读取被封装的 io.Reader
+	doneCheckingReader struct {
+		r    io.Reader
+		done <-chan struct{}
+	}
+
+	bufferedReaderResult struct {
+		part *bufferedPart
+		err  error
+	}
+
+	bufferedPart struct {
+		*bytes.Reader
+		partNumber, offset, size uint64
+		releaseOnce              sync.Once
+		release                  func()
+	}
+)
+
+// 将 io.ReadCloser 封装为带预读缓冲的数据源
+//
+// 数据源在第一次切片时启动后台协程，持续将数据读入分片缓冲区，因此在已读出的分片上传的同时，
+// 后续分片的数据可以继续被读取。分片缓冲区在分片被关闭后归还，全部缓冲区都被占用时后台协程将暂停读取。
+// 所有分片的大小均为第一次切片时指定的大小（最后一个分片除外）。
+// 适合并行上传无法 Seek 且大小未知的数据流，但由于数据无法重新读取，因此不支持断点续传。
+// 关闭数据源时将等待后台协程退出，正在进行的 Read 调用返回后不再读取 r；Read 调用一直阻塞时关闭也将一直阻塞。
+func NewBufferedReaderSource(r io.ReadCloser, options *BufferedReaderSourceOptions) Source {
+	if options == nil {
+		options = &BufferedReaderSourceOptions{}
+	}
+	buffers := options.Buffers
+	if buffers <= 0 {
+		buffers = 4
+	}
+	src := bufferedReaderSource{
+		r:        r,
+		sourceID: options.SourceID,
+		buffers:  make(chan []byte, buffers),
+		parts:    make(chan bufferedReaderResult, buffers),
+		done:     make(chan struct{}),
+	}
+	for i := 0; i < buffers; i++ {
+		src.buffers <- nil // 缓冲区在使用时才分配
+	}
+	return &src
+}
+
+func (brs *bufferedReaderSource) Slice(n uint64) (Part, error) {
+	select {
+	case <-brs.done:
+		return nil, os.ErrClosed
+	default:
+	}
+	brs.startOnce.Do(func() {
+		brs.readAhead.Add(1)
+		go brs.readAheadParts(n)
+	})
+
+	select {
+	case result, ok := <-brs.parts:
+		if !ok {
+			return nil, brs.getError()
+		} else if result.err != nil {
+			brs.setError(result.err)
+			return nil, result.err
+		}
+		return result.part, nil
+	case <-brs.done:
+		return nil, os.ErrClosed
+	}
+}
+
+func (brs *bufferedReaderSource) readAheadParts(partSize uint64) {
+	defer brs.readAhead.Done()
+	defer close(brs.parts)
+
+	var (
+		offset, partNumber uint64
+		r                  = doneCheckingReader{brs.r, brs.done}
+	)
+	for {
+		var buf []byte
+		select {
+		case buf = <-brs.buffers:
+		case <-brs.done:
+			return
+		}
+		if uint64(cap(buf)) < partSize {
+			buf = make([]byte, partSize)
+		}
+		buf = buf[:partSize]
+		haveRead, err := io.ReadFull(&r, buf)
+		if err == os.ErrClosed {
+			return
+		} else if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			brs.buffers <- buf
+			brs.send(bufferedReaderResult{err: err})
+			return
+		} else if haveRead == 0 {
+			brs.buffers <- buf
+			return
+		}
+		partNumber += 1
+		part := &bufferedPart{
+			Reader:     bytes.NewReader(buf[:haveRead]),
+			partNumber: partNumber,
+			offset:     offset,
+			size:       uint64(haveRead),
+		}
+		part.release = func() { brs.buffers <- buf }
+		offset += uint64(haveRead)
+		if !brs.send(bufferedReaderResult{part: part}) || uint64(haveRead) < partSize {
+			return
+		}
+	}
+}
+
+func (brs *bufferedReaderSource) send(result bufferedReaderResult) bool {
+	select {
+	case brs.parts <- result:
+		return true
+	case <-brs.done:
+		return false
+	}
+}
+
+func (brs *bufferedReaderSource) getError() error {
+	brs.errorMutex.Lock()
+	defer brs.errorMutex.Unlock()
+	return brs.err
+}
+
+func (brs *bufferedReaderSource) setError(err error) {
+	brs.errorMutex.Lock()
+	defer brs.errorMutex.Unlock()
+	brs.err = err
+}
+
+func (brs *bufferedReaderSource) SourceID() (string, error) {
+	return brs.sourceID, nil
+}
+
+func (brs *bufferedReaderSource) Close() (err error) {
+	brs.closeOnce.Do(func() {
+		close(brs.done)
+		brs.startOnce.Do(func() {}) // 关闭后不再启动后台协程，正在启动时等待启动完成
+		brs.readAhead.Wait()
+		err = brs.r.Close()
+	})
+	return
+}
+
+func (r *doneCheckingReader) Read(p []byte) (int, error) {
+	select {
+	case <-r.done:
+		return 0, os.ErrClosed
+	default:
+		return r.r.Read(p)
+	}
+}
+
+func (brs *bufferedReaderSource) GetFile() *os.File {
+	return nil
+}
+
+func (p *bufferedPart) PartNumber() uint64 {
+	return p.partNumber
+}
+
+func (p *bufferedPart) Offset() uint64 {
+	return p.offset
+}
+
+func (p *bufferedPart) Size() uint64 {
+	return p.size
+}
+
+// 归还分片缓冲区，关闭后分片不可再读取
+func (p *bufferedPart) Close() error {
+	p.releaseOnce.Do(p.release)
+	return nil
+}
@@ -0,0 +1,204 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	clientv1 "github.com/qiniu/go-sdk/v7/client"
+	"github.com/qiniu/go-sdk/v7/internal/clientv2"
+	"github.com/qiniu/go-sdk/v7/storagev2/retrier"
+)
+
+type (
+	// HTTP 范围请求数据源选项
+	HTTPRangeSourceOptions struct {
+		// HTTP 客户端，如果不配置则使用默认的 HTTP 客户端
+		Client clientv2.Client
+
+		// 最大重试次数，如果不配置，默认为 3
+		RetryMax int
+
+		// 附加 HTTP Header
+		Header http.Header
+
+		// 数据源 ID，如果不配置，则由 URL 和远程对象的 ETag 或 Last-Modified 生成
+		// 如果 URL 包含会随时间变化的签名参数，应当配置该选项以保证断点续传可用
+		SourceID string
+	}
+
+	httpRangeReaderAt struct {
+		ctx     context.Context
+		cancel  context.CancelFunc
+		url     string
+		header  http.Header
+		etag    string
+		ifMatch string
+		client  clientv2.Client
+	}
+)
+
+var (
+	// 远程对象不支持范围请求
+	ErrRangeNotSupported = errors.New("remote object does not support range requests")
+
+	// 远程对象在创建数据源后被修改
+	ErrRemoteObjectModified = errors.New("remote object has been modified")
+)
+
+// 将支持范围请求的 HTTP URL 封装为数据源
+//
+// 首先发送 HEAD 请求获取对象大小和 ETag，之后每个分片通过一次 Range 请求获取，因此分片可以并行上传，也可以断点续传。
+// 许多服务器支持范围请求却不返回 Accept-Ranges，因此 HEAD 响应没有声明 Accept-Ranges: bytes 或没有返回 Content-Length 时，
+// 将发送 Range: bytes=0-0 的 GET 请求进行探测，服务器没有返回 206 时返回 ErrRangeNotSupported。
+// 读取时远程对象已经被修改则返回 ErrRemoteObjectModified。
+// ctx 仅用于 HEAD 请求，之后的 Range 请求不受其影响，关闭数据源时取消所有正在进行的 Range 请求。
+func NewHTTPRangeSource(ctx context.Context, url string, options *HTTPRangeSourceOptions) (Source, error) {
+	if options == nil {
+		options = &HTTPRangeSourceOptions{}
+	}
+	retryMax := options.RetryMax
+	if retryMax <= 0 {
+		retryMax = 3
+	}
+	client := clientv2.NewClient(options.Client, clientv2.NewSimpleRetryInterceptor(clientv2.SimpleRetryConfig{
+		RetryMax: retryMax,
+		ShouldRetry: func(req *http.Request, resp *http.Response, err error) bool {
+			if err != nil {
+				return retrier.IsErrorRetryable(err)
+			}
+			return resp.StatusCode >= 500
+		},
+	}))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = cloneHeader(options.Header)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, clientv1.ResponseError(resp)
+	}
+	size := resp.ContentLength
+	if size != 0 && (size < 0 || resp.Header.Get("Accept-Ranges") != "bytes") {
+		if size, err = probeRangeSupport(ctx, client, url, options.Header); err != nil {
+			return nil, err
+		}
+	}
+
+	rawEtag := resp.Header.Get("ETag")
+	etag := parseEtag(rawEtag)
+	var ifMatch string
+	if !strings.HasPrefix(rawEtag, "W/") { // 弱 ETag 无法用于 If-Match
+		ifMatch = rawEtag
+	}
+	sourceID := options.SourceID
+	if sourceID == "" {
+		if etag != "" {
+			sourceID = fmt.Sprintf("%d:%s:%s", size, etag, url)
+		} else if lastModified := resp.Header.Get("Last-Modified"); lastModified != "" {
+			sourceID = fmt.Sprintf("%d:%s:%s", size, lastModified, url)
+		}
+	}
+	readCtx, cancel := context.WithCancel(context.Background())
+	return NewReaderAtSource(&httpRangeReaderAt{
+		ctx:     readCtx,
+		cancel:  cancel,
+		url:     url,
+		header:  options.Header,
+		etag:    etag,
+		ifMatch: ifMatch,
+		client:  client,
+	}, uint64(size), sourceID), nil
+}
+
+// 发送 Range: bytes=0-0 的 GET 请求探测远程对象是否支持范围请求，支持时返回对象大小
+func probeRangeSupport(ctx context.Context, client clientv2.Client, url string, header http.Header) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return 0, err
+	}
+	req.Header = cloneHeader(header)
+	req.Header.Set("Range", "bytes=0-0")
+	resp, err := client.Do(req)
+	if err != nil {
+		var errorInfo *clientv1.ErrorInfo
+		if errors.As(err, &errorInfo) && errorInfo.Code == http.StatusRequestedRangeNotSatisfiable {
+			return 0, ErrRangeNotSupported
+		}
+		return 0, err
+	}
+	resp.Body.Close() // 服务器忽略 Range 时响应体为整个对象，不读取直接关闭
+	if resp.StatusCode != http.StatusPartialContent {
+		return 0, ErrRangeNotSupported
+	}
+	var from, to, size int64
+	if _, err = fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-%d/%d", &from, &to, &size); err != nil {
+		return 0, ErrRangeNotSupported
+	}
+	return size, nil
+}
+
+func (h *httpRangeReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	req, err := http.NewRequestWithContext(h.ctx, http.MethodGet, h.url, http.NoBody)
+	if err != nil {
+		return 0, err
+	}
+	req.Header = cloneHeader(h.header)
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+int64(len(p))-1))
+	if h.ifMatch != "" {
+		req.Header.Set("If-Match", h.ifMatch)
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		var errorInfo *clientv1.ErrorInfo
+		if errors.As(err, &errorInfo) {
+			switch errorInfo.Code {
+			case http.StatusPreconditionFailed:
+				return 0, ErrRemoteObjectModified
+			case http.StatusRequestedRangeNotSatisfiable:
+				return 0, io.EOF
+			}
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return 0, ErrRangeNotSupported
+	}
+	if respEtag := parseEtag(resp.Header.Get("ETag")); h.etag != "" && respEtag != "" && respEtag != h.etag {
+		return 0, ErrRemoteObjectModified
+	}
+	return io.ReadFull(resp.Body, p)
+}
+
+func (h *httpRangeReaderAt) Close() error {
+	h.cancel()
+	return nil
+}
+
+func parseEtag(etag string) string {
+	etag = strings.TrimPrefix(etag, "W/")
+	etag = strings.TrimPrefix(etag, "\"")
+	etag = strings.TrimSuffix(etag, "\"")
+	return etag
+}
+
+func cloneHeader(h http.Header) http.Header {
+	if h == nil {
+		return make(http.Header)
+	}
+	return h.Clone()
+}
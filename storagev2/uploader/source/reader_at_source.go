@@ -0,0 +1,129 @@
+package source
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+)
+
+type (
+	readerAtSource struct {
+		r          io.ReaderAt
+		totalSize  uint64
+		off        uint64
+		sourceID   string
+		partNumber uint64
+		m          sync.Mutex
+	}
+
+	bufferedReaderAtPart struct {
+		r                        io.ReaderAt
+		partNumber, offset, size uint64
+		reader                   *bytes.Reader
+	}
+)
+
+// 将 io.ReaderAt 封装为数据源
+//
+// 适用于远程对象等随机读取代价较高的 io.ReaderAt，每个分片在首次读取时通过一次 ReadAt 调用将分片数据全部读入内存，
+// 因此多个分片可以并行读取，且分片重试时无需再次读取。
+// 如果 io.ReaderAt 同时实现了 io.Closer，则关闭数据源时会将其关闭。
+func NewReaderAtSource(r io.ReaderAt, totalSize uint64, sourceID string) Source {
+	return &readerAtSource{r: r, totalSize: totalSize, sourceID: sourceID}
+}
+
+func (ras *readerAtSource) Slice(n uint64) (Part, error) {
+	ras.m.Lock()
+	defer ras.m.Unlock()
+
+	offset := ras.off
+	if offset >= ras.totalSize {
+		return nil, nil
+	} else if n > ras.totalSize-offset {
+		n = ras.totalSize - offset
+	}
+	ras.off += n
+	ras.partNumber += 1
+	return &bufferedReaderAtPart{r: ras.r, partNumber: ras.partNumber, offset: offset, size: n}, nil
+}
+
+func (ras *readerAtSource) TotalSize() (uint64, error) {
+	return ras.totalSize, nil
+}
+
+func (ras *readerAtSource) ReadAt(b []byte, off int64) (int, error) {
+	return ras.r.ReadAt(b, off)
+}
+
+func (ras *readerAtSource) SourceID() (string, error) {
+	return ras.sourceID, nil
+}
+
+func (ras *readerAtSource) Close() error {
+	if closer, ok := ras.r.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (ras *readerAtSource) Reset() error {
+	ras.m.Lock()
+	defer ras.m.Unlock()
+
+	ras.off = 0
+	ras.partNumber = 0
+	return nil
+}
+
+func (ras *readerAtSource) GetFile() *os.File {
+	if file, ok := ras.r.(*os.File); ok {
+		return file
+	} else {
+		return nil
+	}
+}
+
+func (p *bufferedReaderAtPart) load() error {
+	if p.reader != nil {
+		return nil
+	}
+	buf := make([]byte, p.size)
+	n, err := p.r.ReadAt(buf, int64(p.offset))
+	if uint64(n) == p.size {
+		err = nil
+	} else if err == nil || err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+	p.reader = bytes.NewReader(buf)
+	return nil
+}
+
+func (p *bufferedReaderAtPart) Read(b []byte) (int, error) {
+	if err := p.load(); err != nil {
+		return 0, err
+	}
+	return p.reader.Read(b)
+}
+
+func (p *bufferedReaderAtPart) Seek(offset int64, whence int) (int64, error) {
+	if err := p.load(); err != nil {
+		return 0, err
+	}
+	return p.reader.Seek(offset, whence)
+}
+
+func (p *bufferedReaderAtPart) PartNumber() uint64 {
+	return p.partNumber
+}
+
+func (p *bufferedReaderAtPart) Offset() uint64 {
+	return p.offset
+}
+
+func (p *bufferedReaderAtPart) Size() uint64 {
+	return p.size
+}
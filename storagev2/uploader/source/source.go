@@ -153,6 +153,19 @@ func (rscs *readSeekCloseSource) GetFile() *os.File {
 	return rscs.rscra.GetFile()
 }
 
+// 底层 io.ReadSeekCloser 的一次读取可能不足，这里读满或返回错误，满足 io.ReaderAt 的约定
+func (rscs *readSeekCloseSource) ReadAt(b []byte, off int64) (n int, err error) {
+	for n < len(b) && err == nil {
+		var haveRead int
+		haveRead, err = rscs.rscra.ReadAt(b[n:], off+int64(n))
+		if haveRead == 0 && err == nil {
+			err = io.ErrNoProgress
+		}
+		n += haveRead
+	}
+	return
+}
+
 func newReadSeekCloseReaderAt(r internal_io.ReadSeekCloser) *readSeekCloseReaderAt {
 	return &readSeekCloseReaderAt{r: r, off: -1}
 }
@@ -243,6 +256,10 @@ func (racs *readAtSeekCloseSource) TotalSize() (uint64, error) {
 	return uint64(totalSize), nil
 }
 
+func (racs *readAtSeekCloseSource) ReadAt(b []byte, off int64) (int, error) {
+	return racs.r.ReadAt(b, off)
+}
+
 func (racs *readAtSeekCloseSource) SourceID() (string, error) {
 	return racs.sourceID, nil
 }
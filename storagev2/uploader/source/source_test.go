@@ -5,10 +5,15 @@ package source_test
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io"
 	"math/rand"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -37,6 +42,117 @@ func TestSeekableSource(t *testing.T) {
 	testSource(t, source, tmpFile)
 }
 
+func TestReaderAtSource(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "test-reader-at-source-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+
+	if _, err = io.CopyN(tmpFile, rand.New(rand.NewSource(time.Now().UnixNano())), 4096); err != nil {
+		t.Fatal(err)
+	}
+
+	source := uploader.NewReaderAtSource(io.NewSectionReader(tmpFile, 0, 4096), 4096, tmpFile.Name())
+	testSource(t, source, tmpFile)
+}
+
+func TestHTTPRangeSource(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "test-http-range-source-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+
+	if _, err = io.CopyN(tmpFile, rand.New(rand.NewSource(time.Now().UnixNano())), 4096); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"testetag"`)
+		http.ServeContent(w, r, "", time.Now(), io.NewSectionReader(tmpFile, 0, 4096))
+	}))
+	defer server.Close()
+
+	// ctx 仅用于 HEAD 请求，取消后仍然可以读取
+	ctx, cancel := context.WithCancel(context.Background())
+	source, err := uploader.NewHTTPRangeSource(ctx, server.URL, &uploader.HTTPRangeSourceOptions{SourceID: tmpFile.Name()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	testSource(t, source, tmpFile)
+
+	source, err = uploader.NewHTTPRangeSource(context.Background(), server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sourceID, err := source.SourceID(); err != nil {
+		t.Fatal(err)
+	} else if sourceID != "4096:testetag:"+server.URL {
+		t.Fatalf("Unexpected source id: %s", sourceID)
+	}
+
+	noRangeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "4096")
+	}))
+	defer noRangeServer.Close()
+
+	if _, err = uploader.NewHTTPRangeSource(context.Background(), noRangeServer.URL, nil); err != uploader.ErrRangeNotSupported {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// HEAD 响应没有声明 Accept-Ranges，但实际支持范围请求
+	unadvertisedRangeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"testetag"`)
+		if r.Method == http.MethodHead {
+			w.Header().Set("Content-Length", "4096")
+			return
+		}
+		http.ServeContent(w, r, "", time.Now(), io.NewSectionReader(tmpFile, 0, 4096))
+	}))
+	defer unadvertisedRangeServer.Close()
+
+	source, err = uploader.NewHTTPRangeSource(context.Background(), unadvertisedRangeServer.URL, &uploader.HTTPRangeSourceOptions{SourceID: tmpFile.Name()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testSource(t, source, tmpFile)
+}
+
+func TestHTTPRangeSourceWithModifiedObject(t *testing.T) {
+	data := make([]byte, 4096)
+	rand.New(rand.NewSource(time.Now().UnixNano())).Read(data)
+
+	var modified int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&modified) == 0 {
+			w.Header().Set("ETag", `"testetag1"`)
+		} else {
+			w.Header().Set("ETag", `"testetag2"`)
+		}
+		http.ServeContent(w, r, "", time.Now(), bytes.NewReader(data))
+	}))
+	defer server.Close()
+
+	source, err := uploader.NewHTTPRangeSource(context.Background(), server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+
+	atomic.StoreInt32(&modified, 1)
+	part, err := source.Slice(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = internal_io.ReadAll(part); !errors.Is(err, uploader.ErrRemoteObjectModified) {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
 func testSource(t *testing.T, source uploader.Source, originalFile *os.File) {
 	if ts, err := source.(uploader.SizedSource).TotalSize(); err != nil {
 		t.Fatal(err)
@@ -104,3 +220,113 @@ func assertReaderEqual(t *testing.T, file *os.File, offset int64, expectedData [
 		t.Fatalf("Range (%d-%d) of file (%s) is inequal", offset, offset+int64(len(expectedData)), file.Name())
 	}
 }
+
+type countingReader struct {
+	r    io.Reader
+	read int64
+	m    sync.Mutex
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.m.Lock()
+	cr.read += int64(n)
+	cr.m.Unlock()
+	return n, err
+}
+
+func (cr *countingReader) haveRead() int64 {
+	cr.m.Lock()
+	defer cr.m.Unlock()
+	return cr.read
+}
+
+// 无限慢速数据流，每次最多返回 16 字节
+type slowReader struct{}
+
+func (slowReader) Read(p []byte) (int, error) {
+	time.Sleep(time.Millisecond)
+	if len(p) > 16 {
+		p = p[:16]
+	}
+	return len(p), nil
+}
+
+func TestBufferedReaderSourceStopsReadingAfterClose(t *testing.T) {
+	r := &countingReader{r: slowReader{}}
+	src := uploader.NewBufferedReaderSource(io.NopCloser(r), &uploader.BufferedReaderSourceOptions{Buffers: 2})
+	part, err := src.Slice(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer part.(io.Closer).Close()
+
+	// 关闭时后台协程正在读取第二个分片
+	if err = src.Close(); err != nil {
+		t.Fatal(err)
+	}
+	haveRead := r.haveRead()
+	time.Sleep(50 * time.Millisecond)
+	if n := r.haveRead(); n != haveRead {
+		t.Fatalf("reader should not be read after close, have read %d, then %d", haveRead, n)
+	}
+	if _, err = src.Slice(1024); err != os.ErrClosed {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestBufferedReaderSource(t *testing.T) {
+	const partSize = 1024
+	data := make([]byte, 5*partSize+100)
+	rand.New(rand.NewSource(time.Now().UnixNano())).Read(data)
+	r := &countingReader{r: bytes.NewReader(data)}
+	src := uploader.NewBufferedReaderSource(io.NopCloser(r), &uploader.BufferedReaderSourceOptions{Buffers: 2})
+	defer src.Close()
+
+	part1, err := src.Slice(partSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part2, err := src.Slice(partSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 两个缓冲区都被占用，预读必须暂停
+	time.Sleep(50 * time.Millisecond)
+	if n := r.haveRead(); n != 2*partSize {
+		t.Fatalf("read ahead should be bounded, have read %d", n)
+	}
+
+	var received []byte
+	for i, part := range []uploader.Part{part1, part2} {
+		if part.PartNumber() != uint64(i+1) || part.Offset() != uint64(i*partSize) || part.Size() != partSize {
+			t.Fatalf("unexpected part")
+		}
+		partBytes, err := internal_io.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		received = append(received, partBytes...)
+		part.(io.Closer).Close()
+	}
+	for {
+		part, err := src.Slice(partSize)
+		if err != nil {
+			t.Fatal(err)
+		} else if part == nil {
+			break
+		}
+		if part.Offset() != uint64(len(received)) {
+			t.Fatalf("unexpected offset")
+		}
+		partBytes, err := internal_io.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		received = append(received, partBytes...)
+		part.(io.Closer).Close()
+	}
+	if !bytes.Equal(received, data) {
+		t.Fatalf("unexpected data")
+	}
+}
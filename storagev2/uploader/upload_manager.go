@@ -10,8 +10,10 @@ import (
 	"strings"
 
 	internal_io "github.com/qiniu/go-sdk/v7/internal/io"
+	"github.com/qiniu/go-sdk/v7/storagev2/encryption"
 	httpclient "github.com/qiniu/go-sdk/v7/storagev2/http_client"
 	resumablerecorder "github.com/qiniu/go-sdk/v7/storagev2/uploader/resumable_recorder"
+	"github.com/qiniu/go-sdk/v7/storagev2/uploader/source"
 	"github.com/qiniu/go-sdk/v7/storagev2/uptoken"
 	"golang.org/x/sync/errgroup"
 )
@@ -25,7 +27,9 @@ type (
 		partSize                  uint64
 		multiPartsThreshold       uint64
 		concurrency               int
+		streamingBuffers          int
 		multiPartsUploaderVersion MultiPartsUploaderVersion
+		encryptionKeyProvider     encryption.KeyProvider
 	}
 
 	// 上传器选项
@@ -48,8 +52,17 @@ type (
 		// 分片上传并行度，如果不填写，默认为 1
 		Concurrency int
 
+		// 流式上传分片缓冲区数量，如果不填写，默认为分片上传并行度加 1
+		// 并行上传无法 Seek 的 io.Reader 时，SDK 将数据预读到分片缓冲区中，使得多个分片可以在数据流仍在产生时并行上传，
+		// 占用的内存不超过分片缓冲区数量与分片大小的乘积
+		StreamingBuffers int
+
 		// 分片上传版本，如果不填写，默认为 V2
 		MultiPartsUploaderVersion MultiPartsUploaderVersion
+
+		// 客户端加密密钥提供者，如果设置，则数据在上传前使用 AES-256-GCM 分帧加密
+		// 加密信封将被写入对象元数据，加密上传不支持断点续传
+		EncryptionKeyProvider encryption.KeyProvider
 	}
 
 	// 分片上传版本
@@ -92,7 +105,9 @@ func NewUploadManager(options *UploadManagerOptions) *UploadManager {
 		partSize:                  partSize,
 		multiPartsThreshold:       multiPartsThreshold,
 		concurrency:               concurrency,
+		streamingBuffers:          options.StreamingBuffers,
 		multiPartsUploaderVersion: options.MultiPartsUploaderVersion,
+		encryptionKeyProvider:     options.EncryptionKeyProvider,
 	}
 	return &uploadManager
 }
@@ -177,7 +192,7 @@ func (uploadManager *UploadManager) UploadDirectory(ctx context.Context, directo
 					ObjectName:      &objectName,
 					FileName:        filepath.Base(path),
 				}
-				err = uploadManager.UploadReader(ctx, http.NoBody, &objectOptions, nil)
+				err = uploadManager.getFormUploader().UploadReader(ctx, http.NoBody, &objectOptions, nil)
 			}
 			return err
 		})
@@ -204,6 +219,10 @@ func (uploadManager *UploadManager) UploadFile(ctx context.Context, path string,
 		return err
 	}
 
+	if uploadManager.encryptionKeyProvider != nil {
+		return uploadManager.uploadEncryptedFile(ctx, path, uint64(fileInfo.Size()), objectOptions, returnValue)
+	}
+
 	var uploader Uploader
 	if fileInfo.Size() > int64(uploadManager.multiPartsThreshold) {
 		uploader = newMultiPartsUploader(uploadManager.getScheduler())
@@ -216,8 +235,6 @@ func (uploadManager *UploadManager) UploadFile(ctx context.Context, path string,
 
 // 上传 io.Reader
 func (uploadManager *UploadManager) UploadReader(ctx context.Context, reader io.Reader, objectOptions *ObjectOptions, returnValue interface{}) error {
-	var uploader Uploader
-
 	if objectOptions == nil {
 		objectOptions = &ObjectOptions{}
 	} else {
@@ -225,6 +242,20 @@ func (uploadManager *UploadManager) UploadReader(ctx context.Context, reader io.
 		objectOptions = &tmp
 	}
 
+	if uploadManager.encryptionKeyProvider != nil {
+		c, err := uploadManager.sealObjectOptions(ctx, objectOptions)
+		if err != nil {
+			return err
+		}
+		reader = encryption.NewEncryptingReader(reader, c)
+	}
+
+	return uploadManager.uploadReader(ctx, reader, objectOptions, returnValue)
+}
+
+func (uploadManager *UploadManager) uploadReader(ctx context.Context, reader io.Reader, objectOptions *ObjectOptions, returnValue interface{}) error {
+	var uploader Uploader
+
 	if rscs, ok := reader.(io.ReadSeeker); ok && canSeekReally(rscs) {
 		size, err := getSeekerSize(rscs)
 		if err == nil && size > uploadManager.multiPartsThreshold {
@@ -247,10 +278,98 @@ func (uploadManager *UploadManager) UploadReader(ctx context.Context, reader io.
 	return uploader.UploadReader(ctx, reader, objectOptions, returnValue)
 }
 
+// 上传数据源
+//
+// 如果数据源预知大小且不超过分片上传阈值，则使用表单上传，否则使用分片上传。
+// 启用客户端加密时，预知大小且实现了 io.ReaderAt 的数据源（如 NewFileSource 和 NewReaderAtSource 创建的数据源）按分片并行加密上传，其他数据源顺序加密上传。
+// 数据源由调用方负责关闭。
+func (uploadManager *UploadManager) UploadSource(ctx context.Context, src source.Source, objectOptions *ObjectOptions, returnValue interface{}) error {
+	if objectOptions == nil {
+		objectOptions = &ObjectOptions{}
+	} else {
+		tmp := *objectOptions
+		objectOptions = &tmp
+	}
+
+	if uploadManager.encryptionKeyProvider != nil {
+		c, err := uploadManager.sealObjectOptions(ctx, objectOptions)
+		if err != nil {
+			return err
+		}
+		// 预知大小且支持随机读取的数据源可以按分片并行加密上传，否则只能顺序读取并加密
+		if rasrc, ok := src.(interface {
+			source.SizedSource
+			io.ReaderAt
+		}); ok {
+			totalSize, err := rasrc.TotalSize()
+			if err != nil {
+				return err
+			}
+			// 每次上传都使用新的数据密钥，因此密文无法断点续传
+			readerAt, cipherSize := encryption.NewEncryptingReaderAt(rasrc, totalSize, c)
+			return uploadManager.uploadSource(ctx, source.NewReaderAtSource(readerAt, cipherSize, ""), objectOptions, returnValue)
+		}
+		return uploadManager.uploadReader(ctx, encryption.NewEncryptingReader(&sourceReader{src: src, partSize: uploadManager.partSize}, c), objectOptions, returnValue)
+	}
+
+	return uploadManager.uploadSource(ctx, src, objectOptions, returnValue)
+}
+
+func (uploadManager *UploadManager) uploadSource(ctx context.Context, src source.Source, objectOptions *ObjectOptions, returnValue interface{}) error {
+	if ssrc, ok := src.(source.SizedSource); ok {
+		totalSize, err := ssrc.TotalSize()
+		if err != nil {
+			return err
+		}
+		if totalSize <= uploadManager.multiPartsThreshold {
+			var reader io.Reader = http.NoBody
+			if totalSize > 0 {
+				part, err := src.Slice(totalSize)
+				if err != nil {
+					return err
+				} else if part != nil {
+					reader = part
+				}
+			}
+			return uploadManager.getFormUploader().UploadReader(ctx, reader, objectOptions, returnValue)
+		}
+	}
+
+	return newMultiPartsUploader(uploadManager.getScheduler()).(multiPartsUploader).uploadSource(ctx, src, objectOptions, returnValue)
+}
+
+func (uploadManager *UploadManager) uploadEncryptedFile(ctx context.Context, path string, size uint64, objectOptions *ObjectOptions, returnValue interface{}) error {
+	c, err := uploadManager.sealObjectOptions(ctx, objectOptions)
+	if err != nil {
+		return err
+	}
+	if objectOptions.FileName == "" {
+		objectOptions.FileName = filepath.Base(path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	readerAt, cipherSize := encryption.NewEncryptingReaderAt(file, size, c)
+	return uploadManager.uploadSource(ctx, source.NewReaderAtSource(readerAt, cipherSize, ""), objectOptions, returnValue)
+}
+
+// 生成数据密钥，并将加密信封写入对象元数据
+func (uploadManager *UploadManager) sealObjectOptions(ctx context.Context, objectOptions *ObjectOptions) (*encryption.Cipher, error) {
+	envelope, c, err := encryption.NewEnvelope(ctx, uploadManager.encryptionKeyProvider)
+	if err != nil {
+		return nil, err
+	}
+	objectOptions.Metadata = envelope.ToMetadata(objectOptions.Metadata)
+	return c, nil
+}
+
 func (uploadManager *UploadManager) getScheduler() multiPartsUploaderScheduler {
 	if uploadManager.concurrency > 1 {
 		return newConcurrentMultiPartsUploaderScheduler(uploadManager.getMultiPartsUploader(), &concurrentMultiPartsUploaderSchedulerOptions{
-			PartSize: uploadManager.partSize, Concurrency: uploadManager.concurrency,
+			PartSize: uploadManager.partSize, Concurrency: uploadManager.concurrency, StreamingBuffers: uploadManager.streamingBuffers,
 		})
 	} else {
 		return newSerialMultiPartsUploaderScheduler(uploadManager.getMultiPartsUploader(), &serialMultiPartsUploaderSchedulerOptions{
@@ -278,3 +397,32 @@ func (uploadManager *UploadManager) getFormUploader() Uploader {
 		UpToken: uploadManager.upTokenProvider,
 	})
 }
+
+type sourceReader struct {
+	src      source.Source
+	part     source.Part
+	partSize uint64
+}
+
+func (r *sourceReader) Read(p []byte) (int, error) {
+	for {
+		if r.part == nil {
+			part, err := r.src.Slice(r.partSize)
+			if err != nil {
+				return 0, err
+			} else if part == nil {
+				return 0, io.EOF
+			}
+			r.part = part
+		}
+		n, err := r.part.Read(p)
+		if err == io.EOF {
+			r.part = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
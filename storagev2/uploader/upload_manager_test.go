@@ -10,24 +10,33 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	goerrors "errors"
+	"hash/crc32"
 	"io"
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/qiniu/go-sdk/v7/internal/etag"
 	internal_io "github.com/qiniu/go-sdk/v7/internal/io"
 	"github.com/qiniu/go-sdk/v7/storagev2/apis"
+	"github.com/qiniu/go-sdk/v7/storagev2/bandwidth"
 	"github.com/qiniu/go-sdk/v7/storagev2/credentials"
+	"github.com/qiniu/go-sdk/v7/storagev2/encryption"
+	"github.com/qiniu/go-sdk/v7/storagev2/errors"
 	"github.com/qiniu/go-sdk/v7/storagev2/http_client"
 	"github.com/qiniu/go-sdk/v7/storagev2/region"
 	"github.com/qiniu/go-sdk/v7/storagev2/uploader"
+	"github.com/qiniu/go-sdk/v7/storagev2/uploader/source"
+	"github.com/qiniu/go-sdk/v7/storagev2/uptoken"
 )
 
 func TestUploadManagerUploadFile(t *testing.T) {
@@ -220,6 +229,163 @@ func TestUploadManagerUploadFile(t *testing.T) {
 	}
 }
 
+func TestUploadManagerUploadEncryptedFile(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "multi-parts-uploader-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	if _, err = io.CopyN(tmpFile, r, 5*1024*1024); err != nil {
+		t.Fatal(err)
+	}
+	keyProvider, err := encryption.NewStaticKeyProvider("testkeyid", bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		uploadedParts      = make(map[int][]byte)
+		uploadedPartsMutex sync.Mutex
+	)
+	serveMux := mux.NewRouter()
+	serveMux.HandleFunc("/buckets/{bucketName}/objects/{encodedObjectName}/uploads", func(w http.ResponseWriter, r *http.Request) {
+		jsonBytes, err := json.Marshal(&apis.ResumableUploadV2InitiateMultipartUploadResponse{
+			UploadId:  "testuploadID",
+			ExpiredAt: time.Now().Add(1 * time.Hour).Unix(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Add("X-ReqId", "fakereqid")
+		w.Write(jsonBytes)
+	}).Methods(http.MethodPost)
+	serveMux.HandleFunc("/buckets/{bucketName}/objects/{encodedObjectName}/uploads/{uploadID}/{partNumber}", func(w http.ResponseWriter, r *http.Request) {
+		partNumber, err := strconv.Atoi(mux.Vars(r)["partNumber"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		actualBody, err := internal_io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		uploadedPartsMutex.Lock()
+		uploadedParts[partNumber] = actualBody
+		uploadedPartsMutex.Unlock()
+		jsonBody, err := json.Marshal(&apis.ResumableUploadV2UploadPartResponse{
+			Etag: "testetag" + strconv.Itoa(partNumber),
+			Md5:  r.Header.Get("Content-MD5"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Add("X-ReqId", "fakereqid")
+		w.Write(jsonBody)
+	}).Methods(http.MethodPut)
+	serveMux.HandleFunc("/buckets/{bucketName}/objects/{encodedObjectName}/uploads/{uploadID}", func(w http.ResponseWriter, r *http.Request) {
+		requestBodyBytes, err := internal_io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body apis.ResumableUploadV2CompleteMultipartUploadRequest
+		if err = body.UnmarshalJSON(requestBodyBytes); err != nil {
+			t.Fatalf("unexpected request body")
+		}
+		if len(body.Parts) != 2 {
+			t.Fatalf("unexpected parts")
+		} else if body.Metadata["x-qn-meta-a"] != "b" {
+			t.Fatalf("unexpected x-qn-meta-a")
+		}
+		envelope, err := encryption.EnvelopeFromMetadata(body.Metadata)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := envelope.OpenCipher(context.Background(), keyProvider)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ciphertext := append(uploadedParts[1], uploadedParts[2]...)
+		if uint64(len(ciphertext)) != encryption.CiphertextSize(5*1024*1024) {
+			t.Fatalf("unexpected ciphertext size")
+		}
+		plaintext, err := internal_io.ReadAll(encryption.NewDecryptingReader(bytes.NewReader(ciphertext), c))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedBody, err := internal_io.ReadAll(io.NewSectionReader(tmpFile, 0, 5*1024*1024))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(plaintext, expectedBody) {
+			t.Fatalf("unexpected decrypted body")
+		}
+		w.Header().Add("X-ReqId", "fakereqid")
+		w.Write([]byte(`{"ok":true}`))
+	}).Methods(http.MethodPost)
+	server := httptest.NewServer(serveMux)
+	defer server.Close()
+
+	var (
+		uploadManager = uploader.NewUploadManager(&uploader.UploadManagerOptions{
+			Options: http_client.Options{
+				Regions:     &region.Region{Up: region.Endpoints{Preferred: []string{server.URL}}},
+				Credentials: credentials.NewCredentials("testak", "testsk"),
+			},
+			Concurrency:           2,
+			EncryptionKeyProvider: keyProvider,
+		})
+		returnValue struct {
+			Ok bool `json:"ok"`
+		}
+		key = "testkey"
+	)
+
+	err = uploadManager.UploadFile(context.Background(), tmpFile.Name(), &uploader.ObjectOptions{
+		BucketName: "testbucket",
+		ObjectName: &key,
+		Metadata:   map[string]string{"a": "b"},
+	}, &returnValue)
+	if err != nil {
+		t.Fatal(err)
+	} else if !returnValue.Ok {
+		t.Fatalf("unexpected response body")
+	}
+
+	// 预知大小且支持随机读取的数据源按帧随机读取并加密，而不是按分片顺序读取
+	readerAt := &recordingReaderAt{r: tmpFile}
+	src := source.NewReaderAtSource(readerAt, 5*1024*1024, "")
+	defer src.Close()
+	returnValue.Ok = false
+	err = uploadManager.UploadSource(context.Background(), src, &uploader.ObjectOptions{
+		BucketName: "testbucket",
+		ObjectName: &key,
+		Metadata:   map[string]string{"a": "b"},
+	}, &returnValue)
+	if err != nil {
+		t.Fatal(err)
+	} else if !returnValue.Ok {
+		t.Fatalf("unexpected response body")
+	}
+	if readerAt.maxReadSize == 0 || readerAt.maxReadSize > encryption.FrameSize {
+		t.Fatalf("unexpected max read size: %d", readerAt.maxReadSize)
+	}
+}
+
+type recordingReaderAt struct {
+	r           io.ReaderAt
+	mutex       sync.Mutex
+	maxReadSize int
+}
+
+func (r *recordingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	r.mutex.Lock()
+	r.maxReadSize = max(r.maxReadSize, len(p))
+	r.mutex.Unlock()
+	return r.r.ReadAt(p, off)
+}
+
 func TestUploadManagerUploadReader(t *testing.T) {
 	tmpFile, err := os.CreateTemp("", "multi-parts-uploader-test-*")
 	if err != nil {
@@ -424,6 +590,77 @@ func TestUploadManagerUploadReader(t *testing.T) {
 	wg.Wait()
 }
 
+// 无限慢速数据流，每次最多返回 4 KB，记录读取的次数
+type slowCountingReader struct {
+	reads int64
+	m     sync.Mutex
+}
+
+func (r *slowCountingReader) Read(p []byte) (int, error) {
+	time.Sleep(time.Millisecond)
+	r.m.Lock()
+	r.reads += 1
+	r.m.Unlock()
+	if len(p) > 4096 {
+		p = p[:4096]
+	}
+	return len(p), nil
+}
+
+func (r *slowCountingReader) haveRead() int64 {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return r.reads
+}
+
+func TestUploadManagerUploadReaderStopsReadingAfterError(t *testing.T) {
+	serveMux := mux.NewRouter()
+	serveMux.HandleFunc("/buckets/{bucketName}/objects/{encodedObjectName}/uploads", func(w http.ResponseWriter, r *http.Request) {
+		jsonBytes, err := json.Marshal(&apis.ResumableUploadV2InitiateMultipartUploadResponse{
+			UploadId:  "testuploadID",
+			ExpiredAt: time.Now().Add(1 * time.Hour).Unix(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Add("X-ReqId", "fakereqid")
+		w.Write(jsonBytes)
+	}).Methods(http.MethodPost)
+	serveMux.HandleFunc("/buckets/{bucketName}/objects/{encodedObjectName}/uploads/{uploadID}/{partNumber}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("X-ReqId", "fakereqid")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"bad request"}`))
+	}).Methods(http.MethodPut)
+	serveMux.HandleFunc("/buckets/{bucketName}/objects/{encodedObjectName}/uploads/{uploadID}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("X-ReqId", "fakereqid")
+		w.Write([]byte(`{}`))
+	}).Methods(http.MethodDelete)
+	server := httptest.NewServer(serveMux)
+	defer server.Close()
+
+	uploadManager := uploader.NewUploadManager(&uploader.UploadManagerOptions{
+		Options: http_client.Options{
+			Regions:     &region.Region{Up: region.Endpoints{Preferred: []string{server.URL}}},
+			Credentials: credentials.NewCredentials("testak", "testsk"),
+		},
+		MultiPartsThreshold: 1024,
+		Concurrency:         2,
+	})
+	reader := new(slowCountingReader)
+	key := "testkey"
+	if err := uploadManager.UploadReader(context.Background(), reader, &uploader.ObjectOptions{
+		BucketName: "testbucket",
+		ObjectName: &key,
+	}, nil); err == nil {
+		t.Fatalf("expected error")
+	}
+	haveRead := reader.haveRead()
+	time.Sleep(50 * time.Millisecond)
+	if n := reader.haveRead(); n != haveRead {
+		t.Fatalf("reader should not be read after UploadReader returns, have read %d times, then %d", haveRead, n)
+	}
+}
+
 func TestUploadManagerUploadDirectory(t *testing.T) {
 	testUploadManagerUploadDirectory(t, true)
 	testUploadManagerUploadDirectory(t, false)
@@ -691,3 +928,239 @@ func TestUploadManagerUploadDirectoryWithFilter(t *testing.T) {
 		t.Fatalf("unexpected uploaded oject name")
 	}
 }
+
+func TestUploadManagerUploadReaderWithBandwidthLimiter(t *testing.T) {
+	data := make([]byte, 96*1024)
+	if _, err := rand.New(rand.NewSource(time.Now().UnixNano())).Read(data); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(2 * 1024 * 1024); err != nil {
+			t.Fatal(err)
+		}
+		file, err := r.MultipartForm.File["file"][0].Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		receivedBytes, err := internal_io.ReadAll(file)
+		if err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(receivedBytes, data) {
+			t.Fatalf("unexpected content")
+		}
+		w.Header().Add("X-ReqId", "fakereqid")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	uploadManager := uploader.NewUploadManager(&uploader.UploadManagerOptions{
+		Options: http_client.Options{
+			Regions:          &region.Region{Up: region.Endpoints{Preferred: []string{server.URL}}},
+			Credentials:      credentials.NewCredentials("testak", "testsk"),
+			BandwidthLimiter: bandwidth.NewLimiter(1024 * 1024 * 1024),
+		},
+	})
+
+	begin := time.Now()
+	objectName := "testkey"
+	if err := uploadManager.UploadReader(context.Background(), bytes.NewReader(data), &uploader.ObjectOptions{
+		BucketName:       "testbucket",
+		ObjectName:       &objectName,
+		FileName:         "testfile",
+		BandwidthLimiter: bandwidth.NewLimiter(64 * 1024),
+	}, nil); err != nil {
+		t.Fatal(err)
+	}
+	// 初始令牌桶中有 64 KB 令牌，剩余的数据（包括表单的其他字段）至少需要 0.5 秒
+	if elapsed := time.Since(begin); elapsed < 450*time.Millisecond {
+		t.Fatalf("upload should be limited, elapsed: %s", elapsed)
+	}
+}
+
+func TestUploadManagerUploadReaderWithChecksums(t *testing.T) {
+	data := make([]byte, 64*1024)
+	if _, err := rand.New(rand.NewSource(time.Now().UnixNano())).Read(data); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		storedMetadata map[string]string
+		storedData     []byte
+		corrupted      bool
+	)
+	serveMux := mux.NewRouter()
+	serveMux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(2 * 1024 * 1024); err != nil {
+			t.Fatal(err)
+		}
+		storedMetadata = make(map[string]string)
+		for k, v := range r.MultipartForm.Value {
+			if strings.HasPrefix(k, "x-qn-meta-") {
+				storedMetadata[k] = v[0]
+			}
+		}
+		if r.MultipartForm.Value["crc32"][0] != strconv.FormatUint(uint64(crc32.ChecksumIEEE(data)), 10) {
+			t.Fatalf("unexpected crc32")
+		}
+		file, err := r.MultipartForm.File["file"][0].Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		if storedData, err = io.ReadAll(file); err != nil {
+			t.Fatal(err)
+		}
+		// 模拟服务器保存的数据被损坏
+		if corrupted {
+			storedData[0] ^= 0xff
+		}
+		w.Header().Add("X-ReqId", "fakereqid")
+		w.Write([]byte(`{"ok":true}`))
+	}).Methods(http.MethodPost)
+	serveMux.HandleFunc("/stat/{entry}", func(w http.ResponseWriter, r *http.Request) {
+		hash, err := etag.FromReader(bytes.NewReader(storedData))
+		if err != nil {
+			t.Fatal(err)
+		}
+		md5Sum := md5.Sum(storedData)
+		jsonBytes, err := json.Marshal(&apis.StatObjectResponse{
+			Size: int64(len(storedData)), Hash: hash, Md5: hex.EncodeToString(md5Sum[:]), MimeType: "application/octet-stream",
+			PutTime: time.Now().UnixNano() / 100, Metadata: storedMetadata,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Add("X-ReqId", "fakereqid")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jsonBytes)
+	}).Methods(http.MethodGet, http.MethodPost)
+	server := httptest.NewServer(serveMux)
+	defer server.Close()
+
+	uploadManager := uploader.NewUploadManager(&uploader.UploadManagerOptions{
+		Options: http_client.Options{
+			Regions: &region.Region{
+				Up: region.Endpoints{Preferred: []string{server.URL}},
+				Rs: region.Endpoints{Preferred: []string{server.URL}},
+			},
+			Credentials: credentials.NewCredentials("testak", "testsk"),
+		},
+	})
+	objectName := "testkey"
+	objectOptions := uploader.ObjectOptions{
+		BucketName: "testbucket",
+		ObjectName: &objectName,
+		FileName:   "testfile",
+		Metadata:   map[string]string{"a": "b"},
+		Checksums:  uploader.ChecksumCRC64 | uploader.ChecksumMD5,
+	}
+	if err := uploadManager.UploadReader(context.Background(), bytes.NewReader(data), &objectOptions, nil); err != nil {
+		t.Fatal(err)
+	}
+	md5Sum := md5.Sum(data)
+	if storedMetadata["x-qn-meta-md5"] != hex.EncodeToString(md5Sum[:]) {
+		t.Fatalf("unexpected md5 metadata")
+	} else if storedMetadata["x-qn-meta-crc64ecma"] == "" {
+		t.Fatalf("crc64 metadata is expected")
+	} else if storedMetadata["x-qn-meta-a"] != "b" {
+		t.Fatalf("unexpected x-qn-meta-a")
+	} else if len(objectOptions.Metadata) != 1 {
+		t.Fatalf("metadata of caller should not be modified")
+	}
+
+	corrupted = true
+	err := uploadManager.UploadReader(context.Background(), bytes.NewReader(data), &objectOptions, nil)
+	expectedHash, _ := etag.FromReader(bytes.NewReader(data))
+	if mismatchErr, ok := err.(errors.ChecksumMismatchError); !ok {
+		t.Fatalf("ChecksumMismatchError is expected, got %v", err)
+	} else if mismatchErr.Algorithm != "Etag" || mismatchErr.Expected != expectedHash || mismatchErr.Actual == expectedHash {
+		t.Fatalf("unexpected error: %v", mismatchErr)
+	}
+}
+
+func TestUploadManagerUploadReaderWithPreconditions(t *testing.T) {
+	existed := false
+	serveMux := mux.NewRouter()
+	serveMux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(2 * 1024 * 1024); err != nil {
+			t.Fatal(err)
+		}
+		putPolicy, err := uptoken.NewParser(r.MultipartForm.Value["token"][0]).GetPutPolicy(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Add("X-ReqId", "fakereqid")
+		if insertOnly, ok := putPolicy.GetInsertOnly(); ok && insertOnly != 0 && existed {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(614)
+			w.Write([]byte(`{"error":"file exists"}`))
+			return
+		}
+		existed = true
+		w.Write([]byte(`{"ok":true}`))
+	}).Methods(http.MethodPost)
+	serveMux.HandleFunc("/stat/{entry}", func(w http.ResponseWriter, r *http.Request) {
+		jsonBytes, err := json.Marshal(&apis.StatObjectResponse{Size: 5, Hash: "currentetag", MimeType: "text/plain", PutTime: time.Now().UnixNano() / 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Add("X-ReqId", "fakereqid")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jsonBytes)
+	}).Methods(http.MethodGet, http.MethodPost)
+	server := httptest.NewServer(serveMux)
+	defer server.Close()
+
+	uploadManager := uploader.NewUploadManager(&uploader.UploadManagerOptions{
+		Options: http_client.Options{
+			Regions: &region.Region{
+				Up: region.Endpoints{Preferred: []string{server.URL}},
+				Rs: region.Endpoints{Preferred: []string{server.URL}},
+			},
+			Credentials: credentials.NewCredentials("testak", "testsk"),
+		},
+	})
+	objectName := "testkey"
+	objectOptions := uploader.ObjectOptions{
+		BucketName:  "testbucket",
+		ObjectName:  &objectName,
+		FileName:    "testfile",
+		IfNotExists: true,
+	}
+	if err := uploadManager.UploadReader(context.Background(), strings.NewReader("hello"), &objectOptions, nil); err != nil {
+		t.Fatal(err)
+	}
+	var preconditionErr errors.PreconditionFailedError
+	err := uploadManager.UploadReader(context.Background(), strings.NewReader("hello"), &objectOptions, nil)
+	if !goerrors.As(err, &preconditionErr) || preconditionErr.Condition != "IfNotExists" {
+		t.Fatalf("PreconditionFailedError is expected, got %v", err)
+	}
+
+	putPolicy, err := uptoken.NewPutPolicy("testbucket", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	objectOptions.UpToken = uptoken.NewSigner(putPolicy, credentials.NewCredentials("testak", "testsk"))
+	var invalidArgumentErr errors.InvalidArgumentError
+	err = uploadManager.UploadReader(context.Background(), strings.NewReader("hello"), &objectOptions, nil)
+	if !goerrors.As(err, &invalidArgumentErr) || invalidArgumentErr.Name != "IfNotExists" {
+		t.Fatalf("up token without insertOnly should be rejected with InvalidArgumentError, got %v", err)
+	}
+	objectOptions.IfMatchETag = "currentetag"
+	err = uploadManager.UploadReader(context.Background(), strings.NewReader("hello"), &objectOptions, nil)
+	if !goerrors.As(err, &invalidArgumentErr) || invalidArgumentErr.Name != "IfMatchETag" {
+		t.Fatalf("IfNotExists with IfMatchETag should be rejected with InvalidArgumentError, got %v", err)
+	}
+
+	objectOptions.UpToken = nil
+	objectOptions.IfNotExists = false
+	objectOptions.IfMatchETag = "staleetag"
+	err = uploadManager.UploadReader(context.Background(), strings.NewReader("hello"), &objectOptions, nil)
+	if !goerrors.As(err, &preconditionErr) || preconditionErr.Condition != "IfMatchETag" {
+		t.Fatalf("PreconditionFailedError is expected, got %v", err)
+	}
+	objectOptions.IfMatchETag = "currentetag"
+	if err = uploadManager.UploadReader(context.Background(), strings.NewReader("hello"), &objectOptions, nil); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -11,8 +11,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/qiniu/go-sdk/v7/internal/etag"
 	internal_io "github.com/qiniu/go-sdk/v7/internal/io"
 	"github.com/qiniu/go-sdk/v7/storagev2/apis"
+	"github.com/qiniu/go-sdk/v7/storagev2/bandwidth"
 	creds "github.com/qiniu/go-sdk/v7/storagev2/credentials"
 	"github.com/qiniu/go-sdk/v7/storagev2/errors"
 	httpclient "github.com/qiniu/go-sdk/v7/storagev2/http_client"
@@ -127,10 +129,28 @@ func (uploader formUploader) UploadReader(ctx context.Context, reader io.Reader,
 }
 
 func (uploader formUploader) upload(ctx context.Context, reader io.ReadSeeker, size uint64, crc32 uint32, returnValue interface{}, objectOptions *ObjectOptions) error {
-	return forEachRegion(ctx, objectOptions, &uploader.options.Options, func(region *region.Region) (bool, error) {
+	ctx = withBandwidthLimiter(ctx, objectOptions)
+	if err := checkPreconditions(ctx, &uploader.options.Options, objectOptions); err != nil {
+		return err
+	}
+	var checksums *localChecksums
+	if objectOptions.Checksums != 0 {
+		var err error
+		if checksums, err = checksumsFromReadSeeker(reader, objectOptions.Checksums); err != nil {
+			return err
+		}
+		prepareChecksums(objectOptions)
+		setChecksumsToMetadata(objectOptions.Metadata, checksums.hasher)
+	}
+	err := forEachRegion(ctx, objectOptions, &uploader.options.Options, func(region *region.Region) (bool, error) {
 		err := uploader.uploadToRegion(ctx, region, reader, size, crc32, returnValue, objectOptions)
 		return true, err
 	})
+	if err != nil {
+		return wrapPreconditionError(err, objectOptions)
+	}
+	// 表单上传时服务器还会按照 crc32 参数校验上传的数据
+	return verifyUploadedObject(ctx, &uploader.options.Options, objectOptions, checksums, true)
 }
 
 func (uploader formUploader) uploadToRegion(ctx context.Context, region *region.Region, reader io.ReadSeeker, size uint64, crc32 uint32, returnValue interface{}, objectOptions *ObjectOptions) error {
@@ -197,6 +217,27 @@ func (uploader multiPartsUploader) UploadFile(ctx context.Context, path string,
 }
 
 func (uploader multiPartsUploader) UploadReader(ctx context.Context, reader io.Reader, objectOptions *ObjectOptions, returnValue interface{}) error {
+	var src source.Source
+	if rss, ok := reader.(io.ReadSeeker); ok && canSeekReally(rss) {
+		if rasc, ok := rss.(source.ReadAtSeekCloser); ok {
+			src = source.NewReadAtSeekCloserSource(rasc, "")
+		} else if rscs, ok := rss.(internal_io.ReadSeekCloser); ok {
+			src = source.NewReadSeekCloserSource(rscs, "")
+		} else {
+			src = source.NewReadSeekCloserSource(internal_io.MakeReadSeekCloserFromReader(rss), "")
+		}
+	} else if scheduler, ok := uploader.scheduler.(concurrentMultiPartsUploaderScheduler); ok {
+		// 并行上传时预读数据流，使得分片上传的同时可以继续读取后续分片
+		src = source.NewBufferedReaderSource(io.NopCloser(reader), &source.BufferedReaderSourceOptions{Buffers: scheduler.streamingBuffers})
+		defer src.Close()
+	} else {
+		src = source.NewReadCloserSource(io.NopCloser(reader), "")
+	}
+
+	return uploader.uploadSource(ctx, src, objectOptions, returnValue)
+}
+
+func (uploader multiPartsUploader) uploadSource(ctx context.Context, src source.Source, objectOptions *ObjectOptions, returnValue interface{}) error {
 	if objectOptions == nil {
 		objectOptions = &ObjectOptions{}
 	} else {
@@ -215,37 +256,38 @@ func (uploader multiPartsUploader) UploadReader(ctx context.Context, reader io.R
 	}
 	objectOptions.UpToken = upToken
 
-	var src source.Source
-	if rss, ok := reader.(io.ReadSeeker); ok && canSeekReally(rss) {
-		if rasc, ok := rss.(source.ReadAtSeekCloser); ok {
-			src = source.NewReadAtSeekCloserSource(rasc, "")
-		} else if rscs, ok := rss.(internal_io.ReadSeekCloser); ok {
-			src = source.NewReadSeekCloserSource(rscs, "")
-		} else {
-			src = source.NewReadSeekCloserSource(internal_io.MakeReadSeekCloserFromReader(rss), "")
-		}
-	} else {
-		src = source.NewReadCloserSource(io.NopCloser(reader), "")
-	}
-
 	return uploader.upload(ctx, src, &options.Options, objectOptions, returnValue)
 }
 
 func (uploader multiPartsUploader) upload(ctx context.Context, src source.Source, httpClientOptions *httpclient.Options, objectOptions *ObjectOptions, returnValue interface{}) error {
-	resumed, err := uploader.uploadResumedParts(ctx, src, objectOptions, returnValue)
+	ctx = withBandwidthLimiter(ctx, objectOptions)
+	if err := checkPreconditions(ctx, httpClientOptions, objectOptions); err != nil {
+		return err
+	}
+	var checksums *localChecksums
+	if objectOptions.Checksums != 0 {
+		prepareChecksums(objectOptions)
+		checksums = newLocalChecksums(objectOptions.Checksums)
+	}
+	// 只有分片大小为 4 MB 时，服务器计算的 Etag 才与标准七牛 Etag 相同
+	compareEtag := uploader.scheduler.PartSize() == etag.BlockSize
+	resumed, err := uploader.uploadResumedParts(ctx, src, objectOptions, checksums, returnValue)
 	if err == nil && resumed {
-		return nil
+		return verifyUploadedObject(ctx, httpClientOptions, objectOptions, checksums, compareEtag)
 	} else if resumed {
 		if rsrc, ok := src.(source.ResetableSource); ok {
 			if resetErr := rsrc.Reset(); resetErr == nil {
-				return err
+				return wrapPreconditionError(err, objectOptions)
 			}
 		}
 	}
-	return uploader.tryToUploadToEachRegion(ctx, src, httpClientOptions, objectOptions, returnValue)
+	if err = uploader.tryToUploadToEachRegion(ctx, src, httpClientOptions, objectOptions, checksums, returnValue); err != nil {
+		return wrapPreconditionError(err, objectOptions)
+	}
+	return verifyUploadedObject(ctx, httpClientOptions, objectOptions, checksums, compareEtag)
 }
 
-func (uploader multiPartsUploader) uploadResumedParts(ctx context.Context, src source.Source, objectOptions *ObjectOptions, returnValue interface{}) (bool, error) {
+func (uploader multiPartsUploader) uploadResumedParts(ctx context.Context, src source.Source, objectOptions *ObjectOptions, checksums *localChecksums, returnValue interface{}) (bool, error) {
 	multiPartsObjectOptions := MultiPartsObjectOptions{*objectOptions, uploader.scheduler.PartSize()}
 	if initializedParts := uploader.scheduler.MultiPartsUploader().TryToResume(ctx, src, &multiPartsObjectOptions); initializedParts == nil {
 		return false, nil
@@ -257,7 +299,7 @@ func (uploader multiPartsUploader) uploadResumedParts(ctx context.Context, src s
 				size = totalSize
 			}
 		}
-		if err := uploader.uploadPartsAndComplete(ctx, src, size, initializedParts, objectOptions, returnValue); err != nil {
+		if err := uploader.uploadPartsAndComplete(ctx, src, size, initializedParts, objectOptions, checksums, returnValue); err != nil {
 			return true, err
 		} else {
 			return true, nil
@@ -265,7 +307,7 @@ func (uploader multiPartsUploader) uploadResumedParts(ctx context.Context, src s
 	}
 }
 
-func (uploader multiPartsUploader) tryToUploadToEachRegion(ctx context.Context, src source.Source, httpClientOptions *httpclient.Options, objectOptions *ObjectOptions, returnValue interface{}) error {
+func (uploader multiPartsUploader) tryToUploadToEachRegion(ctx context.Context, src source.Source, httpClientOptions *httpclient.Options, objectOptions *ObjectOptions, checksums *localChecksums, returnValue interface{}) error {
 	return forEachRegion(ctx, objectOptions, httpClientOptions, func(region *region.Region) (bool, error) {
 		objectOptions.RegionsProvider = region
 		multiPartsObjectOptions := MultiPartsObjectOptions{*objectOptions, uploader.scheduler.PartSize()}
@@ -278,7 +320,7 @@ func (uploader multiPartsUploader) tryToUploadToEachRegion(ctx context.Context,
 		}
 		if err == nil {
 			defer initializedParts.Close()
-			if err = uploader.uploadPartsAndComplete(ctx, src, size, initializedParts, objectOptions, returnValue); err == nil {
+			if err = uploader.uploadPartsAndComplete(ctx, src, size, initializedParts, objectOptions, checksums, returnValue); err == nil {
 				return true, nil
 			}
 		}
@@ -291,7 +333,7 @@ func (uploader multiPartsUploader) tryToUploadToEachRegion(ctx context.Context,
 	})
 }
 
-func (uploader multiPartsUploader) uploadPartsAndComplete(ctx context.Context, src source.Source, size uint64, initializedParts InitializedParts, objectOptions *ObjectOptions, returnValue interface{}) error {
+func (uploader multiPartsUploader) uploadPartsAndComplete(ctx context.Context, src source.Source, size uint64, initializedParts InitializedParts, objectOptions *ObjectOptions, checksums *localChecksums, returnValue interface{}) error {
 	var uploadPartsOptions UploadPartsOptions
 	if objectOptions.OnUploadingProgress != nil {
 		progress := newUploadingPartsProgress()
@@ -305,6 +347,11 @@ func (uploader multiPartsUploader) uploadPartsAndComplete(ctx context.Context, s
 			return nil
 		}
 	}
+	if checksums != nil {
+		// 每次尝试都从头切片，因此重新计算校验和
+		*checksums = *newLocalChecksums(objectOptions.Checksums)
+		src = &checksumSource{Source: src, checksums: checksums, metadata: objectOptions.Metadata}
+	}
 	uploadParts, err := uploader.scheduler.UploadParts(ctx, initializedParts, src, &uploadPartsOptions)
 	if err != nil {
 		return err
@@ -322,13 +369,20 @@ func getUpToken(c creds.CredentialsProvider, objectOptions *ObjectOptions, upTok
 			c = creds.Default()
 		}
 		if c != nil && objectOptions.BucketName != "" {
-			return newCredentialsUpTokenSigner(c, objectOptions.BucketName, 1*time.Hour, 10*time.Minute), nil
+			return newCredentialsUpTokenSigner(c, objectOptions.BucketName, objectOptions.IfNotExists, 1*time.Hour, 10*time.Minute), nil
 		} else {
 			return nil, errors.MissingRequiredFieldError{Name: "UpToken"}
 		}
 	}
 }
 
+func withBandwidthLimiter(ctx context.Context, objectOptions *ObjectOptions) context.Context {
+	if objectOptions.BandwidthLimiter != nil {
+		ctx = bandwidth.WithLimiter(ctx, objectOptions.BandwidthLimiter)
+	}
+	return ctx
+}
+
 func crc32FromReadSeeker(r io.ReadSeeker) (uint32, error) {
 	offset, err := r.Seek(0, io.SeekCurrent)
 	if err != nil {